@@ -0,0 +1,40 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScalebarFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-scalebar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "in.png")
+	writePNG(t, inputPath, circleImage(32, 32))
+	for _, c := range []struct {
+		args  []string
+		valid bool
+	}{
+		{[]string{"-scalebar", "20,5um", "-grid", "8"}, true},
+		{[]string{"-scalebar", "20"}, true},
+		{[]string{"-scalebar", "x,5um"}, false},
+		{[]string{"-grid", "-1"}, false},
+	} {
+		outputPath := filepath.Join(dir, "out.png")
+		os.Remove(outputPath)
+		args := append([]string{"-input", inputPath, "-output", outputPath}, c.args...)
+		out := runMain(t, nil, args...)
+		if strings.Contains(string(out), "Invalid") == c.valid {
+			t.Errorf("%v: got output %q", c.args, out)
+		}
+		if _, err := os.Stat(outputPath); (err == nil) != c.valid {
+			t.Errorf("%v: output written %t", c.args, err == nil)
+		}
+	}
+}
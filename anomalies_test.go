@@ -0,0 +1,51 @@
+package main
+
+import (
+	"image"
+	_ "image/jpeg"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chfanghr/canny-go/canny"
+)
+
+func TestSubtractEdgesFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-subtract-edges")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "in.png")
+	referencePath := filepath.Join(dir, "reference.jpg")
+	outputPath := filepath.Join(dir, "out.jpg")
+	writePNG(t, inputPath, circleImage(32, 32))
+	runMain(t, nil, "-input", inputPath, "-output", referencePath)
+	runMain(t, nil, "-input", inputPath, "-output", outputPath, "-subtract-edges", referencePath)
+
+	f, err := os.Open(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if r, _, _, _ := img.At(x, y).RGBA(); r>>8 >= canny.REFERENCE_EDGE_THRESHOLD {
+				t.Fatalf("(%d, %d): edge left after subtracting the edges of the same image", x, y)
+			}
+		}
+	}
+
+	out := runMain(t, nil, "-input", inputPath, "-output", outputPath, "-subtract-edges", referencePath, "-subtract-tolerance", "-1")
+	if !strings.Contains(string(out), "Invalid edge subtraction tolerance") {
+		t.Errorf("got output %q", out)
+	}
+}
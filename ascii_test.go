@@ -0,0 +1,39 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestASCIIFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-ascii")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "in.png")
+	outputPath := filepath.Join(dir, "out.png")
+	writePNG(t, inputPath, circleImage(64, 64))
+	out := runMain(t, nil, "-input", inputPath, "-output", outputPath, "-ascii", "-ascii-width", "32")
+
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	art := 0
+	for _, line := range lines {
+		if strings.Contains(line, "#") {
+			art++
+			if len(line) != 32 {
+				t.Errorf("got line width %d, want 32: %q", len(line), line)
+			}
+		}
+	}
+	if art == 0 {
+		t.Errorf("expected ASCII art of the circle, got %q", out)
+	}
+	if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+		t.Errorf("expected no output file, got %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chfanghr/canny-go/canny"
+)
+
+// processDirectory processes every image in inputDir and writes the results
+// to outputDir, named by outputName. With cli.recursive set, subdirectories
+// are processed too and mirrored under outputDir. Inputs whose output exists
+// and is newer than the input are skipped unless cli.force is set, so
+// interrupted runs can be resumed.
+func processDirectory(ctx context.Context, inputDir, outputDir string, opts canny.Options, cli cliOptions) {
+	err := filepath.Walk(inputDir, func(inputPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if filepath.Clean(inputPath) == filepath.Clean(outputDir) {
+				return filepath.SkipDir
+			}
+			if (inputPath != inputDir) && !cli.recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		tiff := isTIFF(inputPath)
+		if !tiff && !isImageFile(inputPath) {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(inputDir, inputPath)
+		if err != nil {
+			return err
+		}
+		outputPath := filepath.Join(outputDir, outputName(relPath, cli.outputPattern))
+		if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+			return err
+		}
+
+		if !cli.force && isUpToDate(inputPath, outputPath) {
+			fmt.Fprintf(infoOut, "Skipping %s, output is up to date.\n", inputPath)
+			return nil
+		}
+		if tiff {
+			return processTIFF(ctx, inputPath, outputPath, opts, cli.bandHeight)
+		}
+		return processFile(ctx, inputPath, outputPath, opts, cli)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// processArchive processes every image entry of the tar archive at
+// inputPath and writes the results to a tar archive at outputPath, named by
+// outputName. Entries that aren't images are skipped. Every entry is
+// processed through a temporary file, so the input is never unpacked.
+func processArchive(ctx context.Context, inputPath, outputPath string, opts canny.Options, cli cliOptions) {
+	input, err := os.Open(inputPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer input.Close()
+
+	tmpDir, err := ioutil.TempDir("", "canny")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	err = createAtomic(outputPath, func(w io.Writer) error {
+		tr := tar.NewReader(input)
+		tw := tar.NewWriter(w)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if (header.Typeflag != tar.TypeReg) && (header.Typeflag != tar.TypeRegA) {
+				continue
+			}
+			data, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return err
+			}
+			if _, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+				continue
+			}
+
+			entryName := filepath.ToSlash(outputName(filepath.FromSlash(header.Name), cli.outputPattern))
+			entryOutput := filepath.Join(tmpDir, filepath.Base(entryName))
+			entryCli := cli
+			entryCli.inputData = data
+			if err := processFile(ctx, header.Name, entryOutput, opts, entryCli); err != nil {
+				return err
+			}
+
+			result, err := ioutil.ReadFile(entryOutput)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			os.Remove(entryOutput)
+			err = tw.WriteHeader(&tar.Header{
+				Name:    entryName,
+				Mode:    0644,
+				Size:    int64(len(result)),
+				ModTime: header.ModTime,
+			})
+			if err != nil {
+				return err
+			}
+			if _, err := tw.Write(result); err != nil {
+				return err
+			}
+		}
+		return tw.Close()
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// outputName returns the output path of the input at relPath, which is
+// relPath itself without a pattern. In pattern, {name} is the file name of
+// relPath without its extension, {ext} the extension without its dot and
+// {dir} the directory of relPath.
+func outputName(relPath, pattern string) string {
+	if pattern == "" {
+		return relPath
+	}
+	ext := filepath.Ext(relPath)
+	replacer := strings.NewReplacer(
+		"{name}", strings.TrimSuffix(filepath.Base(relPath), ext),
+		"{ext}", strings.TrimPrefix(ext, "."),
+		"{dir}", filepath.Dir(relPath),
+	)
+	return filepath.Clean(replacer.Replace(pattern))
+}
+
+// isArchive reports whether path names a tar archive.
+func isArchive(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".tar")
+}
+
+// isUpToDate reports whether outputPath exists and was modified after
+// inputPath.
+func isUpToDate(inputPath, outputPath string) bool {
+	outputInfo, err := os.Stat(outputPath)
+	if err != nil {
+		return false
+	}
+	inputInfo, err := os.Stat(inputPath)
+	if err != nil {
+		return false
+	}
+
+	return outputInfo.ModTime().After(inputInfo.ModTime())
+}
+
+// isImageFile reports whether the file at path starts with the header of a
+// registered image format.
+func isImageFile(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	_, _, err = image.DecodeConfig(file)
+	return err == nil
+}
+
+func isDirectory(path string) bool {
+	info, err := os.Stat(path)
+	return (err == nil) && info.IsDir()
+}
+
+// isFlagSet reports whether the named flag was given on the command line.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
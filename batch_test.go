@@ -0,0 +1,318 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsUpToDate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "in.png")
+	output := filepath.Join(dir, "out.png")
+	writePNG(t, input, circleImage(16, 16))
+	if isUpToDate(input, output) {
+		t.Error("missing output reported as up to date")
+	}
+
+	writePNG(t, output, circleImage(16, 16))
+	now := time.Now()
+	if err := os.Chtimes(output, now, now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if isUpToDate(input, output) {
+		t.Error("output older than its input reported as up to date")
+	}
+	if err := os.Chtimes(output, now, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if !isUpToDate(input, output) {
+		t.Error("output newer than its input reported as stale")
+	}
+}
+
+func TestDirectoryModeSkipsUpToDateOutputs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputDir := filepath.Join(dir, "in")
+	outputDir := filepath.Join(dir, "out")
+	if err := os.Mkdir(inputDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writePNG(t, filepath.Join(inputDir, "a.png"), circleImage(24, 24))
+	writePNG(t, filepath.Join(inputDir, "b.png"), circleImage(32, 24))
+	if err := ioutil.WriteFile(filepath.Join(inputDir, "notes.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := runMain(t, nil, "-input", inputDir, "-output", outputDir)
+	if strings.Contains(string(out), "Skipping") {
+		t.Errorf("first run skipped inputs:\n%s", out)
+	}
+	for _, name := range []string{"a.png", "b.png"} {
+		if _, err := os.Stat(filepath.Join(outputDir, name)); err != nil {
+			t.Errorf("missing output for %s: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "notes.txt")); err == nil {
+		t.Error("non-image file was processed")
+	}
+
+	// Outputs newer than their inputs are skipped unless -force is given.
+	later := time.Now().Add(time.Hour)
+	for _, name := range []string{"a.png", "b.png"} {
+		if err := os.Chtimes(filepath.Join(outputDir, name), later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out = runMain(t, nil, "-input", inputDir, "-output", outputDir)
+	if n := strings.Count(string(out), "Skipping"); n != 2 {
+		t.Errorf("second run skipped %d inputs, want 2:\n%s", n, out)
+	}
+	out = runMain(t, nil, "-input", inputDir, "-output", outputDir, "-force")
+	if strings.Contains(string(out), "Skipping") {
+		t.Errorf("-force skipped inputs:\n%s", out)
+	}
+}
+
+func TestRecursiveDirectoryMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputDir := filepath.Join(dir, "in")
+	if err := os.MkdirAll(filepath.Join(inputDir, "sub", "deep"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writePNG(t, filepath.Join(inputDir, "a.png"), circleImage(24, 24))
+	writePNG(t, filepath.Join(inputDir, "sub", "b.png"), circleImage(24, 24))
+	writePNG(t, filepath.Join(inputDir, "sub", "deep", "noext"), circleImage(24, 24))
+	// Inputs are recognized by their header, not their extension.
+	if err := ioutil.WriteFile(filepath.Join(inputDir, "sub", "fake.png"), []byte("not an image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	exists := func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}
+
+	flatOutput := filepath.Join(dir, "flat")
+	runMain(t, nil, "-input", inputDir, "-output", flatOutput)
+	if !exists(filepath.Join(flatOutput, "a.png")) || exists(filepath.Join(flatOutput, "sub")) {
+		t.Error("without -recursive only the top level should be processed")
+	}
+
+	// The output directory is inside the input directory and not processed
+	// itself.
+	output := filepath.Join(inputDir, "edges")
+	runMain(t, nil, "-input", inputDir, "-output", output, "-recursive")
+	for _, name := range []string{"a.png", filepath.Join("sub", "b.png"), filepath.Join("sub", "deep", "noext")} {
+		if !exists(filepath.Join(output, name)) {
+			t.Errorf("missing output for %s", name)
+		}
+	}
+	if exists(filepath.Join(output, "sub", "fake.png")) {
+		t.Error("file without an image header was processed")
+	}
+	if exists(filepath.Join(output, "edges")) {
+		t.Error("the output directory was processed as input")
+	}
+}
+
+// writeTar writes a tar archive of the given entries to path. Entries named
+// with a trailing "~" are written with the legacy TypeRegA flag.
+func writeTar(t *testing.T, path string, entries map[string][]byte) {
+	var b bytes.Buffer
+	tw := tar.NewWriter(&b)
+	for name, data := range entries {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}
+		if strings.HasSuffix(name, "~") {
+			header.Typeflag = tar.TypeRegA
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArchiveMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	encode := func(img image.Image) []byte {
+		var b bytes.Buffer
+		if err := png.Encode(&b, img); err != nil {
+			t.Fatal(err)
+		}
+		return b.Bytes()
+	}
+	inputPath := filepath.Join(dir, "in.tar")
+	outputPath := filepath.Join(dir, "out.tar")
+	writeTar(t, inputPath, map[string][]byte{
+		"images/a.png":  encode(circleImage(32, 24)),
+		"images/b.png~": encode(circleImage(16, 16)),
+		"README":        []byte("not an image"),
+	})
+	runMain(t, nil, "-input", inputPath, "-output", outputPath)
+
+	output, err := os.Open(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer output.Close()
+	sizes := map[string]image.Point{}
+	tr := tar.NewReader(output)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		config, _, err := image.DecodeConfig(tr)
+		if err != nil {
+			t.Fatalf("%s: %v", header.Name, err)
+		}
+		sizes[header.Name] = image.Point{config.Width, config.Height}
+	}
+	want := map[string]image.Point{"images/a.png": {32, 24}, "images/b.png~": {16, 16}}
+	if !reflect.DeepEqual(sizes, want) {
+		t.Errorf("got edge maps %v, want %v", sizes, want)
+	}
+}
+
+func TestArchiveModeFailureLeavesNoOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The header decodes, the missing image data doesn't.
+	inputPath := filepath.Join(dir, "in.tar")
+	writeTar(t, inputPath, map[string][]byte{"broken.png": pngHeader(8, 8)})
+	stderr := runMainError(t, "-input", inputPath, "-output", filepath.Join(dir, "out.tar"))
+	if !strings.Contains(stderr, "broken.png") {
+		t.Errorf("expected the error to name the entry, got %q", stderr)
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.Name() != "in.tar" {
+			t.Errorf("left %s behind", entry.Name())
+		}
+	}
+}
+
+func TestOutputName(t *testing.T) {
+	for _, c := range []struct {
+		relPath string
+		pattern string
+		want    string
+	}{
+		{"a.png", "", "a.png"},
+		{filepath.Join("sub", "b.jpg"), "", filepath.Join("sub", "b.jpg")},
+		{"a.png", "{name}_edges.{ext}", "a_edges.png"},
+		{"a.png", "{name}.png", "a.png"},
+		{"photo.JPEG", "{name}.png", "photo.png"},
+		{filepath.Join("sub", "b.jpg"), "{dir}/{name}_edges.{ext}", filepath.Join("sub", "b_edges.jpg")},
+		{filepath.Join("sub", "b.jpg"), "{name}.{ext}", "b.jpg"},
+		{"a.png", "{dir}/{name}.gif", "a.gif"},
+		{"noext", "{name}_edges.{ext}", "noext_edges."},
+		{"archive.tar.png", "{name}-{ext}", "archive.tar-png"},
+	} {
+		if got := outputName(c.relPath, c.pattern); got != c.want {
+			t.Errorf("outputName(%q, %q): got %q, want %q", c.relPath, c.pattern, got, c.want)
+		}
+	}
+}
+
+func TestOutputPatternDirectoryMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-pattern")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputDir := filepath.Join(dir, "in")
+	if err := os.MkdirAll(filepath.Join(inputDir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writePNG(t, filepath.Join(inputDir, "a.png"), circleImage(24, 24))
+	writePNG(t, filepath.Join(inputDir, "sub", "b.png"), circleImage(24, 24))
+
+	output := filepath.Join(dir, "out")
+	runMain(t, nil, "-input", inputDir, "-output", output, "-recursive", "-output-pattern", "{dir}/{name}_edges.png")
+	for _, name := range []string{"a_edges.png", filepath.Join("sub", "b_edges.png")} {
+		data, err := ioutil.ReadFile(filepath.Join(output, name))
+		if err != nil {
+			t.Errorf("missing output %s: %v", name, err)
+			continue
+		}
+		if !bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")) {
+			t.Errorf("%s isn't encoded as PNG", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(output, "a.png")); err == nil {
+		t.Error("output written under the input name despite -output-pattern")
+	}
+
+	archiveInput := filepath.Join(dir, "in.tar")
+	archiveOutput := filepath.Join(dir, "out.tar")
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, circleImage(16, 16)); err != nil {
+		t.Fatal(err)
+	}
+	writeTar(t, archiveInput, map[string][]byte{"images/c.png": encoded.Bytes()})
+	runMain(t, nil, "-input", archiveInput, "-output", archiveOutput, "-output-pattern", "{dir}/{name}.jpg")
+	archive, err := os.Open(archiveOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+	header, err := tar.NewReader(archive).Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Name != "images/c.jpg" {
+		t.Errorf("got archive entry %q, want %q", header.Name, "images/c.jpg")
+	}
+}
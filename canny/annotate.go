@@ -0,0 +1,57 @@
+package canny
+
+import (
+	"image"
+	"image/color"
+)
+
+// GRID_COLOR and SCALEBAR_COLOR are the colors of the measurement
+// annotations.
+var (
+	GRID_COLOR     = color.RGBA{0, 96, 160, 255}
+	SCALEBAR_COLOR = color.RGBA{255, 255, 0, 255}
+)
+
+// SCALEBAR_MARGIN is the distance of the scale bar from the bottom left
+// corner, SCALEBAR_THICKNESS its height in pixels.
+const (
+	SCALEBAR_MARGIN    = 8
+	SCALEBAR_THICKNESS = 3
+)
+
+// DrawGrid draws horizontal and vertical lines every spacing pixels, starting
+// at 0, onto img. Pixels brighter than black are left alone so edges stay
+// visible on top of the grid.
+func DrawGrid(img *image.RGBA, spacing int) {
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if ((x-bounds.Min.X)%spacing != 0) && ((y-bounds.Min.Y)%spacing != 0) {
+				continue
+			}
+			if c := img.RGBAAt(x, y); (c.R != 0) || (c.G != 0) || (c.B != 0) {
+				continue
+			}
+			img.SetRGBA(x, y, GRID_COLOR)
+		}
+	}
+}
+
+// DrawScaleBar draws a bar of the given length in pixels near the bottom left
+// corner of img, with label above it.
+func DrawScaleBar(img *image.RGBA, length int, label string) {
+	bounds := img.Bounds()
+	x0 := bounds.Min.X + SCALEBAR_MARGIN
+	y0 := bounds.Max.Y - SCALEBAR_MARGIN - SCALEBAR_THICKNESS
+	for y := y0; y < y0+SCALEBAR_THICKNESS; y++ {
+		for x := x0; x < x0+length; x++ {
+			if (image.Point{x, y}).In(bounds) {
+				img.SetRGBA(x, y, SCALEBAR_COLOR)
+			}
+		}
+	}
+
+	if label != "" {
+		drawText(img, x0, y0-FONT_HEIGHT-2, label, SCALEBAR_COLOR, 1)
+	}
+}
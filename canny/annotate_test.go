@@ -0,0 +1,73 @@
+package canny
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestDrawGridSpacing(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 30))
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 40; x++ {
+			img.SetRGBA(x, y, color.RGBA{0, 0, 0, 255})
+		}
+	}
+	white := color.RGBA{255, 255, 255, 255}
+	img.SetRGBA(10, 5, white)
+
+	DrawGrid(img, 10)
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 40; x++ {
+			want := color.RGBA{0, 0, 0, 255}
+			if (x%10 == 0) || (y%10 == 0) {
+				want = GRID_COLOR
+			}
+			if (x == 10) && (y == 5) {
+				want = white
+			}
+			if got := img.RGBAAt(x, y); got != want {
+				t.Errorf("(%d, %d): got %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestDrawScaleBarLength(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 60, 40))
+	DrawScaleBar(img, 25, "")
+
+	y0 := 40 - SCALEBAR_MARGIN - SCALEBAR_THICKNESS
+	for y := 0; y < 40; y++ {
+		count := 0
+		for x := 0; x < 60; x++ {
+			if img.RGBAAt(x, y) == SCALEBAR_COLOR {
+				count++
+				if (x < SCALEBAR_MARGIN) || (x >= SCALEBAR_MARGIN+25) {
+					t.Errorf("row %d: bar reaches x = %d", y, x)
+				}
+			}
+		}
+		want := 0
+		if (y >= y0) && (y < y0+SCALEBAR_THICKNESS) {
+			want = 25
+		}
+		if count != want {
+			t.Errorf("row %d: got %d bar pixels, want %d", y, count, want)
+		}
+	}
+
+	labeled := image.NewRGBA(image.Rect(0, 0, 60, 40))
+	DrawScaleBar(labeled, 25, "10um")
+	label := 0
+	for y := 0; y < y0; y++ {
+		for x := 0; x < 60; x++ {
+			if labeled.RGBAAt(x, y) == SCALEBAR_COLOR {
+				label++
+			}
+		}
+	}
+	if label == 0 {
+		t.Error("expected the label above the bar")
+	}
+}
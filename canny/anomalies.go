@@ -0,0 +1,32 @@
+package canny
+
+import (
+	"fmt"
+)
+
+// REFERENCE_EDGE_THRESHOLD is the gray value from which a pixel of a
+// reference edge map counts as an edge. It is half the range, so the ringing
+// of lossy JPEG edge maps doesn't add spurious edges.
+const REFERENCE_EDGE_THRESHOLD = 128
+
+// SubtractEdges clears every edge pixel of edges within tolerance pixels, in
+// both directions, of an edge pixel of reference, leaving only the edges the
+// reference lacks, such as defects of an inspected part. Reference pixels
+// count as edges from REFERENCE_EDGE_THRESHOLD. The edge maps must have the
+// same dimensions.
+func SubtractEdges(edges, reference [][]GrayPixel, tolerance int) ([][]GrayPixel, error) {
+	if (len(edges) != len(reference)) || (len(edges[0]) != len(reference[0])) {
+		return nil, fmt.Errorf("reference edge map is %dx%d, edges are %dx%d", len(reference[0]), len(reference), len(edges[0]), len(edges))
+	}
+
+	mask := make([][]bool, len(reference))
+	for y := range reference {
+		mask[y] = make([]bool, len(reference[y]))
+		for x := range reference[y] {
+			mask[y][x] = reference[y][x].Y >= REFERENCE_EDGE_THRESHOLD
+		}
+	}
+	clearMasked(edges, dilate(mask, tolerance))
+
+	return edges, nil
+}
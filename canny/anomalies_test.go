@@ -0,0 +1,78 @@
+package canny
+
+import (
+	_ "image/jpeg"
+	"testing"
+)
+
+func TestDilate(t *testing.T) {
+	mask := make([][]bool, 7)
+	for y := range mask {
+		mask[y] = make([]bool, 7)
+	}
+	mask[3][3] = true
+	mask[0][6] = true
+	dilated := dilate(mask, 1)
+	for y := range dilated {
+		for x := range dilated[y] {
+			want := ((x >= 2) && (x <= 4) && (y >= 2) && (y <= 4)) || ((x >= 5) && (y <= 1))
+			if dilated[y][x] != want {
+				t.Errorf("(%d, %d): got %t, want %t", x, y, dilated[y][x], want)
+			}
+		}
+	}
+}
+
+func TestSubtractEdges(t *testing.T) {
+	// Vertical edges at x = 3 and x = 8, the reference has one at x = 4.
+	edges := func() [][]GrayPixel {
+		return newPixels(12, 6, func(x, y int) uint8 {
+			if (x == 3) || (x == 8) {
+				return 255
+			}
+			return 0
+		})
+	}
+	reference := newPixels(12, 6, func(x, y int) uint8 {
+		switch x {
+		case 4:
+			return 255
+		case 10:
+			// Ringing below REFERENCE_EDGE_THRESHOLD.
+			return 100
+		}
+		return 0
+	})
+
+	for _, c := range []struct {
+		tolerance int
+		left      bool
+	}{
+		{0, true},
+		{1, false},
+	} {
+		result, err := SubtractEdges(edges(), reference, c.tolerance)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for y := range result {
+			if (result[y][3].Y != 0) != c.left {
+				t.Errorf("tolerance %d, row %d: got %d at the matched edge", c.tolerance, y, result[y][3].Y)
+			}
+			if result[y][8].Y == 0 {
+				t.Errorf("tolerance %d, row %d: the anomaly was removed", c.tolerance, y)
+			}
+		}
+	}
+
+	self, err := SubtractEdges(edges(), edges(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := countEdges(self); got != 0 {
+		t.Errorf("got %d edge pixels subtracting edges from themselves, want none", got)
+	}
+	if _, err := SubtractEdges(edges(), square(6), 1); err == nil {
+		t.Error("expected an error for mismatching dimensions")
+	}
+}
@@ -0,0 +1,53 @@
+package canny
+
+import (
+	"strings"
+)
+
+// ASCIIArt renders an edge map as text of the given width in characters.
+// Every character covers a block of pixels and shows '#' if the block
+// contains an edge. Blocks are twice as tall as wide to make up for the
+// aspect ratio of terminal characters.
+func ASCIIArt(edges [][]GrayPixel, width int) string {
+	srcHeight := len(edges)
+	srcWidth := len(edges[0])
+	height := (srcHeight*width + srcWidth) / (2 * srcWidth)
+	if height < 1 {
+		height = 1
+	}
+
+	var b strings.Builder
+	for y := 0; y < height; y++ {
+		minY, maxY := y*srcHeight/height, (y+1)*srcHeight/height
+		for x := 0; x < width; x++ {
+			minX, maxX := x*srcWidth/width, (x+1)*srcWidth/width
+			if hasEdgeInBlock(edges, minX, minY, maxX, maxY) {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteByte('\n')
+	}
+
+	return b.String()
+}
+
+func hasEdgeInBlock(edges [][]GrayPixel, minX, minY, maxX, maxY int) bool {
+	// Blocks are at least one pixel large when upscaling.
+	if maxX == minX {
+		maxX++
+	}
+	if maxY == minY {
+		maxY++
+	}
+	for y := minY; (y < maxY) && (y < len(edges)); y++ {
+		for x := minX; (x < maxX) && (x < len(edges[y])); x++ {
+			if edges[y][x].Y != 0 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
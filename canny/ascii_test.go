@@ -0,0 +1,31 @@
+package canny
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestASCIIArt(t *testing.T) {
+	edges := newPixels(40, 20, func(x, y int) uint8 {
+		if (x == 0) && (y == 0) {
+			return 255
+		}
+		return 0
+	})
+	lines := strings.Split(strings.TrimSuffix(ASCIIArt(edges, 10), "\n"), "\n")
+	// Characters are twice as tall as wide, so 10 columns cover 40 pixels and
+	// 2.5 rows cover 20.
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3", len(lines))
+	}
+	for y, line := range lines {
+		if len(line) != 10 {
+			t.Errorf("line %d: got width %d, want 10", y, len(line))
+		}
+		for x, c := range line {
+			if (c == '#') != ((x == 0) && (y == 0)) {
+				t.Errorf("(%d, %d): got %q", x, y, c)
+			}
+		}
+	}
+}
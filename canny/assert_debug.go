@@ -0,0 +1,10 @@
+//go:build cannydebug
+// +build cannydebug
+
+package canny
+
+// assertInvariant panics with the given message. Debug builds (-tags cannydebug)
+// use it to catch internal invariant violations at the point they happen.
+func assertInvariant(msg string) {
+	panic("invariant violated: " + msg)
+}
@@ -0,0 +1,7 @@
+//go:build !cannydebug
+// +build !cannydebug
+
+package canny
+
+// assertInvariant is a no-op in release builds, callers return an error instead.
+func assertInvariant(msg string) {}
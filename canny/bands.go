@@ -0,0 +1,104 @@
+package canny
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// BandReader returns the rows [minY, maxY) of an image, such as TIFF.ReadRows.
+type BandReader func(minY, maxY int) ([][]GrayPixel, error)
+
+// DetectBands detects the edges of a width x height image that is read in
+// bands of bandHeight rows, so that only one band of the input is resident at
+// a time. Every band is read with a margin of rows above and below it, which
+// the blur, the gradient and the non-maximum suppression need to compute the
+// band exactly as DetectStages would. Only the thinned magnitudes of the whole
+// image are kept for the thresholds and the edge tracking, so the result
+// equals the edges of DetectStages on the full image.
+func DetectBands(ctx context.Context, width, height, bandHeight int, read BandReader, opts Options) ([][]GrayPixel, error) {
+	if (width <= 0) || (height <= 0) {
+		return nil, errors.New("image must not be empty")
+	}
+	if bandHeight <= 0 {
+		return nil, errors.New("band height must be positive")
+	}
+	if opts.Deterministic && (opts.Operator.X != nil) && !opts.Operator.Integer() {
+		return nil, ErrNonIntegerOperator
+	}
+	if opts.PreErode > 0 {
+		return nil, errors.New("pre-erosion needs the thresholds of the whole image and can't run in bands")
+	}
+
+	suppressed, err := suppressBands(ctx, width, height, bandHeight, read, opts)
+	if err != nil {
+		return nil, err
+	}
+	stages, err := detectFromSuppressed(ctx, suppressed, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return stages.Edges, nil
+}
+
+// bandMargin returns the number of rows above and below a band that the
+// local stages of the pipeline read: the radius of the blur, the radius of
+// the gradient operator and the row on either side that the non-maximum
+// suppression compares with.
+func bandMargin(opts Options) int {
+	op := opts.Operator
+	if op.X == nil {
+		op = SOBEL
+	}
+	margin := op.Size()/2 + 1
+	if opts.Blur {
+		margin += BLUR_KERNEL_SIZE / 2
+	}
+	return margin
+}
+
+// suppressBands runs the pipeline up to the non-maximum suppression band by
+// band and returns the thinned magnitudes of the whole image.
+func suppressBands(ctx context.Context, width, height, bandHeight int, read BandReader, opts Options) ([][]GrayPixel, error) {
+	margin := bandMargin(opts)
+	suppressed := make([][]GrayPixel, height)
+
+	for minY := 0; minY < height; minY += bandHeight {
+		maxY := minInt(height, minY+bandHeight)
+		readMinY := maxInt(0, minY-margin)
+		readMaxY := minInt(height, maxY+margin)
+
+		pixels, err := read(readMinY, readMaxY)
+		if err != nil {
+			return nil, err
+		}
+		if len(pixels) != readMaxY-readMinY {
+			return nil, fmt.Errorf("band of rows [%d, %d) has %d rows", readMinY, readMaxY, len(pixels))
+		}
+		for _, row := range pixels {
+			if len(row) != width {
+				return nil, fmt.Errorf("band of rows [%d, %d) has a row of %d pixels, want %d", readMinY, readMaxY, len(row), width)
+			}
+		}
+
+		blurred, err := blurPixels(ctx, pixels, opts)
+		if err != nil {
+			return nil, err
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		magnitudes, angles, err := gradientPixels(ctx, blurred, opts)
+		if err != nil {
+			return nil, err
+		}
+		_, bandSuppressed, err := suppressGradients(ctx, magnitudes, angles, opts)
+		if err != nil {
+			return nil, err
+		}
+		copy(suppressed[minY:maxY], bandSuppressed[minY-readMinY:maxY-readMinY])
+	}
+
+	return suppressed, nil
+}
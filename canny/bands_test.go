@@ -0,0 +1,69 @@
+package canny
+
+import (
+	"context"
+	"testing"
+)
+
+// testImage returns a 37x29 image of a bright disc and a ramp, with edges in
+// every direction.
+func testImage() [][]GrayPixel {
+	return newPixels(37, 29, func(x, y int) uint8 {
+		if (x-15)*(x-15)+(y-13)*(y-13) < 80 {
+			return 220
+		}
+		return uint8(3*x + 2*y)
+	})
+}
+
+// pixelReader reads the bands of pixels.
+func pixelReader(pixels [][]GrayPixel) BandReader {
+	return func(minY, maxY int) ([][]GrayPixel, error) {
+		band := make([][]GrayPixel, maxY-minY)
+		for y := range band {
+			band[y] = append([]GrayPixel(nil), pixels[minY+y]...)
+		}
+		return band, nil
+	}
+}
+
+func TestDetectBandsMatchesFullImage(t *testing.T) {
+	pixels := testImage()
+	cases := []struct {
+		name string
+		opts Options
+	}{
+		{"default", Options{Blur: true, MinRatio: 0.2, MaxRatio: 0.6}},
+		{"no blur", Options{MinRatio: 0.2, MaxRatio: 0.6}},
+		{"central", Options{Blur: true, MinRatio: 0.2, MaxRatio: 0.6, Operator: CENTRAL_DIFFERENCE}},
+		{"floor and border", Options{Blur: true, MinRatio: 0.1, MaxRatio: 0.3, MaxBorder: 3, MagnitudeFloor: 20}},
+	}
+
+	for _, c := range cases {
+		want, err := CannyEdgeDetectContext(context.Background(), pixels, c.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, bandHeight := range []int{1, 5, 8, 29, 100} {
+			got, err := DetectBands(context.Background(), 37, 29, bandHeight, pixelReader(pixels), c.opts)
+			if err != nil {
+				t.Fatalf("%s, bands of %d: %v", c.name, bandHeight, err)
+			}
+			if !equalPixels(got, want) {
+				t.Errorf("%s, bands of %d: edges differ from the full image", c.name, bandHeight)
+			}
+		}
+	}
+}
+
+func TestDetectBandsRejectsWrongBand(t *testing.T) {
+	read := func(minY, maxY int) ([][]GrayPixel, error) {
+		return newPixels(36, maxY-minY, func(x, y int) uint8 { return 0 }), nil
+	}
+	if _, err := DetectBands(context.Background(), 37, 29, 8, read, Options{}); err == nil {
+		t.Error("expected an error for bands of the wrong width")
+	}
+	if _, err := DetectBands(context.Background(), 37, 29, 0, pixelReader(testImage()), Options{}); err == nil {
+		t.Error("expected an error for an empty band height")
+	}
+}
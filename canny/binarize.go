@@ -0,0 +1,77 @@
+package canny
+
+// OtsuThreshold returns the gray value that best separates the histogram of
+// pixels into two classes by maximizing the between-class variance. Pixels
+// up to the threshold form the dark class.
+func OtsuThreshold(pixels [][]GrayPixel) uint8 {
+	var histogram [256]int
+	total := 0
+	sum := 0
+	for y := 0; y < len(pixels); y++ {
+		for x := 0; x < len(pixels[y]); x++ {
+			histogram[pixels[y][x].Y]++
+			total++
+			sum += int(pixels[y][x].Y)
+		}
+	}
+
+	var best uint8
+	var bestVariance float64
+	darkCount, darkSum := 0, 0
+	for t := 0; t < 256; t++ {
+		darkCount += histogram[t]
+		darkSum += t * histogram[t]
+		lightCount := total - darkCount
+		if (darkCount == 0) || (lightCount == 0) {
+			continue
+		}
+		darkMean := float64(darkSum) / float64(darkCount)
+		lightMean := float64(sum-darkSum) / float64(lightCount)
+		variance := float64(darkCount) * float64(lightCount) * (darkMean - lightMean) * (darkMean - lightMean)
+		if variance > bestVariance {
+			bestVariance = variance
+			best = uint8(t)
+		}
+	}
+
+	return best
+}
+
+// BinarizeDark marks every pixel at or below the Otsu threshold of pixels,
+// such as text on a light background, as 255 and all others as 0.
+func BinarizeDark(pixels [][]GrayPixel) [][]GrayPixel {
+	threshold := OtsuThreshold(pixels)
+	var result [][]GrayPixel
+	for y := 0; y < len(pixels); y++ {
+		resultRow := make([]GrayPixel, 0, len(pixels[y]))
+		for x := 0; x < len(pixels[y]); x++ {
+			value := uint8(0)
+			if pixels[y][x].Y <= threshold {
+				value = uint8(255)
+			}
+			resultRow = append(resultRow, GrayPixel{value, uint8(255)})
+		}
+		result = append(result, resultRow)
+	}
+
+	return result
+}
+
+// CombineMasks returns the pixel-wise maximum of two maps of the same
+// dimensions, the union of binary masks.
+func CombineMasks(a, b [][]GrayPixel) [][]GrayPixel {
+	var result [][]GrayPixel
+	for y := 0; y < len(a); y++ {
+		resultRow := make([]GrayPixel, 0, len(a[y]))
+		for x := 0; x < len(a[y]); x++ {
+			r := a[y][x]
+			if b[y][x].Y > r.Y {
+				r = b[y][x]
+			}
+			resultRow = append(resultRow, r)
+		}
+		result = append(result, resultRow)
+	}
+
+	return result
+}
@@ -0,0 +1,55 @@
+package canny
+
+import (
+	"testing"
+)
+
+func TestOtsuThreshold(t *testing.T) {
+	pixels := newPixels(10, 10, func(x, y int) uint8 {
+		if x < 3 {
+			return uint8(20 + y)
+		}
+		return uint8(200 + y)
+	})
+	if got := OtsuThreshold(pixels); (got < 29) || (got >= 200) {
+		t.Errorf("got threshold %d, want one between the classes", got)
+	}
+}
+
+func TestWithBinaryHasGlyphsAndEdges(t *testing.T) {
+	// Two dark strokes of an "L" on white.
+	glyph := func(x, y int) bool {
+		return ((x >= 8) && (x < 12) && (y >= 6) && (y < 26)) || ((x >= 8) && (x < 22) && (y >= 22) && (y < 26))
+	}
+	pixels := newPixels(32, 32, func(x, y int) uint8 {
+		if glyph(x, y) {
+			return 10
+		}
+		return 240
+	})
+	edges, err := CannyEdgeDetect(pixels, true, 0.1, 0.3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mask := CombineMasks(edges, BinarizeDark(pixels))
+
+	outline := 0
+	for y := range mask {
+		for x := range mask[y] {
+			if glyph(x, y) && (mask[y][x].Y != 255) {
+				t.Errorf("(%d, %d): glyph missing from the mask", x, y)
+			}
+			if edges[y][x].Y != 0 {
+				if mask[y][x].Y == 0 {
+					t.Errorf("(%d, %d): edge missing from the mask", x, y)
+				}
+				if !glyph(x, y) {
+					outline++
+				}
+			}
+		}
+	}
+	if outline == 0 {
+		t.Error("expected edges outside the glyph in the mask")
+	}
+}
@@ -0,0 +1,75 @@
+package canny
+
+import (
+	"context"
+	"testing"
+)
+
+type blurFunc func([][]GrayPixel, uint, RoundingMode, int) ([][]GrayPixel, error)
+
+func blurFuncs() map[string]blurFunc {
+	ctx := context.Background()
+	return map[string]blurFunc{
+		"float": func(pixels [][]GrayPixel, kernelSize uint, rounding RoundingMode, workers int) ([][]GrayPixel, error) {
+			return gaussianBlur(ctx, pixels, kernelSize, rounding, workers), nil
+		},
+		"fixed": func(pixels [][]GrayPixel, kernelSize uint, rounding RoundingMode, workers int) ([][]GrayPixel, error) {
+			return gaussianBlurFixed(ctx, pixels, kernelSize, rounding, workers)
+		},
+		"linear": func(pixels [][]GrayPixel, kernelSize uint, rounding RoundingMode, workers int) ([][]GrayPixel, error) {
+			return gaussianBlurLinear(ctx, pixels, kernelSize, rounding, workers), nil
+		},
+	}
+}
+
+func TestBlurFlatImage(t *testing.T) {
+	for _, level := range []uint8{100, 240} {
+		pixels := newPixels(9, 7, func(x, y int) uint8 { return level })
+		for name, blur := range blurFuncs() {
+			blurred, err := blur(pixels, BLUR_KERNEL_SIZE, ROUND_NEAREST, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !equalPixels(blurred, pixels) {
+				t.Errorf("%s blur changed a flat image of %d", name, level)
+			}
+		}
+	}
+}
+
+func TestBlurParallelMatchesSerial(t *testing.T) {
+	pixels := noisePixels(53, 41, 7)
+	for name, blur := range blurFuncs() {
+		for _, rounding := range []RoundingMode{ROUND_NEAREST, ROUND_TRUNCATE} {
+			serial, err := blur(pixels, BLUR_KERNEL_SIZE, rounding, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, workers := range []int{0, 2, 3, 8, 64} {
+				parallel, err := blur(pixels, BLUR_KERNEL_SIZE, rounding, workers)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !equalPixels(parallel, serial) {
+					t.Errorf("%s blur with %d workers differs from the serial blur", name, workers)
+				}
+			}
+		}
+	}
+}
+
+func TestBlurFixedMatchesFloat(t *testing.T) {
+	pixels := noisePixels(31, 23, 3)
+	float := gaussianBlur(context.Background(), pixels, BLUR_KERNEL_SIZE, ROUND_TRUNCATE, 1)
+	fixed, err := gaussianBlurFixed(context.Background(), pixels, BLUR_KERNEL_SIZE, ROUND_TRUNCATE, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := range float {
+		for x := range float[y] {
+			if d := int(float[y][x].Y) - int(fixed[y][x].Y); (d < -1) || (d > 1) {
+				t.Fatalf("(%d, %d): float %d, fixed %d", x, y, float[y][x].Y, fixed[y][x].Y)
+			}
+		}
+	}
+}
@@ -0,0 +1,1191 @@
+// Package canny detects edges in images with the Canny edge detector. Images
+// are converted to arrays of GrayPixel with ImageToPixelArray, passed through
+// CannyEdgeDetectContext or DetectStages with a set of Options, and converted
+// back with PixelArrayToImage.
+package canny
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/deckarep/golang-set"
+	"image"
+	"math"
+	"runtime"
+	"sync"
+)
+
+type direction int
+
+const (
+	HORIZONTAL direction = iota
+	VERTICAL
+)
+
+var SOBEL_X = []float64{1, 0, -1, 2, 0, -2, 1, 0, -1}
+var SOBEL_Y = []float64{1, 2, 1, 0, 0, 0, -1, -2, -1}
+
+// Options configures the edge detection pipeline.
+type Options struct {
+	// Blur enables the gaussian blur before computing gradients.
+	Blur bool
+	// MinRatio and MaxRatio are the lower and upper hysteresis thresholds
+	// relative to the maximum gradient magnitude.
+	MinRatio float64
+	MaxRatio float64
+	// Operator is the gradient operator, the zero value selects SOBEL.
+	Operator Operator
+	// MaxBorder is the width of the frame at the image border that is ignored
+	// when looking for the maximum magnitude the thresholds are scaled by.
+	MaxBorder int
+	// RefMax, if set, replaces the maximum magnitude the thresholds are
+	// scaled by with a fixed reference, so edge maps of differently bright
+	// images stay comparable.
+	RefMax float64
+	// MagnitudeFloor zeroes every gradient magnitude below it before
+	// non-maximum suppression, independent of the thresholds.
+	MagnitudeFloor uint8
+	// Percentile, if set, derives the upper threshold from the given
+	// percentile (0, 100) of the non-zero magnitudes after non-maximum
+	// suppression instead of MaxRatio. The lower threshold keeps the
+	// proportion MinRatio/MaxRatio to it.
+	Percentile float64
+	// NMSTieBreak keeps only the first pixel in scan order among neighbours
+	// whose magnitudes differ by at most NMSTolerance during non-maximum
+	// suppression, instead of keeping all of them.
+	NMSTieBreak  bool
+	NMSTolerance uint8
+	// NMSInterpolation selects how the neighbour magnitudes along the
+	// gradient are sampled during non-maximum suppression. BinRule only
+	// applies to NMS_DISCRETE.
+	NMSInterpolation NMSInterpolation
+	// ParallelHysteresis tracks edges by labeling connected components and
+	// processing them concurrently. The result is the same as the sequential
+	// tracking, which is usually faster on sparse edge maps.
+	ParallelHysteresis bool
+	// BinRule decides the direction bin of gradient angles exactly on a bin
+	// boundary during non-maximum suppression.
+	BinRule BinRule
+	// Deterministic computes the blur and the gradients with integer
+	// arithmetic, producing bit-identical results on every platform. It
+	// requires an operator with integer coefficients.
+	Deterministic bool
+	// RadialCenter, if set, replaces the gradient magnitude with its
+	// component along the direction from the center to every pixel, which
+	// favours circular edges around it. It takes precedence over
+	// Deterministic for the gradients.
+	RadialCenter *image.Point
+	// Rounding decides how the blurred values and the gradient magnitudes
+	// are quantized to gray values, the zero value rounds to nearest.
+	Rounding RoundingMode
+	// Norm combines the X and Y gradients into the magnitude, the zero value
+	// selects NORM_L2.
+	Norm GradientNorm
+	// AngleRange, if set, zeroes every pixel after non-maximum suppression
+	// whose gradient orientation lies outside [lo, hi] degrees, see
+	// filterAngleRange.
+	AngleRange *[2]float64
+	// BorderValid zeroes the gradient magnitude within half the operator
+	// size of the border, where the kernel would reach into the padding, so
+	// only true convolutions of interior pixels can become edges.
+	BorderValid bool
+	// PreErode erodes the gradient magnitudes above the low threshold that
+	// many times with a 3x3 square before non-maximum suppression, pruning
+	// responses thinner than 2*PreErode+1 pixels. It trades sensitivity for
+	// cleanliness, see erodeCandidates.
+	PreErode int
+	// Strict makes DetectStages return an *InvariantError instead of
+	// panicking when an internal invariant is violated, such as mismatching
+	// dimensions or a non-finite gradient direction. Panics in the
+	// goroutines of ParallelHysteresis are not recovered.
+	Strict bool
+	// Seeds are treated as strong points by the edge tracking regardless of
+	// their magnitude, so weak edges connected to them are promoted too.
+	// Seeds below the upper threshold are raised to it, seeds outside the
+	// image are ignored.
+	Seeds []image.Point
+	// LinearBlur blurs in linear light instead of on the gamma encoded gray
+	// values and encodes the result again before the gradients, so edges
+	// aren't darkened by the blur while the thresholds keep referring to
+	// gamma encoded values. It takes precedence over Deterministic for the
+	// blur.
+	LinearBlur bool
+	// Thinning selects how edges are thinned, the zero value is the
+	// non-maximum suppression of THINNING_NMS.
+	Thinning Thinning
+	// Workers is the number of goroutines the blur and ParallelHysteresis
+	// split the image between, the zero value starts one per CPU. The result
+	// doesn't depend on it.
+	Workers int
+}
+
+// Thinning selects how the edges are thinned to lines.
+type Thinning int
+
+const (
+	// THINNING_NMS suppresses every pixel with a higher neighbour along its
+	// gradient direction before thresholding.
+	THINNING_NMS Thinning = iota
+	// THINNING_SKELETON skips the suppression and reduces the thresholded
+	// edges to a one pixel wide skeleton with the Zhang-Suen algorithm,
+	// regardless of the gradient.
+	THINNING_SKELETON
+	// THINNING_NONE skips the suppression and keeps the edges as thick as
+	// the thresholded gradient response.
+	THINNING_NONE
+)
+
+// THINNINGS maps the names accepted on the command line to the thinnings.
+var THINNINGS = map[string]Thinning{
+	"nms":      THINNING_NMS,
+	"skeleton": THINNING_SKELETON,
+	"none":     THINNING_NONE,
+}
+
+func CannyEdgeDetect(pixels [][]GrayPixel, blur bool, minRatio, maxRatio float64) ([][]GrayPixel, error) {
+	opts := Options{Blur: blur, MinRatio: minRatio, MaxRatio: maxRatio}
+	return CannyEdgeDetectContext(context.Background(), pixels, opts)
+}
+
+// CannyEdgeDetectContext is like CannyEdgeDetect but aborts with the context's
+// error once ctx is done. Cancellation is checked between the pipeline stages
+// and between the rows of every stage.
+func CannyEdgeDetectContext(ctx context.Context, pixels [][]GrayPixel, opts Options) ([][]GrayPixel, error) {
+	stages, err := DetectStages(ctx, pixels, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return stages.Edges, nil
+}
+
+// Detect detects the edges of img. The returned image has the same bounds
+// as img, so edges line up with the input even if img.Bounds().Min isn't the
+// origin, such as for a SubImage. 16-bit grayscale images are detected on
+// their full precision like with DetectStages16.
+func Detect(img image.Image, opts Options) (*image.Gray, error) {
+	var stages *Stages
+	var err error
+	if samples := Gray16Samples(img); samples != nil {
+		stages, err = DetectStages16(context.Background(), samples, opts)
+	} else {
+		stages, err = DetectStages(context.Background(), ImageToPixelArray(img), opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := PixelArrayToImage(stages.Edges)
+	if err != nil {
+		return nil, err
+	}
+	result.Rect = result.Rect.Add(img.Bounds().Min)
+	return result, nil
+}
+
+// Stages holds the intermediate results of a single detection run.
+type Stages struct {
+	// Magnitudes is the gradient magnitude of every pixel before non-maximum
+	// suppression.
+	Magnitudes [][]GrayPixel
+	// Directions is the gradient direction of every pixel in degrees,
+	// modulo 180 in [-90, 90).
+	Directions [][]float64
+	// Strong and Weak are the points classified by the double threshold,
+	// before edge tracking.
+	Strong mapset.Set
+	Weak   mapset.Set
+	// Suppressed is the gradient magnitude after non-maximum suppression,
+	// before thresholding.
+	Suppressed [][]GrayPixel
+	// Edges is the final edge map.
+	Edges [][]GrayPixel
+}
+
+// InvariantError reports an internal invariant violation that DetectStages
+// recovered in strict mode. Value is the value the pipeline panicked with,
+// which names the offending dimensions or coordinates.
+type InvariantError struct {
+	Value interface{}
+}
+
+func (e *InvariantError) Error() string {
+	return fmt.Sprintf("invariant violated: %v", e.Value)
+}
+
+// DetectStages runs the edge detection pipeline and returns the final edges
+// along with the intermediate results.
+func DetectStages(ctx context.Context, pixels [][]GrayPixel, opts Options) (*Stages, error) {
+	detect := func() (*Stages, error) { return detectStages(ctx, pixels, opts) }
+	if opts.Strict {
+		return detectStrict(detect)
+	}
+	return detect()
+}
+
+// ResumeFromGradients runs the pipeline from non-maximum suppression onwards
+// on previously computed gradient magnitudes and directions, such as those
+// written by WriteRawGradients, skipping the blur and the gradients. The
+// magnitude floor and the border are applied again, which doesn't change
+// magnitudes they were already applied to, so resuming from the Magnitudes
+// and Directions of DetectStages with the same opts reproduces its result.
+func ResumeFromGradients(ctx context.Context, magnitudes [][]GrayPixel, directions [][]float64, opts Options) (*Stages, error) {
+	if err := checkRectangular(magnitudes); err != nil {
+		return nil, err
+	}
+	if (len(directions) != len(magnitudes)) || (len(directions[0]) != len(magnitudes[0])) {
+		return nil, fmt.Errorf("dimensions of magnitudes %dx%d and directions %dx%d must match", len(magnitudes[0]), len(magnitudes), len(directions[0]), len(directions))
+	}
+	detect := func() (*Stages, error) {
+		return detectFromGradients(ctx, clonePixels(magnitudes), directions, opts)
+	}
+	if opts.Strict {
+		return detectStrict(detect)
+	}
+	return detect()
+}
+
+// detectStrict runs detect and turns a panic into an *InvariantError.
+func detectStrict(detect func() (*Stages, error)) (stages *Stages, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			stages, err = nil, &InvariantError{r}
+		}
+	}()
+	return detect()
+}
+
+func detectStages(ctx context.Context, pixels [][]GrayPixel, opts Options) (*Stages, error) {
+	if err := checkRectangular(pixels); err != nil {
+		return nil, err
+	}
+	if opts.Deterministic && (opts.Operator.X != nil) && !opts.Operator.Integer() {
+		return nil, ErrNonIntegerOperator
+	}
+	pixels, err := blurPixels(ctx, pixels, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	pixels, angles, err := gradientPixels(ctx, pixels, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return detectFromGradients(ctx, pixels, angles, opts)
+}
+
+// BLUR_KERNEL_SIZE is the size of the binomial kernel of the blur.
+const BLUR_KERNEL_SIZE = 5
+
+// blurPixels blurs pixels, or returns them unchanged if opts.Blur isn't set.
+func blurPixels(ctx context.Context, pixels [][]GrayPixel, opts Options) ([][]GrayPixel, error) {
+	if !opts.Blur {
+		return pixels, nil
+	}
+	if opts.LinearBlur {
+		return gaussianBlurLinear(ctx, pixels, BLUR_KERNEL_SIZE, opts.Rounding, opts.Workers), nil
+	}
+	if opts.Deterministic {
+		return gaussianBlurFixed(ctx, pixels, BLUR_KERNEL_SIZE, opts.Rounding, opts.Workers)
+	}
+	return gaussianBlur(ctx, pixels, BLUR_KERNEL_SIZE, opts.Rounding, opts.Workers), nil
+}
+
+// gradientPixels computes the gradients of pixels with opts.Operator, projected
+// onto the radii around opts.RadialCenter if it is set, or in fixed-point
+// arithmetic if opts.Deterministic is set.
+func gradientPixels(ctx context.Context, pixels [][]GrayPixel, opts Options) ([][]GrayPixel, [][]float64, error) {
+	if opts.RadialCenter != nil {
+		magnitudes, directions := radialGradient(ctx, pixels, opts.Operator, *opts.RadialCenter, opts.Rounding, opts.Norm)
+		return magnitudes, directions, nil
+	}
+	if opts.Deterministic {
+		return gradientFixed(ctx, pixels, opts.Operator, opts.Rounding, opts.Norm)
+	}
+	magnitudes, directions := gradient(ctx, pixels, opts.Operator, opts.Rounding, opts.Norm)
+	return magnitudes, directions, nil
+}
+
+// DetectStages16 is like DetectStages for 16-bit gray samples. The blur and
+// the gradients are computed on the full precision of the samples, scaled to
+// the range of 8-bit gray values, and only the gradient magnitudes are
+// quantized. Shallow slopes that vanish in an 8-bit image keep their
+// magnitude this way. The fixed-point pipeline of opts.Deterministic works on
+// 8-bit values, so with it set the samples are quantized first.
+func DetectStages16(ctx context.Context, samples [][]uint16, opts Options) (*Stages, error) {
+	if (len(samples) > 0) && (len(samples[0]) == 0) {
+		return nil, ErrEmptyFirstRow
+	}
+	for y := 1; y < len(samples); y++ {
+		if len(samples[y]) != len(samples[0]) {
+			return nil, fmt.Errorf("ragged sample array, row %d has %d samples, row 0 has %d", y, len(samples[y]), len(samples[0]))
+		}
+	}
+	if opts.Deterministic {
+		pixels := make([][]GrayPixel, len(samples))
+		for y, row := range samples {
+			pixels[y] = make([]GrayPixel, len(row))
+			for x, v := range row {
+				pixels[y][x] = GrayPixel{scale16To8(v), 255}
+			}
+		}
+		return DetectStages(ctx, pixels, opts)
+	}
+	values := samplesToValues(samples)
+	if opts.Blur {
+		values = gaussianBlurValues(ctx, values, BLUR_KERNEL_SIZE, opts.Workers)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+	var pixels [][]GrayPixel
+	var angles [][]float64
+	if opts.RadialCenter != nil {
+		pixels, angles = radialGradientValues(ctx, values, opts.Operator, *opts.RadialCenter, opts.Rounding, opts.Norm)
+	} else {
+		pixels, angles = gradientValues(ctx, values, opts.Operator, opts.Rounding, opts.Norm)
+	}
+
+	return detectFromGradients(ctx, pixels, angles, opts)
+}
+
+// detectFromGradients runs the pipeline from the gradient magnitudes and
+// directions onwards.
+func detectFromGradients(ctx context.Context, pixels [][]GrayPixel, angles [][]float64, opts Options) (*Stages, error) {
+	magnitudes, suppressed, err := suppressGradients(ctx, pixels, angles, opts)
+	if err != nil {
+		return nil, err
+	}
+	stages, err := detectFromSuppressed(ctx, suppressed, opts)
+	if err != nil {
+		return nil, err
+	}
+	stages.Magnitudes = magnitudes
+	stages.Directions = angles
+
+	return stages, nil
+}
+
+// suppressGradients applies the magnitude floor to the gradient magnitudes in
+// pixels and thins them. It returns the magnitudes along with the thinned
+// result. Every stage up to here only depends on a small neighbourhood of
+// every pixel.
+func suppressGradients(ctx context.Context, pixels [][]GrayPixel, angles [][]float64, opts Options) ([][]GrayPixel, [][]GrayPixel, error) {
+	if opts.MagnitudeFloor > 0 {
+		applyMagnitudeFloor(pixels, opts.MagnitudeFloor)
+	}
+	if opts.BorderValid {
+		op := opts.Operator
+		if op.X == nil {
+			op = SOBEL
+		}
+		clearBorder(pixels, op.Size()/2)
+	}
+	if opts.PreErode > 0 {
+		erodeCandidates(pixels, opts)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	var suppressed [][]GrayPixel
+	if opts.Thinning == THINNING_NMS {
+		var err error
+		suppressed, err = nonMaximumSuppression(ctx, pixels, angles, nmsTieTolerance(opts), opts.BinRule, opts.NMSInterpolation)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		suppressed = clonePixels(pixels)
+	}
+	if opts.AngleRange != nil {
+		filterAngleRange(suppressed, angles, *opts.AngleRange)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return pixels, suppressed, nil
+}
+
+// detectFromSuppressed thresholds the thinned magnitudes in pixels and tracks
+// the edges. The Magnitudes and Directions of the result are left unset.
+func detectFromSuppressed(ctx context.Context, pixels [][]GrayPixel, opts Options) (*Stages, error) {
+	suppressed := clonePixels(pixels)
+	high, low := thresholds(pixels, opts)
+	strong, weak := doublethreshold(ctx, pixels, high, low)
+	if len(opts.Seeds) > 0 {
+		addSeeds(pixels, strong, weak, opts.Seeds, high)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	stages := &Stages{Strong: strong.Clone(), Weak: weak.Clone(), Suppressed: suppressed}
+	if opts.ParallelHysteresis {
+		edgeTrackingComponents(ctx, pixels, strong, weak, opts.Workers)
+	} else {
+		edgeTracking(ctx, pixels, strong, weak)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if opts.Thinning == THINNING_SKELETON {
+		skeletonizeEdges(pixels)
+	}
+	stages.Edges = pixels
+
+	return stages, nil
+}
+
+// addSeeds adds the seeds inside the image to strong, removes them from weak
+// and raises their magnitude to at least high.
+func addSeeds(pixels [][]GrayPixel, strong, weak mapset.Set, seeds []image.Point, high float64) {
+	bounds := image.Rect(0, 0, len(pixels[0]), len(pixels))
+	value := uint8(math.Min(255, math.Ceil(high)))
+	if value == 0 {
+		value = uint8(1)
+	}
+
+	for _, seed := range seeds {
+		if !seed.In(bounds) {
+			continue
+		}
+		weak.Remove(seed)
+		strong.Add(seed)
+		if pixels[seed.Y][seed.X].Y < value {
+			pixels[seed.Y][seed.X].Y = value
+		}
+	}
+}
+
+// PointsToPixels renders a set of image.Point as white pixels on a black
+// image of the given size.
+func PointsToPixels(points mapset.Set, width, height int) [][]GrayPixel {
+	result := make([][]GrayPixel, height)
+	for y := range result {
+		result[y] = make([]GrayPixel, width)
+		for x := range result[y] {
+			result[y][x] = GrayPixel{uint8(0), uint8(255)}
+		}
+	}
+
+	pointIter := points.Iterator()
+	for p := range pointIter.C {
+		point := p.(image.Point)
+		result[point.Y][point.X].Y = uint8(255)
+	}
+
+	return result
+}
+
+// canceled reports whether ctx is done without blocking. The stages check it
+// between rows and stop early, leaving their result incomplete, so callers
+// must check ctx.Err() before using it.
+func canceled(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// edgeTracking performs the hysteresis: starting from the strong points it
+// follows 8-connected chains of weak points and adds them to strong. Weak
+// points that aren't connected to any strong point are cleared.
+func edgeTracking(ctx context.Context, pixels [][]GrayPixel, strong, weak mapset.Set) {
+	weakMask := pointsToMask(weak, len(pixels[0]), len(pixels))
+
+	var queue []image.Point
+	strongIter := strong.Iterator()
+	for strongPixel := range strongIter.C {
+		queue = append(queue, strongPixel.(image.Point))
+	}
+
+	for len(queue) > 0 {
+		if canceled(ctx) {
+			return
+		}
+		p := queue[0]
+		queue = queue[1:]
+		for _, offset := range NEIGHBOUR_OFFSETS {
+			n := p.Add(offset)
+			if (n.Y < 0) || (n.Y >= len(weakMask)) || (n.X < 0) || (n.X >= len(weakMask[n.Y])) {
+				continue
+			}
+			if weakMask[n.Y][n.X] {
+				weakMask[n.Y][n.X] = false
+				strong.Add(n)
+				queue = append(queue, n)
+			}
+		}
+	}
+
+	if canceled(ctx) {
+		return
+	}
+	clearMasked(pixels, weakMask)
+}
+
+// edgeTrackingComponents is equivalent to edgeTracking, but labels the
+// connected components of the strong and weak points and keeps every
+// component that contains a strong point. Components are checked and cleared
+// concurrently, one band of rows per worker.
+func edgeTrackingComponents(ctx context.Context, pixels [][]GrayPixel, strong, weak mapset.Set, workers int) {
+	height := len(pixels)
+	width := len(pixels[0])
+	strongMask := pointsToMask(strong, width, height)
+	mask := pointsToMask(weak, width, height)
+	for y := range mask {
+		for x := range mask[y] {
+			mask[y][x] = mask[y][x] || strongMask[y][x]
+		}
+	}
+	labels, count := LabelComponents(mask)
+
+	keep := make([]bool, count+1)
+	var mu sync.Mutex
+	forEachRowBand(height, workers, func(minY, maxY int) {
+		bandKeep := make([]bool, count+1)
+		for y := minY; y < maxY; y++ {
+			if canceled(ctx) {
+				break
+			}
+			for x := 0; x < width; x++ {
+				if strongMask[y][x] {
+					bandKeep[labels[y][x]] = true
+				}
+			}
+		}
+		mu.Lock()
+		for label := range bandKeep {
+			keep[label] = keep[label] || bandKeep[label]
+		}
+		mu.Unlock()
+	})
+
+	if canceled(ctx) {
+		return
+	}
+	forEachRowBand(height, workers, func(minY, maxY int) {
+		for y := minY; y < maxY; y++ {
+			if canceled(ctx) {
+				break
+			}
+			for x := 0; x < width; x++ {
+				label := labels[y][x]
+				if (label == 0) || strongMask[y][x] {
+					continue
+				}
+				if keep[label] {
+					strong.Add(image.Point{x, y})
+				} else {
+					pixels[y][x].Y = uint8(0)
+				}
+			}
+		}
+	})
+}
+
+// forEachRowBand splits the rows [0, height) into one band per worker and
+// calls fn for every band concurrently. Zero workers start one per CPU.
+// Bands of columns are split the same way, passing the width as height.
+func forEachRowBand(height, workers int, fn func(minY, maxY int)) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	bandHeight := (height + workers - 1) / workers
+	var wg sync.WaitGroup
+
+	for minY := 0; minY < height; minY += bandHeight {
+		maxY := int(math.Min(float64(height), float64(minY+bandHeight)))
+		wg.Add(1)
+		go func(minY, maxY int) {
+			defer wg.Done()
+			fn(minY, maxY)
+		}(minY, maxY)
+	}
+	wg.Wait()
+}
+
+func pointsToMask(points mapset.Set, width, height int) [][]bool {
+	mask := make([][]bool, height)
+	for y := range mask {
+		mask[y] = make([]bool, width)
+	}
+
+	pointIter := points.Iterator()
+	for p := range pointIter.C {
+		point := p.(image.Point)
+		mask[point.Y][point.X] = true
+	}
+
+	return mask
+}
+
+func clearMasked(pixels [][]GrayPixel, mask [][]bool) {
+	for y := range mask {
+		for x := range mask[y] {
+			if mask[y][x] {
+				pixels[y][x].Y = uint8(0)
+			}
+		}
+	}
+}
+
+func doublethreshold(ctx context.Context, pixels [][]GrayPixel, high, low float64) (mapset.Set, mapset.Set) {
+	strong := mapset.NewSet()
+	weak := mapset.NewSet()
+
+	for y := 0; y < len(pixels); y++ {
+		if canceled(ctx) {
+			break
+		}
+		for x := 0; x < len(pixels[0]); x++ {
+			pixVal := float64(pixels[y][x].Y)
+			if pixVal > high {
+				strong.Add(image.Point{x, y})
+			} else if (high > pixVal) && (pixVal > low) {
+				weak.Add(image.Point{x, y})
+			} else {
+				pixels[y][x].Y = uint8(0)
+			}
+		}
+	}
+
+	return strong, weak
+}
+
+// nonMaximumSuppression zeroes every pixel that has a higher neighbour along
+// its gradient direction. With a non-negative tieTolerance, neighbours within
+// the tolerance count as equal, and of equal pixels only the first in scan
+// order survives, so flat-topped ridges thin to a single pixel. rule bins
+// directions exactly on a bin boundary, interpolation selects how the
+// neighbour magnitudes are sampled.
+func nonMaximumSuppression(ctx context.Context, pixels [][]GrayPixel, directions [][]float64, tieTolerance int, rule BinRule, interpolation NMSInterpolation) ([][]GrayPixel, error) {
+
+	if (len(pixels) != len(directions)) || (len(pixels[0]) != len(directions[0])) {
+		msg := fmt.Sprintf("dimensions of pixel array %dx%d and direction array %dx%d must match", len(pixels[0]), len(pixels), len(directions[0]), len(directions))
+		assertInvariant(msg)
+		return nil, errors.New(msg)
+	}
+	var result [][]GrayPixel
+
+	for y := 0; y < len(pixels); y++ {
+		if canceled(ctx) {
+			break
+		}
+		var resultRow []GrayPixel
+		for x := 0; x < len(pixels[0]); x++ {
+			r := pixels[y][x]
+			if isSuppressedWith(pixels, directions, x, y, tieTolerance, rule, interpolation) {
+				resultRow = append(resultRow, GrayPixel{uint8(0), uint8(255)})
+			} else {
+				resultRow = append(resultRow, r)
+			}
+		}
+		result = append(result, resultRow)
+	}
+
+	return result, nil
+}
+
+// nmsTieTolerance returns the tie tolerance of nonMaximumSuppression for
+// opts, -1 if ties aren't broken.
+func nmsTieTolerance(opts Options) int {
+	if !opts.NMSTieBreak {
+		return -1
+	}
+	return int(opts.NMSTolerance)
+}
+
+func isSuppressed(pixels [][]GrayPixel, directions [][]float64, x, y int, tieTolerance int, rule BinRule) bool {
+	r := int(pixels[y][x].Y)
+	pPoint, qPoint := getPointsInGradientDirection(directions, x, y, rule)
+
+	for _, n := range []image.Point{pPoint, qPoint} {
+		if n == (image.Point{x, y}) {
+			continue
+		}
+		v := int(pixels[n.Y][n.X].Y)
+		if tieTolerance < 0 {
+			if v > r {
+				return true
+			}
+			continue
+		}
+		if v > r+tieTolerance {
+			return true
+		}
+		earlier := (n.Y < y) || ((n.Y == y) && (n.X < x))
+		if earlier && (abs(v-r) <= tieTolerance) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ErrEmptyFirstRow is returned for pixel arrays whose first row is empty,
+// which leaves their width undefined.
+var ErrEmptyFirstRow = errors.New("first row of pixel array is empty")
+
+// ErrEmptyImage is returned for pixel arrays without any rows.
+var ErrEmptyImage = errors.New("pixel array is empty")
+
+// ErrNonIntegerOperator is returned for deterministic detection with an
+// operator whose coefficients aren't integers, such as GD5.
+var ErrNonIntegerOperator = errors.New("deterministic detection requires an operator with integer coefficients")
+
+func clonePixels(pixels [][]GrayPixel) [][]GrayPixel {
+	result := make([][]GrayPixel, len(pixels))
+	for y := range pixels {
+		result[y] = append([]GrayPixel(nil), pixels[y]...)
+	}
+	return result
+}
+
+// checkRectangular reports an error if pixels has no rows, its first row is
+// empty or the rows differ in length.
+func checkRectangular(pixels [][]GrayPixel) error {
+	if len(pixels) == 0 {
+		return ErrEmptyImage
+	}
+	if len(pixels[0]) == 0 {
+		return ErrEmptyFirstRow
+	}
+	for y := 1; y < len(pixels); y++ {
+		if len(pixels[y]) != len(pixels[0]) {
+			return fmt.Errorf("ragged pixel array, row %d has %d pixels, row 0 has %d", y, len(pixels[y]), len(pixels[0]))
+		}
+	}
+	return nil
+}
+
+func sobel(ctx context.Context, pixels [][]GrayPixel) ([][]GrayPixel, [][]float64) {
+	return gradient(ctx, pixels, SOBEL, ROUND_NEAREST, NORM_L2)
+}
+
+// gaussianBlur blurs pixels with the binomial kernel of kernelSize, applied
+// as a horizontal and a vertical pass.
+func gaussianBlur(ctx context.Context, pixels [][]GrayPixel, kernelSize uint, rounding RoundingMode, workers int) [][]GrayPixel {
+	var result [][]GrayPixel
+
+	for _, row := range gaussianBlurValues(ctx, pixelValues(pixels), kernelSize, workers) {
+		resultRow := make([]GrayPixel, len(row))
+		for x, v := range row {
+			resultRow[x] = GrayPixel{quantize(v, rounding), 255}
+		}
+		result = append(result, resultRow)
+	}
+
+	return result
+}
+
+// gaussianBlurValues blurs gray values without quantizing the result.
+func gaussianBlurValues(ctx context.Context, pixels [][]float64, kernelSize uint, workers int) [][]float64 {
+	if kernelSize%2 == 0 {
+		panic(fmt.Errorf("size of kernel must be odd, got %d", kernelSize))
+	}
+	return separableBlur(ctx, pixels, binomialKernel(kernelSize), workers)
+}
+
+// separableBlur convolves values with kernel along the rows and then along
+// the columns, mirroring at the borders. The horizontal pass runs in bands of
+// rows and the vertical pass in bands of columns, every worker writing only
+// its own band, so the result doesn't depend on the number of workers.
+func separableBlur(ctx context.Context, values [][]float64, kernel []float64, workers int) [][]float64 {
+	radius := len(kernel) / 2
+	height := len(values)
+	width := len(values[0])
+
+	horizontal := make([][]float64, height)
+	result := make([][]float64, height)
+	for y := range result {
+		horizontal[y] = make([]float64, width)
+		result[y] = make([]float64, width)
+	}
+
+	forEachRowBand(height, workers, func(minY, maxY int) {
+		for y := minY; (y < maxY) && !canceled(ctx); y++ {
+			for x := 0; x < width; x++ {
+				var sum float64
+				for i, k := range kernel {
+					sum += k * values[y][mirrorIndex(x+i-radius, x, width)]
+				}
+				horizontal[y][x] = sum
+			}
+		}
+	})
+	forEachRowBand(width, workers, func(minX, maxX int) {
+		for x := minX; (x < maxX) && !canceled(ctx); x++ {
+			for y := 0; y < height; y++ {
+				var sum float64
+				for i, k := range kernel {
+					sum += k * horizontal[mirrorIndex(y+i-radius, y, height)][x]
+				}
+				result[y][x] = sum
+			}
+		}
+	})
+
+	return result
+}
+
+func getPixelInGradientDirection(pixels [][]GrayPixel, directions [][]float64, x, y int, rule BinRule) (p, q GrayPixel) {
+	pPoint, qPoint := getPointsInGradientDirection(directions, x, y, rule)
+	p = pixels[pPoint.Y][pPoint.X]
+	q = pixels[qPoint.Y][qPoint.X]
+	return p, q
+}
+
+// RoundingMode decides how real values are quantized to gray values.
+type RoundingMode int
+
+const (
+	// ROUND_NEAREST rounds to the nearest integer, halves away from zero.
+	// This is the default.
+	ROUND_NEAREST RoundingMode = iota
+	// ROUND_TRUNCATE truncates toward zero, like a plain conversion.
+	ROUND_TRUNCATE
+)
+
+// quantize converts v to a gray value with the given rounding, clamping it
+// to [0, 255].
+func quantize(v float64, rounding RoundingMode) uint8 {
+	if rounding == ROUND_NEAREST {
+		v = math.Round(v)
+	}
+	return uint8(math.Max(0, math.Min(v, 255)))
+}
+
+// BinRule decides which direction bin a gradient angle exactly on a bin
+// boundary (±22.5 or ±67.5 degrees) falls into.
+type BinRule int
+
+const (
+	// ROUND_HALF_UP assigns boundary angles to the bin above, so bins are
+	// half-open intervals [lo, hi). This is the default.
+	ROUND_HALF_UP BinRule = iota
+	// ROUND_HALF_DOWN assigns boundary angles to the bin below, so bins are
+	// half-open intervals (lo, hi].
+	ROUND_HALF_DOWN
+)
+
+// DIRECTION_BIN_BOUNDARIES separate the direction bins in degrees.
+var DIRECTION_BIN_BOUNDARIES = []float64{-67.5, -22.5, 22.5, 67.5}
+
+// directionBinIndex returns the direction bin of an angle in degrees: 0 is
+// vertical, 1 anti-diagonal, 2 horizontal and 3 diagonal. Angles are taken
+// modulo 180 degrees, so gradients on either side of the seam at ±90 degrees
+// share the vertical bin.
+func directionBinIndex(angle float64, rule BinRule) int {
+	if math.IsNaN(angle) || math.IsInf(angle, 0) {
+		panic(fmt.Errorf("invalid value for direction %v, not a finite angle", angle))
+	}
+
+	angle = math.Mod(angle, float64(180))
+	if angle < float64(-90) {
+		angle += float64(180)
+	} else if angle >= float64(90) {
+		angle -= float64(180)
+	}
+
+	index := 0
+	for _, boundary := range DIRECTION_BIN_BOUNDARIES {
+		if (angle > boundary) || ((rule == ROUND_HALF_UP) && (angle == boundary)) {
+			index++
+		}
+	}
+	return index % len(DIRECTION_BIN_BOUNDARIES)
+}
+
+// getPointsInGradientDirection returns the positions of the two neighbours of
+// (x, y) along its gradient direction, clamped to the image.
+func getPointsInGradientDirection(directions [][]float64, x, y int, rule BinRule) (p, q image.Point) {
+	var pY, pX, qY, qX int
+	height := len(directions)
+	width := len(directions[0])
+	if math.IsNaN(directions[y][x]) || math.IsInf(directions[y][x], 0) {
+		panic(fmt.Errorf("invalid value for direction %v at (%d, %d), not a finite angle", directions[y][x], x, y))
+	}
+
+	switch directionBinIndex(directions[y][x], rule) {
+	case 0:
+		pY, pX = y-1, x
+		qY, qX = y+1, x
+	case 1:
+		pY, pX = y-1, x+1
+		qY, qX = y+1, x-1
+	case 2:
+		pY, pX = y, x+1
+		qY, qX = y, x-1
+	case 3:
+		pY, pX = y+1, x+1
+		qY, qX = y-1, x-1
+	}
+
+	if (pY < 0) || (pY >= height) {
+		pY = y
+	}
+	if (pX < 0) || (pX >= width) {
+		pX = x
+	}
+	if (qY < 0) || (qY >= height) {
+		qY = y
+	}
+	if (qX < 0) || (qX >= width) {
+		qX = x
+	}
+
+	return image.Point{pX, pY}, image.Point{qX, qY}
+}
+
+func getSurroundingPixelMatrix(pixels [][]float64, posY, posX int, length int) matrix {
+	if length%2 == 0 {
+		panic(fmt.Errorf("length must be odd number, got %d at (%d, %d)", length, posX, posY))
+	}
+
+	var currentPixel float64
+	padding := (length / 2)
+
+	minX := posX - padding
+	minY := posY - padding
+	maxX := posX + padding
+	maxY := posY + padding
+	height := len(pixels)
+	width := len(pixels[0])
+
+	var curY, curX int
+	var values = make([]float64, 0, (maxY-minY+1)*(maxX-minX+1))
+
+	for y := minY; y <= maxY; y++ {
+		curY = mirrorIndex(y, posY, height)
+		for x := minX; x <= maxX; x++ {
+			curX = mirrorIndex(x, posX, width)
+
+			currentPixel = pixels[curY][curX]
+			values = append(values, currentPixel)
+		}
+	}
+
+	return newMatrix(length, length, values)
+}
+
+// mirrorIndex maps index i of a kernel centered at pos into [0, length) by
+// mirroring it around pos. Images smaller than the kernel mirror beyond the
+// opposite border, so the result is clamped to the image.
+func mirrorIndex(i, pos, length int) int {
+	if i < 0 {
+		i = pos + abs(i)
+	} else if i >= length {
+		overlap := i - length + 1
+		i = pos - overlap
+	}
+	if i < 0 {
+		return 0
+	}
+	if i >= length {
+		return length - 1
+	}
+	return i
+}
+
+func getPixelVector(pixels [][]float64, posY, posX int, length int, dir direction) vector {
+	if length%2 == 0 {
+		panic(fmt.Errorf("length must be odd number, got %d at (%d, %d)", length, posX, posY))
+	}
+
+	var values []float64
+	var currentPixel float64
+	padding := (length / 2)
+
+	switch dir {
+	case HORIZONTAL:
+		minX := posX - padding
+		maxX := posX + padding
+		values = make([]float64, 0, maxX-minX+1)
+		for i := minX; i <= maxX; i++ {
+			currentPixel = pixels[posY][mirrorIndex(i, posX, len(pixels[posY]))]
+			values = append(values, currentPixel)
+
+		}
+	case VERTICAL:
+		minY := posY - padding
+		maxY := posY + padding
+		values = make([]float64, 0, maxY-minY+1)
+		for i := minY; i <= maxY; i++ {
+			currentPixel = pixels[mirrorIndex(i, posY, len(pixels))][posX]
+			values = append(values, currentPixel)
+		}
+	}
+
+	return newVector(len(values), values)
+}
+
+func innerProduct(pixels, kernel vector) float64 {
+	if pixels.Len() != kernel.Len() {
+		panic(fmt.Errorf("length of given vectors must be equal, got %d and %d", pixels.Len(), kernel.Len()))
+	}
+
+	var result float64 = 0
+	for i := 0; i < pixels.Len(); i++ {
+		result += pixels.At(i, 0) * kernel.At(i, 0)
+	}
+
+	return result
+}
+
+func convolve(m1, m2 matrix) float64 {
+	row_1, col_1 := m1.Dims()
+	row_2, col_2 := m2.Dims()
+	if row_1 != row_2 || col_1 != col_2 {
+		panic(fmt.Errorf("invalid matrix dimensions %dx%d and %dx%d for convolution operation", row_1, col_1, row_2, col_2))
+	}
+
+	var result float64 = 0
+	rows, cols := m1.Dims()
+
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols; x++ {
+			result += m1.At(y, x) * m2.At(y, x)
+		}
+	}
+
+	return result
+}
+
+// pixelValues returns the gray values of pixels.
+func pixelValues(pixels [][]GrayPixel) [][]float64 {
+	values := make([][]float64, len(pixels))
+	for y := range pixels {
+		values[y] = make([]float64, len(pixels[y]))
+		for x := range pixels[y] {
+			values[y][x] = float64(pixels[y][x].Y)
+		}
+	}
+	return values
+}
+
+// samplesToValues scales 16-bit samples to the range of 8-bit gray values,
+// keeping their fraction.
+func samplesToValues(samples [][]uint16) [][]float64 {
+	values := make([][]float64, len(samples))
+	for y := range samples {
+		values[y] = make([]float64, len(samples[y]))
+		for x := range samples[y] {
+			values[y][x] = float64(samples[y][x]) * 255 / 65535
+		}
+	}
+	return values
+}
+
+// thresholds returns the upper and lower hysteresis thresholds for the
+// suppressed magnitudes.
+func thresholds(pixels [][]GrayPixel, opts Options) (high, low float64) {
+	if opts.Percentile > 0 {
+		high = percentileMagnitude(pixels, opts.Percentile)
+		if opts.MaxRatio == 0 {
+			return high, 0
+		}
+		return high, high * opts.MinRatio / opts.MaxRatio
+	}
+
+	max := opts.RefMax
+	if max == 0 {
+		max = float64(maxPixelValue(pixels, opts.MaxBorder))
+	}
+	high = opts.MaxRatio * max
+	low = opts.MinRatio * max
+	return high, low
+}
+
+// percentileMagnitude returns the smallest magnitude that at least p percent
+// of the non-zero magnitudes don't exceed.
+func percentileMagnitude(pixels [][]GrayPixel, p float64) float64 {
+	var histogram [256]int
+	count := 0
+	for y := 0; y < len(pixels); y++ {
+		for x := 0; x < len(pixels[y]); x++ {
+			if v := pixels[y][x].Y; v != 0 {
+				histogram[v]++
+				count++
+			}
+		}
+	}
+
+	target := p / 100 * float64(count)
+	cumulative := 0
+	for v := 1; v < len(histogram); v++ {
+		cumulative += histogram[v]
+		if float64(cumulative) >= target {
+			return float64(v)
+		}
+	}
+
+	return float64(255)
+}
+
+func applyMagnitudeFloor(pixels [][]GrayPixel, floor uint8) {
+	for y := 0; y < len(pixels); y++ {
+		for x := 0; x < len(pixels[y]); x++ {
+			if pixels[y][x].Y < floor {
+				pixels[y][x].Y = uint8(0)
+			}
+		}
+	}
+}
+
+// clearBorder zeroes a frame of the given width at each side of pixels.
+func clearBorder(pixels [][]GrayPixel, width int) {
+	for y := 0; y < len(pixels); y++ {
+		for x := 0; x < len(pixels[y]); x++ {
+			if (y < width) || (y >= len(pixels)-width) || (x < width) || (x >= len(pixels[y])-width) {
+				pixels[y][x].Y = uint8(0)
+			}
+		}
+	}
+}
+
+// maxPixelValue returns the maximum gray value, ignoring a frame of border
+// pixels at each side. The whole image is scanned if the frame would cover it.
+func maxPixelValue(pixels [][]GrayPixel, border int) uint8 {
+	if (border < 0) || (2*border >= len(pixels)) || (2*border >= len(pixels[0])) {
+		border = 0
+	}
+	var max uint8 = 0
+	for y := border; y < len(pixels)-border; y++ {
+		for x := border; x < len(pixels[0])-border; x++ {
+			pixVal := pixels[y][x].Y
+			if pixVal > max {
+				max = pixVal
+			}
+		}
+	}
+
+	return max
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return (-x)
+	} else {
+		return x
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
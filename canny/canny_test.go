@@ -0,0 +1,770 @@
+package canny
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newPixels returns an opaque pixel array of the given size with the gray
+// values of f.
+func newPixels(width, height int, f func(x, y int) uint8) [][]GrayPixel {
+	pixels := make([][]GrayPixel, height)
+	for y := range pixels {
+		pixels[y] = make([]GrayPixel, width)
+		for x := range pixels[y] {
+			pixels[y][x] = GrayPixel{f(x, y), 255}
+		}
+	}
+	return pixels
+}
+
+// equalPixels reports whether a and b have the same size and gray values.
+func equalPixels(a, b [][]GrayPixel) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for y := range a {
+		if len(a[y]) != len(b[y]) {
+			return false
+		}
+		for x := range a[y] {
+			if a[y][x].Y != b[y][x].Y {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestNonMaximumSuppressionDimensionMismatch(t *testing.T) {
+	pixels := newPixels(4, 3, func(x, y int) uint8 { return uint8(x * y) })
+	directions := make([][]float64, 2)
+	for y := range directions {
+		directions[y] = make([]float64, 4)
+	}
+
+	// Debug builds panic on the violated invariant instead of returning.
+	defer func() {
+		if r := recover(); r != nil {
+			if msg, ok := r.(string); !ok || !strings.Contains(msg, "dimensions") {
+				t.Errorf("unexpected panic %v", r)
+			}
+		}
+	}()
+	result, err := nonMaximumSuppression(context.Background(), pixels, directions, -1, ROUND_HALF_UP, NMS_DISCRETE)
+	if err == nil {
+		t.Fatal("expected an error for mismatching dimensions")
+	}
+	if result != nil {
+		t.Errorf("expected no result, got %d rows", len(result))
+	}
+}
+
+func TestCannyEdgeDetectContextDeadline(t *testing.T) {
+	pixels := newPixels(64, 64, func(x, y int) uint8 { return uint8(x * y) })
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	_, err := CannyEdgeDetectContext(ctx, pixels, Options{Blur: true, MinRatio: 0.2, MaxRatio: 0.6})
+	if err != context.DeadlineExceeded {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestStagesStopBetweenRows(t *testing.T) {
+	pixels := newPixels(16, 16, func(x, y int) uint8 { return uint8(16 * x) })
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if blurred := gaussianBlur(ctx, pixels, 5, ROUND_NEAREST, 0); countEdges(blurred) != 0 {
+		t.Error("blur computed values after cancellation")
+	}
+	magnitudes, directions := sobel(ctx, pixels)
+	if (len(magnitudes) != 0) || (len(directions) != 0) {
+		t.Errorf("gradient computed %d rows after cancellation", len(magnitudes))
+	}
+	directions = make([][]float64, 16)
+	for y := range directions {
+		directions[y] = make([]float64, 16)
+	}
+	if suppressed, err := nonMaximumSuppression(ctx, pixels, directions, -1, ROUND_HALF_UP, NMS_DISCRETE); (err != nil) || (len(suppressed) != 0) {
+		t.Errorf("suppression computed %d rows after cancellation, error %v", len(suppressed), err)
+	}
+	strong, weak := doublethreshold(ctx, pixels, 200, 100)
+	if (strong.Cardinality() != 0) || (weak.Cardinality() != 0) {
+		t.Errorf("threshold classified %d points after cancellation", strong.Cardinality()+weak.Cardinality())
+	}
+	strong.Add(image.Point{2, 3})
+	weak.Add(image.Point{3, 3})
+	weak.Add(image.Point{9, 9})
+	edgeTracking(ctx, pixels, strong, weak)
+	if strong.Contains(image.Point{3, 3}) || (pixels[9][9].Y == 0) {
+		t.Error("edge tracking visited a weak point after cancellation")
+	}
+}
+
+// square returns a size x size image with a bright square in its middle.
+func square(size int) [][]GrayPixel {
+	return newPixels(size, size, func(x, y int) uint8 {
+		if (x >= size/4) && (x < 3*size/4) && (y >= size/4) && (y < 3*size/4) {
+			return 200
+		}
+		return 20
+	})
+}
+
+func TestDetectStagesThresholdMaps(t *testing.T) {
+	stages, err := DetectStages(context.Background(), square(16), Options{MinRatio: 0.2, MaxRatio: 0.6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stages.Strong.Cardinality() == 0 {
+		t.Fatal("expected strong points")
+	}
+	if stages.Strong.Intersect(stages.Weak).Cardinality() != 0 {
+		t.Error("strong and weak points overlap")
+	}
+	for y := range stages.Edges {
+		for x := range stages.Edges[y] {
+			p := image.Point{x, y}
+			if (stages.Edges[y][x].Y != 0) && !stages.Strong.Contains(p) && !stages.Weak.Contains(p) {
+				t.Errorf("edge at %v is neither strong nor weak", p)
+			}
+		}
+	}
+
+	strong := PointsToPixels(stages.Strong, 16, 16)
+	for y := range strong {
+		for x := range strong[y] {
+			if want := stages.Strong.Contains(image.Point{x, y}); (strong[y][x].Y == 255) != want {
+				t.Errorf("(%d, %d): got %d, want strong %v", x, y, strong[y][x].Y, want)
+			}
+		}
+	}
+}
+
+func TestGradients16KeepShallowSlopes(t *testing.T) {
+	// A ramp rising by less than a third of an 8-bit step per pixel.
+	samples := make([][]uint16, 8)
+	quantized := make([][]GrayPixel, 8)
+	for y := range samples {
+		samples[y] = make([]uint16, 32)
+		quantized[y] = make([]GrayPixel, 32)
+		for x := range samples[y] {
+			samples[y][x] = uint16(20000 + 80*x)
+			quantized[y][x] = GrayPixel{uint8((uint32(samples[y][x])*255 + 32767) / 65535), 255}
+		}
+	}
+
+	magnitudes16, _ := gradientValues(context.Background(), samplesToValues(samples), SOBEL, ROUND_NEAREST, NORM_L2)
+	magnitudes8, _ := sobel(context.Background(), quantized)
+	differing := 0
+	for y := 1; y < len(samples)-1; y++ {
+		for x := 1; x < len(samples[y])-1; x++ {
+			if magnitudes16[y][x].Y != magnitudes16[1][1].Y {
+				t.Errorf("(%d, %d): got magnitude %d of the uniform ramp, want %d", x, y, magnitudes16[y][x].Y, magnitudes16[1][1].Y)
+			}
+			if magnitudes16[y][x].Y != magnitudes8[y][x].Y {
+				differing++
+			}
+		}
+	}
+	if magnitudes16[1][1].Y == 0 {
+		t.Error("16-bit magnitudes of the ramp vanished")
+	}
+	if differing == 0 {
+		t.Error("16-bit magnitudes equal those of the quantized ramp")
+	}
+
+	stages, err := DetectStages16(context.Background(), samples, Options{Blur: true, MinRatio: 0.2, MaxRatio: 0.6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (len(stages.Edges) != len(samples)) || (len(stages.Edges[0]) != len(samples[0])) {
+		t.Errorf("got %dx%d edges, want %dx%d", len(stages.Edges[0]), len(stages.Edges), len(samples[0]), len(samples))
+	}
+}
+
+func TestMaxPixelValueIgnoresBorder(t *testing.T) {
+	pixels := newPixels(8, 6, func(x, y int) uint8 {
+		if x == 0 {
+			return 250
+		}
+		return uint8(10*x + y)
+	})
+	for _, c := range []struct {
+		border int
+		want   uint8
+	}{
+		{0, 250},
+		{1, 64},
+		{2, 53},
+		{3, 250},
+		{-1, 250},
+	} {
+		if got := maxPixelValue(pixels, c.border); got != c.want {
+			t.Errorf("border %d: got %d, want %d", c.border, got, c.want)
+		}
+	}
+}
+
+func TestMaxBorderKeepsWeakInteriorEdges(t *testing.T) {
+	// A faint square inside a frame with a strong border artifact.
+	pixels := newPixels(32, 32, func(x, y int) uint8 {
+		if (x < 2) || (y < 2) {
+			return 100
+		}
+		if (x >= 10) && (x < 22) && (y >= 10) && (y < 22) {
+			return 56
+		}
+		return 40
+	})
+	countInterior := func(opts Options) int {
+		stages, err := DetectStages(context.Background(), pixels, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		count := 0
+		for y := 6; y < 26; y++ {
+			for x := 6; x < 26; x++ {
+				if stages.Edges[y][x].Y != 0 {
+					count++
+				}
+			}
+		}
+		return count
+	}
+
+	if n := countInterior(Options{MinRatio: 0.2, MaxRatio: 0.6}); n != 0 {
+		t.Fatalf("got %d interior edge pixels without -max-border, want the artifact to hide them", n)
+	}
+	if countInterior(Options{MinRatio: 0.2, MaxRatio: 0.6, MaxBorder: 4}) == 0 {
+		t.Error("expected interior edges with the border excluded")
+	}
+}
+
+func TestMagnitudeFloorRemovesFaintEdges(t *testing.T) {
+	// A strong square on the left and a faint one on the right.
+	pixels := newPixels(40, 20, func(x, y int) uint8 {
+		if (y >= 6) && (y < 14) && (x >= 4) && (x < 12) {
+			return 100
+		}
+		if (y >= 6) && (y < 14) && (x >= 26) && (x < 34) {
+			return 34
+		}
+		return 30
+	})
+	count := func(edges [][]GrayPixel, minX, maxX int) int {
+		n := 0
+		for y := range edges {
+			for x := minX; x < maxX; x++ {
+				if edges[y][x].Y != 0 {
+					n++
+				}
+			}
+		}
+		return n
+	}
+
+	opts := Options{MinRatio: 0.02, MaxRatio: 0.05}
+	stages, err := DetectStages(context.Background(), pixels, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count(stages.Edges, 20, 40) == 0 {
+		t.Fatal("expected edges of the faint square without a floor")
+	}
+
+	opts.MagnitudeFloor = 40
+	stages, err = DetectStages(context.Background(), pixels, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := count(stages.Edges, 20, 40); n != 0 {
+		t.Errorf("got %d edge pixels of the faint square above the floor, want none", n)
+	}
+	if count(stages.Edges, 0, 20) == 0 {
+		t.Error("expected the edges of the strong square to survive the floor")
+	}
+}
+
+func TestPercentileThresholds(t *testing.T) {
+	// Non-zero magnitudes 1 to 100, once each, around zeros.
+	pixels := newPixels(20, 10, func(x, y int) uint8 {
+		if i := y*20 + x; i < 100 {
+			return uint8(i + 1)
+		}
+		return 0
+	})
+	if got := percentileMagnitude(pixels, 50); got != 50 {
+		t.Errorf("50th percentile: got %v, want 50", got)
+	}
+	if got := percentileMagnitude(pixels, 90.5); got != 91 {
+		t.Errorf("90.5th percentile: got %v, want 91", got)
+	}
+
+	high, low := thresholds(pixels, Options{MinRatio: 0.2, MaxRatio: 0.8, Percentile: 75})
+	if (high != 75) || (math.Abs(low-18.75) > 1e-9) {
+		t.Errorf("percentile thresholds: got %v and %v, want 75 and 18.75", high, low)
+	}
+	high, low = thresholds(pixels, Options{MinRatio: 0.2, MaxRatio: 0.8})
+	if (high != 80) || (math.Abs(low-20) > 1e-9) {
+		t.Errorf("ratio thresholds: got %v and %v, want 80 and 20", high, low)
+	}
+}
+
+func TestNonMaximumSuppressionTieBreak(t *testing.T) {
+	// A vertical ridge two pixels wide with magnitudes 100 and 99, and
+	// horizontal gradients everywhere.
+	pixels := newPixels(8, 4, func(x, y int) uint8 {
+		switch x {
+		case 3:
+			return 100
+		case 4:
+			return 99
+		}
+		return 10
+	})
+	directions := make([][]float64, 4)
+	for y := range directions {
+		directions[y] = make([]float64, 8)
+	}
+	ridge := func(result [][]GrayPixel) []uint8 {
+		var kept []uint8
+		for x := 0; x < 8; x++ {
+			if result[1][x].Y >= 99 {
+				kept = append(kept, uint8(x))
+			}
+		}
+		return kept
+	}
+
+	cases := []struct {
+		tolerance int
+		want      []uint8
+	}{
+		// Without tie-breaking 99 is lower than its neighbour.
+		{-1, []uint8{3}},
+		// 99 and 100 tie, and the first one in scan order survives.
+		{1, []uint8{3}},
+	}
+	for _, c := range cases {
+		result, err := nonMaximumSuppression(context.Background(), pixels, directions, c.tolerance, ROUND_HALF_UP, NMS_DISCRETE)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ridge(result); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("tolerance %d: kept %v, want %v", c.tolerance, got, c.want)
+		}
+	}
+
+	// An equal ridge is kept whole without tie-breaking and thinned with it.
+	flat := newPixels(8, 4, func(x, y int) uint8 {
+		if (x == 3) || (x == 4) {
+			return 100
+		}
+		return 10
+	})
+	for _, c := range []struct {
+		tolerance int
+		want      []uint8
+	}{{-1, []uint8{3, 4}}, {0, []uint8{3}}} {
+		result, err := nonMaximumSuppression(context.Background(), flat, directions, c.tolerance, ROUND_HALF_UP, NMS_DISCRETE)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ridge(result); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("flat ridge, tolerance %d: kept %v, want %v", c.tolerance, got, c.want)
+		}
+	}
+}
+
+func TestDirectionBinIndexBoundaries(t *testing.T) {
+	cases := []struct {
+		angle    float64
+		up, down int
+	}{
+		{-90, 0, 0},
+		{-67.5, 1, 0},
+		{-45, 1, 1},
+		{-22.5, 2, 1},
+		{0, 2, 2},
+		{22.5, 3, 2},
+		{67.5, 0, 3},
+		{89.9, 0, 0},
+	}
+	for _, c := range cases {
+		if got := directionBinIndex(c.angle, ROUND_HALF_UP); got != c.up {
+			t.Errorf("%v rounded up: got bin %d, want %d", c.angle, got, c.up)
+		}
+		if got := directionBinIndex(c.angle, ROUND_HALF_DOWN); got != c.down {
+			t.Errorf("%v rounded down: got bin %d, want %d", c.angle, got, c.down)
+		}
+	}
+}
+
+func TestRefMaxComparesImagesWithDifferentMaxima(t *testing.T) {
+	// Both images hold the same faint disc, but the second one also has a
+	// high contrast speck in its corner that raises its maximum magnitude.
+	disc := func(x, y int) bool { return (x-14)*(x-14)+(y-14)*(y-14) < 50 }
+	speck := func(x, y int) bool { return (x >= 34) && (y >= 34) }
+	plain := newPixels(40, 40, func(x, y int) uint8 {
+		if disc(x, y) {
+			return 60
+		}
+		return 30
+	})
+	specked := newPixels(40, 40, func(x, y int) uint8 {
+		switch {
+		case speck(x, y):
+			return 85
+		case disc(x, y):
+			return 60
+		}
+		return 30
+	})
+	discEdges := func(opts Options, pixels [][]GrayPixel) []image.Point {
+		edges, err := CannyEdgeDetectContext(context.Background(), pixels, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var points []image.Point
+		for y := 0; y < 30; y++ {
+			for x := 0; x < 30; x++ {
+				if edges[y][x].Y != 0 {
+					points = append(points, image.Point{x, y})
+				}
+			}
+		}
+		return points
+	}
+
+	opts := Options{MinRatio: 0.4, MaxRatio: 0.6}
+	if reflect.DeepEqual(discEdges(opts, plain), discEdges(opts, specked)) {
+		t.Fatal("expected the speck to change the edges of the disc without a reference maximum")
+	}
+	opts.RefMax = 150
+	plainEdges := discEdges(opts, plain)
+	if len(plainEdges) == 0 {
+		t.Fatal("expected edges around the disc")
+	}
+	if got := discEdges(opts, specked); !reflect.DeepEqual(got, plainEdges) {
+		t.Errorf("got %d disc edge pixels with the speck, want the same %d as without", len(got), len(plainEdges))
+	}
+}
+
+func TestRaggedPixelArray(t *testing.T) {
+	ragged := square(8)
+	ragged[2] = ragged[2][:5]
+	check := func(name string, err error) {
+		if (err == nil) || !strings.Contains(err.Error(), "row 2 has 5") {
+			t.Errorf("%s: got %v, want an error naming the ragged row", name, err)
+		}
+	}
+
+	_, err := DetectStages(context.Background(), ragged, Options{Blur: true})
+	check("DetectStages", err)
+	_, err = CannyEdgeDetect(ragged, false, 0.1, 0.3)
+	check("CannyEdgeDetect", err)
+	_, err = ExplainPixel(context.Background(), ragged, Options{}, 1, 1)
+	check("ExplainPixel", err)
+
+	samples := [][]uint16{make([]uint16, 8), make([]uint16, 8), make([]uint16, 5)}
+	_, err = DetectStages16(context.Background(), samples, Options{})
+	check("DetectStages16", err)
+}
+
+func TestEmptyFirstRow(t *testing.T) {
+	pixels := [][]GrayPixel{{}, {{1, 255}}}
+	check := func(name string, err error) {
+		if err != ErrEmptyFirstRow {
+			t.Errorf("%s: got %v, want %v", name, err, ErrEmptyFirstRow)
+		}
+	}
+
+	_, err := DetectStages(context.Background(), pixels, Options{Blur: true})
+	check("DetectStages", err)
+	_, err = CannyEdgeDetect(pixels, true, 0.05, 0.09)
+	check("CannyEdgeDetect", err)
+	_, err = ExplainPixel(context.Background(), pixels, Options{}, 0, 1)
+	check("ExplainPixel", err)
+	_, err = DetectStages16(context.Background(), [][]uint16{{}, {1}}, Options{})
+	check("DetectStages16", err)
+	_, err = PixelArrayToImage(pixels)
+	check("PixelArrayToImage", err)
+}
+
+func TestQuantize(t *testing.T) {
+	for _, c := range []struct {
+		v        float64
+		rounding RoundingMode
+		want     uint8
+	}{
+		{127.9, ROUND_NEAREST, 128},
+		{127.9, ROUND_TRUNCATE, 127},
+		{127.5, ROUND_NEAREST, 128},
+		{127.4, ROUND_NEAREST, 127},
+		{300, ROUND_NEAREST, 255},
+		{300, ROUND_TRUNCATE, 255},
+		{-3, ROUND_TRUNCATE, 0},
+	} {
+		if got := quantize(c.v, c.rounding); got != c.want {
+			t.Errorf("quantize(%v, %d): got %d, want %d", c.v, c.rounding, got, c.want)
+		}
+	}
+
+	// sqrt(127.9²) through the magnitude of a gradient.
+	if got := magnitude(127.9, 0, ROUND_NEAREST, NORM_L2); got != 128 {
+		t.Errorf("got magnitude %d, want 128", got)
+	}
+	if got := magnitude(127.9, 0, ROUND_TRUNCATE, NORM_L2); got != 127 {
+		t.Errorf("got magnitude %d, want 127", got)
+	}
+}
+
+func TestBorderValidClearsFrame(t *testing.T) {
+	for _, op := range []Operator{SOBEL, CENTRAL_DIFFERENCE} {
+		opts := Options{MinRatio: 0.1, MaxRatio: 0.3, Operator: op}
+		padded, err := DetectStages(context.Background(), noisePixels(20, 16, 7), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts.BorderValid = true
+		valid, err := DetectStages(context.Background(), noisePixels(20, 16, 7), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for y := range valid.Edges {
+			for x := range valid.Edges[y] {
+				frame := (x == 0) || (y == 0) || (x == 19) || (y == 15)
+				if frame && ((valid.Edges[y][x].Y != 0) || (valid.Magnitudes[y][x].Y != 0)) {
+					t.Errorf("%s (%d, %d): got edge %d and magnitude %d in the frame", op.Name, x, y, valid.Edges[y][x].Y, valid.Magnitudes[y][x].Y)
+				}
+				if !frame && (valid.Magnitudes[y][x] != padded.Magnitudes[y][x]) {
+					t.Errorf("%s (%d, %d): got magnitude %d, padded run has %d", op.Name, x, y, valid.Magnitudes[y][x].Y, padded.Magnitudes[y][x].Y)
+				}
+			}
+		}
+	}
+}
+
+// detectSeeds are valid and malformed images that TestDetectRandomInputs
+// mutates.
+func detectSeeds(t *testing.T) [][]byte {
+	img, err := PixelArrayToImage(square(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoders := []func(io.Writer, image.Image) error{
+		png.Encode,
+		func(w io.Writer, img image.Image) error { return jpeg.Encode(w, img, &jpeg.Options{Quality: 90}) },
+		func(w io.Writer, img image.Image) error { return gif.Encode(w, img, nil) },
+	}
+	var seeds [][]byte
+	for _, encode := range encoders {
+		var b bytes.Buffer
+		if err := encode(&b, img); err != nil {
+			t.Fatal(err)
+		}
+		seeds = append(seeds, b.Bytes(), b.Bytes()[:b.Len()/2])
+	}
+	return append(seeds, nil, []byte("\x89PNG\r\n\x1a\n"), []byte{0xff, 0xd8, 0xff})
+}
+
+// TestDetectRandomInputs stands in for a fuzz target, which Go 1.13 has no
+// support for. It feeds mutated images and pixel arrays of random, often
+// degenerate, sizes through the pipeline, which must return an error instead
+// of panicking.
+func TestDetectRandomInputs(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	detect := func(pixels [][]GrayPixel, opts Options) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		_, err = CannyEdgeDetectContext(context.Background(), pixels, opts)
+		return err
+	}
+
+	for i, seed := range detectSeeds(t) {
+		for n := 0; n < 50; n++ {
+			data := append([]byte(nil), seed...)
+			for m := rng.Intn(4); (m > 0) && (len(data) > 0); m-- {
+				data[rng.Intn(len(data))] = byte(rng.Intn(256))
+			}
+			if (len(data) > 0) && (rng.Intn(4) == 0) {
+				data = data[:rng.Intn(len(data))]
+			}
+			img, _, err := image.Decode(bytes.NewReader(data))
+			if (err != nil) || (img.Bounds().Dx()*img.Bounds().Dy() > 1<<16) {
+				continue
+			}
+			err = detect(ImageToPixelArray(img), Options{Blur: true, MinRatio: 0.1, MaxRatio: 0.3})
+			if (err != nil) && (err != ErrEmptyImage) && (err != ErrEmptyFirstRow) {
+				t.Errorf("seed %d, mutation %d: %v", i, n, err)
+			}
+		}
+	}
+
+	for n := 0; n < 300; n++ {
+		pixels := make([][]GrayPixel, rng.Intn(6))
+		width := rng.Intn(6)
+		for y := range pixels {
+			w := width
+			if rng.Intn(5) == 0 {
+				w = rng.Intn(6)
+			}
+			pixels[y] = make([]GrayPixel, w)
+			for x := range pixels[y] {
+				pixels[y][x] = GrayPixel{uint8(rng.Intn(256)), 255}
+			}
+		}
+		opts := Options{
+			Blur:             rng.Intn(2) == 0,
+			MinRatio:         0.1,
+			MaxRatio:         0.3,
+			NMSInterpolation: NMSInterpolation(rng.Intn(3)),
+			BorderValid:      rng.Intn(2) == 0,
+		}
+		err := detect(pixels, opts)
+		if (err != nil) && strings.HasPrefix(err.Error(), "panic") {
+			t.Errorf("%d rows of %v with %+v: %v", len(pixels), rowLengths(pixels), opts, err)
+		}
+		if (err == nil) && (checkRectangular(pixels) != nil) {
+			t.Errorf("%d rows of %v: expected an error", len(pixels), rowLengths(pixels))
+		}
+	}
+}
+
+func rowLengths(pixels [][]GrayPixel) []int {
+	lengths := make([]int, len(pixels))
+	for y := range pixels {
+		lengths[y] = len(pixels[y])
+	}
+	return lengths
+}
+
+func TestStrictReturnsInvariantError(t *testing.T) {
+	// A 2x2 operator has no center pixel, which the window lookup rejects.
+	op := Operator{Name: "2x2", X: []float64{-1, 1, -1, 1}, Y: []float64{-1, -1, 1, 1}}
+	opts := Options{MinRatio: 0.1, MaxRatio: 0.3, Operator: op, Strict: true}
+	_, err := DetectStages(context.Background(), square(8), opts)
+	invariant, ok := err.(*InvariantError)
+	if !ok {
+		t.Fatalf("got error %v, want an *InvariantError", err)
+	}
+	if msg := invariant.Error(); !strings.Contains(msg, "got 2 at (0, 0)") {
+		t.Errorf("got %q, want the window length and coordinates", msg)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic without Strict")
+		}
+	}()
+	opts.Strict = false
+	DetectStages(context.Background(), square(8), opts)
+}
+
+func TestSeedsPromoteWeakEdges(t *testing.T) {
+	// A strong step at x = 3 and a weak one at x = 9, too far apart for the
+	// weak one to be connected to the strong one.
+	pixels := func() [][]GrayPixel {
+		return newPixels(14, 10, func(x, y int) uint8 {
+			switch {
+			case x >= 9:
+				return 130
+			case x >= 3:
+				return 120
+			}
+			return 100
+		})
+	}
+	weakEdges := func(stages *Stages) int {
+		count := 0
+		for y := range stages.Edges {
+			for x := 7; x < 12; x++ {
+				if stages.Edges[y][x].Y != 0 {
+					count++
+				}
+			}
+		}
+		return count
+	}
+
+	opts := Options{MinRatio: 0.2, MaxRatio: 0.6}
+	plain, err := DetectStages(context.Background(), pixels(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := weakEdges(plain); got != 0 {
+		t.Fatalf("got %d edge pixels along the weak step without seeds", got)
+	}
+
+	opts.Seeds = []image.Point{{-1, 4}, {14, 4}, {9, 5}}
+	seeded, err := DetectStages(context.Background(), pixels(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := weakEdges(seeded); got < len(seeded.Edges) {
+		t.Errorf("got %d edge pixels along the weak step, want the whole step", got)
+	}
+}
+
+func TestDetectKeepsOffsetBounds(t *testing.T) {
+	pixels := square(16)
+	opts := Options{Blur: true, MinRatio: 0.2, MaxRatio: 0.6}
+	want, err := CannyEdgeDetectContext(context.Background(), pixels, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The square in the middle of a larger image of a different gray, cut out
+	// as a sub-image with its origin at (5, 3).
+	img := image.NewGray(image.Rect(0, 0, 24, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 24; x++ {
+			img.SetGray(x, y, color.Gray{255})
+		}
+	}
+	for y := range pixels {
+		for x := range pixels[y] {
+			img.SetGray(5+x, 3+y, color.Gray{pixels[y][x].Y})
+		}
+	}
+	sub := img.SubImage(image.Rect(5, 3, 21, 19))
+
+	edges, err := Detect(sub, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edges.Bounds() != sub.Bounds() {
+		t.Fatalf("got bounds %v, want %v", edges.Bounds(), sub.Bounds())
+	}
+	for y := range want {
+		for x := range want[y] {
+			if got := edges.GrayAt(5+x, 3+y).Y; got != want[y][x].Y {
+				t.Errorf("(%d, %d): got %d, want %d", x, y, got, want[y][x].Y)
+			}
+		}
+	}
+	if countEdges(want) == 0 {
+		t.Error("expected edges around the square")
+	}
+}
@@ -0,0 +1,108 @@
+package canny
+
+import (
+	"context"
+	"image"
+	"math"
+)
+
+// ChannelCombine selects how DetectChannels merges the edge maps of the red,
+// green and blue channels.
+type ChannelCombine int
+
+const (
+	// CHANNEL_MAX keeps the highest magnitude of the three channels.
+	CHANNEL_MAX ChannelCombine = iota
+	// CHANNEL_AND keeps only edges found in all three channels.
+	CHANNEL_AND
+	// CHANNEL_OR keeps edges found in any channel.
+	CHANNEL_OR
+	// CHANNEL_SUM weighs the magnitudes of the channels by their luma
+	// coefficients in CHANNEL_WEIGHTS and adds them up.
+	CHANNEL_SUM
+)
+
+// CHANNEL_COMBINES maps the names accepted on the command line to the
+// combination strategies.
+var CHANNEL_COMBINES = map[string]ChannelCombine{
+	"max": CHANNEL_MAX,
+	"and": CHANNEL_AND,
+	"or":  CHANNEL_OR,
+	"sum": CHANNEL_SUM,
+}
+
+// CHANNEL_WEIGHTS are the weights of the red, green and blue edge maps for
+// CHANNEL_SUM, the luma coefficients of ITU-R BT.601.
+var CHANNEL_WEIGHTS = [3]float64{0.299, 0.587, 0.114}
+
+// ChannelPixels splits img into its red, green and blue channels, each as a
+// pixel array carrying the alpha of img.
+func ChannelPixels(img image.Image) [3][][]GrayPixel {
+	var channels [3][][]GrayPixel
+	bounds := img.Bounds()
+
+	for c := range channels {
+		channels[c] = make([][]GrayPixel, bounds.Dy())
+	}
+	for y := 0; y < bounds.Dy(); y++ {
+		for c := range channels {
+			channels[c][y] = make([]GrayPixel, bounds.Dx())
+		}
+		for x := 0; x < bounds.Dx(); x++ {
+			r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			alpha := uint8(a >> 8)
+			channels[0][y][x] = GrayPixel{uint8(r >> 8), alpha}
+			channels[1][y][x] = GrayPixel{uint8(g >> 8), alpha}
+			channels[2][y][x] = GrayPixel{uint8(b >> 8), alpha}
+		}
+	}
+
+	return channels
+}
+
+// DetectChannels detects the edges of every channel separately and merges
+// the three edge maps with the given strategy. Edges of CHANNEL_AND and
+// CHANNEL_OR are binary, the other strategies keep magnitudes.
+func DetectChannels(ctx context.Context, channels [3][][]GrayPixel, opts Options, combine ChannelCombine) ([][]GrayPixel, error) {
+	var edges [3][][]GrayPixel
+	for c := range channels {
+		var err error
+		edges[c], err = CannyEdgeDetectContext(ctx, channels[c], opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := edges[0]
+	for y := range result {
+		for x := range result[y] {
+			r, g, b := edges[0][y][x].Y, edges[1][y][x].Y, edges[2][y][x].Y
+			var value uint8
+			switch combine {
+			case CHANNEL_MAX:
+				value = maxUint8(r, maxUint8(g, b))
+			case CHANNEL_AND:
+				if (r != 0) && (g != 0) && (b != 0) {
+					value = uint8(255)
+				}
+			case CHANNEL_OR:
+				if (r != 0) || (g != 0) || (b != 0) {
+					value = uint8(255)
+				}
+			case CHANNEL_SUM:
+				sum := CHANNEL_WEIGHTS[0]*float64(r) + CHANNEL_WEIGHTS[1]*float64(g) + CHANNEL_WEIGHTS[2]*float64(b)
+				value = uint8(math.Min(255, math.Round(sum)))
+			}
+			result[y][x].Y = value
+		}
+	}
+
+	return result, nil
+}
+
+func maxUint8(a, b uint8) uint8 {
+	if a > b {
+		return a
+	}
+	return b
+}
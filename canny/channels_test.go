@@ -0,0 +1,74 @@
+package canny
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// multicolorScene draws a red, a green and a blue square, overlapping a
+// white one, on black.
+func multicolorScene() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 48, 48))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	for _, r := range []struct {
+		rect image.Rectangle
+		c    color.RGBA
+	}{
+		{image.Rect(4, 4, 20, 20), color.RGBA{255, 0, 0, 255}},
+		{image.Rect(28, 4, 44, 20), color.RGBA{0, 255, 0, 255}},
+		{image.Rect(4, 28, 20, 44), color.RGBA{0, 0, 255, 255}},
+		{image.Rect(28, 28, 44, 44), color.RGBA{255, 255, 255, 255}},
+	} {
+		draw.Draw(img, r.rect, image.NewUniform(r.c), image.Point{}, draw.Src)
+	}
+	return img
+}
+
+func TestChannelCombineStrategies(t *testing.T) {
+	channels := ChannelPixels(multicolorScene())
+	opts := Options{MinRatio: 0.1, MaxRatio: 0.3}
+	counts := make(map[ChannelCombine]int)
+	results := make(map[ChannelCombine][][]GrayPixel)
+	for _, combine := range CHANNEL_COMBINES {
+		edges, err := DetectChannels(context.Background(), channels, opts, combine)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results[combine] = edges
+		counts[combine] = countEdges(edges)
+	}
+
+	if (counts[CHANNEL_AND] == 0) || (counts[CHANNEL_AND] >= counts[CHANNEL_OR]) {
+		t.Errorf("got %d edge pixels with and, %d with or", counts[CHANNEL_AND], counts[CHANNEL_OR])
+	}
+	if (counts[CHANNEL_MAX] != counts[CHANNEL_OR]) || (counts[CHANNEL_SUM] != counts[CHANNEL_OR]) {
+		t.Errorf("got %d edge pixels with max and %d with sum, want the %d of or", counts[CHANNEL_MAX], counts[CHANNEL_SUM], counts[CHANNEL_OR])
+	}
+	// Only the white square has edges in every channel.
+	for y := range results[CHANNEL_AND] {
+		for x := range results[CHANNEL_AND][y] {
+			if (results[CHANNEL_AND][y][x].Y != 0) && ((x < 24) || (y < 24)) {
+				t.Errorf("and: unexpected edge at (%d, %d) outside of the white square", x, y)
+			}
+		}
+	}
+}
+
+func TestChannelPixelsOffsetBounds(t *testing.T) {
+	img := image.NewRGBA(image.Rect(5, 7, 8, 9))
+	img.SetRGBA(5, 7, color.RGBA{10, 20, 30, 255})
+	img.SetRGBA(7, 8, color.RGBA{40, 50, 60, 128})
+	channels := ChannelPixels(img)
+	if (len(channels[0]) != 2) || (len(channels[0][0]) != 3) {
+		t.Fatalf("got %dx%d, want 3x2", len(channels[0][0]), len(channels[0]))
+	}
+	if (channels[0][0][0] != GrayPixel{10, 255}) || (channels[1][0][0] != GrayPixel{20, 255}) || (channels[2][0][0] != GrayPixel{30, 255}) {
+		t.Errorf("got %v, %v, %v at the top left", channels[0][0][0], channels[1][0][0], channels[2][0][0])
+	}
+	if (channels[2][1][2] != GrayPixel{60, 128}) {
+		t.Errorf("got %v at the bottom right", channels[2][1][2])
+	}
+}
@@ -0,0 +1,179 @@
+package canny
+
+import (
+	"image"
+	"image/color"
+)
+
+// EdgeMask returns true for every edge (non-zero) pixel of an edge map.
+func EdgeMask(pixels [][]GrayPixel) [][]bool {
+	mask := make([][]bool, len(pixels))
+	for y := range pixels {
+		mask[y] = make([]bool, len(pixels[y]))
+		for x := range pixels[y] {
+			mask[y][x] = pixels[y][x].Y != 0
+		}
+	}
+
+	return mask
+}
+
+// LabelComponents labels the 8-connected components of the set pixels in
+// mask. Labels start at 1, unset pixels are labeled 0. It returns the labels
+// and the number of components.
+func LabelComponents(mask [][]bool) ([][]int, int) {
+	labels := make([][]int, len(mask))
+	for y := range mask {
+		labels[y] = make([]int, len(mask[y]))
+	}
+	count := 0
+
+	for y := range mask {
+		for x := range mask[y] {
+			if !mask[y][x] || (labels[y][x] != 0) {
+				continue
+			}
+			count++
+			labels[y][x] = count
+			stack := []image.Point{{x, y}}
+			for len(stack) > 0 {
+				p := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				for i := p.Y - 1; i <= p.Y+1; i++ {
+					if (i < 0) || (i >= len(mask)) {
+						continue
+					}
+					for j := p.X - 1; j <= p.X+1; j++ {
+						if (j < 0) || (j >= len(mask[i])) {
+							continue
+						}
+						if mask[i][j] && (labels[i][j] == 0) {
+							labels[i][j] = count
+							stack = append(stack, image.Point{j, i})
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return labels, count
+}
+
+// ComponentSizes returns the pixel count of every component, indexed by label.
+// Index 0 counts the unlabeled pixels.
+func ComponentSizes(labels [][]int, count int) []int {
+	sizes := make([]int, count+1)
+	for y := range labels {
+		for x := range labels[y] {
+			sizes[labels[y][x]]++
+		}
+	}
+
+	return sizes
+}
+
+// KeepLargestComponent clears every edge pixel that doesn't belong to the
+// connected edge component with the most pixels.
+func KeepLargestComponent(pixels [][]GrayPixel) [][]GrayPixel {
+	labels, count := LabelComponents(EdgeMask(pixels))
+	if count == 0 {
+		return pixels
+	}
+	sizes := ComponentSizes(labels, count)
+
+	largest := 1
+	for label := 2; label <= count; label++ {
+		if sizes[label] > sizes[largest] {
+			largest = label
+		}
+	}
+
+	for y := range pixels {
+		for x := range pixels[y] {
+			if labels[y][x] != largest {
+				pixels[y][x].Y = uint8(0)
+			}
+		}
+	}
+
+	return pixels
+}
+
+// ComponentBounds returns the bounding box of every 8-connected component of
+// mask, in label order.
+func ComponentBounds(mask [][]bool) []image.Rectangle {
+	labels, count := LabelComponents(mask)
+	bounds := make([]image.Rectangle, count)
+	seen := make([]bool, count)
+
+	for y := range labels {
+		for x := range labels[y] {
+			label := labels[y][x]
+			if label == 0 {
+				continue
+			}
+			pixel := image.Rect(x, y, x+1, y+1)
+			if !seen[label-1] {
+				bounds[label-1] = pixel
+				seen[label-1] = true
+			} else {
+				bounds[label-1] = bounds[label-1].Union(pixel)
+			}
+		}
+	}
+
+	return bounds
+}
+
+// DrawComponentBounds renders the edges in white on black with the bounding
+// box of every connected component outlined in the first OVERLAY_PALETTE
+// color.
+func DrawComponentBounds(pixels [][]GrayPixel) *image.RGBA {
+	img := GrayToRGBA(pixels)
+	c := OVERLAY_PALETTE[0]
+	for _, r := range ComponentBounds(EdgeMask(pixels)) {
+		maxX, maxY := r.Max.X-1, r.Max.Y-1
+		drawLine(img, r.Min.X, r.Min.Y, maxX, r.Min.Y, c)
+		drawLine(img, maxX, r.Min.Y, maxX, maxY, c)
+		drawLine(img, maxX, maxY, r.Min.X, maxY, c)
+		drawLine(img, r.Min.X, maxY, r.Min.X, r.Min.Y, c)
+	}
+
+	return img
+}
+
+// RemoveIsolated clears every edge pixel without an edge pixel in its
+// 8-neighbourhood.
+func RemoveIsolated(pixels [][]GrayPixel) [][]GrayPixel {
+	mask := EdgeMask(pixels)
+	for y := range mask {
+		for x := range mask[y] {
+			if mask[y][x] && (countNeighbours(mask, x, y) == 0) {
+				pixels[y][x].Y = uint8(0)
+			}
+		}
+	}
+
+	return pixels
+}
+
+// PaletteComponents renders every connected edge component in its own color,
+// cycling through OVERLAY_PALETTE, on a black background.
+func PaletteComponents(pixels [][]GrayPixel) *image.RGBA {
+	labels, _ := LabelComponents(EdgeMask(pixels))
+	img := image.NewRGBA(image.Rect(0, 0, len(pixels[0]), len(pixels)))
+
+	for y := range labels {
+		for x := range labels[y] {
+			label := labels[y][x]
+			if label == 0 {
+				img.SetRGBA(x, y, color.RGBA{0, 0, 0, 255})
+			} else {
+				img.SetRGBA(x, y, OVERLAY_PALETTE[(label-1)%len(OVERLAY_PALETTE)])
+			}
+		}
+	}
+
+	return img
+}
@@ -0,0 +1,136 @@
+package canny
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestLabelComponents(t *testing.T) {
+	mask := [][]bool{
+		{true, false, false, true},
+		{false, true, false, true},
+		{false, false, false, false},
+		{true, true, false, true},
+	}
+	labels, count := LabelComponents(mask)
+	if count != 4 {
+		t.Fatalf("got %d components, want 4", count)
+	}
+	if labels[0][0] != labels[1][1] {
+		t.Error("diagonal neighbours got different labels")
+	}
+	if labels[3][0] != labels[3][1] {
+		t.Error("horizontal neighbours got different labels")
+	}
+	if labels[2][2] != 0 {
+		t.Errorf("unset pixel got label %d", labels[2][2])
+	}
+	sizes := ComponentSizes(labels, count)
+	if (sizes[labels[0][3]] != 2) || (sizes[labels[3][3]] != 1) || (sizes[0] != 9) {
+		t.Errorf("got sizes %v", sizes)
+	}
+}
+
+func TestKeepLargestComponentRemovesSpeckles(t *testing.T) {
+	speckles := [][2]int{{3, 3}, {40, 4}, {4, 40}, {40, 40}}
+	pixels := newPixels(48, 48, func(x, y int) uint8 {
+		if (x >= 14) && (x < 34) && (y >= 14) && (y < 34) {
+			return 200
+		}
+		for _, s := range speckles {
+			if (x >= s[0]) && (x < s[0]+3) && (y >= s[1]) && (y < s[1]+3) {
+				return 200
+			}
+		}
+		return 20
+	})
+	edges, err := CannyEdgeDetect(pixels, false, 0.2, 0.6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, before := LabelComponents(EdgeMask(edges))
+	if before < 2 {
+		t.Fatalf("got %d edge components, want the square and the speckles", before)
+	}
+
+	edges = KeepLargestComponent(edges)
+	labels, count := LabelComponents(EdgeMask(edges))
+	if count != 1 {
+		t.Fatalf("got %d edge components, want 1", count)
+	}
+	for y := range labels {
+		for x := range labels[y] {
+			if (labels[y][x] != 0) && ((x < 10) || (x >= 38) || (y < 10) || (y >= 38)) {
+				t.Errorf("edge at (%d, %d) outside of the square survived", x, y)
+			}
+		}
+	}
+}
+
+func TestPaletteComponents(t *testing.T) {
+	// Two separate lines and a background pixel.
+	pixels := newPixels(6, 4, func(x, y int) uint8 {
+		if ((y == 0) && (x < 3)) || ((y == 3) && (x >= 2)) {
+			return 255
+		}
+		return 0
+	})
+	img := PaletteComponents(pixels)
+
+	if got := img.RGBAAt(5, 1); got != (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("background: got %v, want opaque black", got)
+	}
+	first, second := img.RGBAAt(0, 0), img.RGBAAt(5, 3)
+	if (img.RGBAAt(2, 0) != first) || (img.RGBAAt(2, 3) != second) {
+		t.Error("pixels of one component got different colors")
+	}
+	if (first != OVERLAY_PALETTE[0]) || (second != OVERLAY_PALETTE[1]) {
+		t.Errorf("got colors %v and %v, want the first two palette colors", first, second)
+	}
+}
+
+func TestRemoveIsolated(t *testing.T) {
+	// A lone pixel at (1, 1), a diagonal 2-pixel segment at (4, 1) and (5, 2),
+	// and a lone pixel in the corner.
+	isSegment := func(x, y int) bool { return ((x == 4) && (y == 1)) || ((x == 5) && (y == 2)) }
+	edges := newPixels(7, 5, func(x, y int) uint8 {
+		if isSegment(x, y) || ((x == 1) && (y == 1)) || ((x == 6) && (y == 4)) {
+			return 255
+		}
+		return 0
+	})
+	result := RemoveIsolated(edges)
+	for y := range result {
+		for x := range result[y] {
+			if want := isSegment(x, y); (result[y][x].Y != 0) != want {
+				t.Errorf("(%d, %d): got %d, want edge %v", x, y, result[y][x].Y, want)
+			}
+		}
+	}
+}
+
+func TestComponentBounds(t *testing.T) {
+	// An L shape and a diagonal line, not touching each other.
+	mask := EdgeMask(newPixels(12, 10, func(x, y int) uint8 {
+		switch {
+		case (x == 1) && (y >= 1) && (y <= 5):
+			return 255
+		case (y == 5) && (x >= 1) && (x <= 4):
+			return 255
+		case (x >= 7) && (x <= 10) && (y == x-5):
+			return 255
+		}
+		return 0
+	}))
+	bounds := ComponentBounds(mask)
+	want := []image.Rectangle{image.Rect(1, 1, 5, 6), image.Rect(7, 2, 11, 6)}
+	if len(bounds) != len(want) {
+		t.Fatalf("got %v, want %v", bounds, want)
+	}
+	for i := range want {
+		if bounds[i] != want[i] {
+			t.Errorf("component %d: got %v, want %v", i, bounds[i], want[i])
+		}
+	}
+}
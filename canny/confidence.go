@@ -0,0 +1,62 @@
+package canny
+
+import (
+	"image"
+
+	"github.com/deckarep/golang-set"
+)
+
+// CONFIDENCE_HALF_DISTANCE is the connection distance in pixels at which a
+// promoted weak pixel has half the confidence of a strong one.
+const CONFIDENCE_HALF_DISTANCE = 8
+
+// EdgeConfidence encodes the confidence of every edge pixel as a gray value.
+// Strong pixels have the full confidence of 255. Weak pixels promoted by the
+// hysteresis are dimmed by their distance d along 8-connected edge pixels to
+// the nearest strong pixel, to 255/(1+d/CONFIDENCE_HALF_DISTANCE). The
+// distances follow the breadth-first search of edgeTracking. Edge pixels not
+// connected to any strong pixel, such as those added by CombineMasks, get the
+// lowest non-zero confidence of 1.
+func EdgeConfidence(edges [][]GrayPixel, strong mapset.Set) [][]GrayPixel {
+	mask := EdgeMask(edges)
+	result := clonePixels(edges)
+	for y := range result {
+		for x := range result[y] {
+			if mask[y][x] {
+				result[y][x].Y = uint8(1)
+			}
+		}
+	}
+
+	var queue []image.Point
+	distances := make(map[image.Point]int)
+	strongIter := strong.Iterator()
+	for p := range strongIter.C {
+		point := p.(image.Point)
+		if mask[point.Y][point.X] {
+			mask[point.Y][point.X] = false
+			distances[point] = 0
+			queue = append(queue, point)
+		}
+	}
+
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		d := distances[p]
+		result[p.Y][p.X].Y = uint8(255 / (1 + float64(d)/CONFIDENCE_HALF_DISTANCE))
+		for _, offset := range NEIGHBOUR_OFFSETS {
+			n := p.Add(offset)
+			if (n.Y < 0) || (n.Y >= len(mask)) || (n.X < 0) || (n.X >= len(mask[n.Y])) {
+				continue
+			}
+			if mask[n.Y][n.X] {
+				mask[n.Y][n.X] = false
+				distances[n] = d + 1
+				queue = append(queue, n)
+			}
+		}
+	}
+
+	return result
+}
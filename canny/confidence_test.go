@@ -0,0 +1,80 @@
+package canny
+
+import (
+	"context"
+	"image"
+	"testing"
+
+	"github.com/deckarep/golang-set"
+)
+
+func TestEdgeConfidenceFallsWithDistance(t *testing.T) {
+	// A line of 20 edge pixels with a strong seed at its left end, and an
+	// isolated edge pixel below it.
+	edges := newPixels(20, 3, func(x, y int) uint8 {
+		if (y == 0) || ((x == 10) && (y == 2)) {
+			return 100
+		}
+		return 0
+	})
+	strong := mapset.NewSet()
+	strong.Add(image.Point{0, 0})
+
+	confidence := EdgeConfidence(edges, strong)
+	for x := 0; x < 20; x++ {
+		want := uint8(255 / (1 + float64(x)/CONFIDENCE_HALF_DISTANCE))
+		if got := confidence[0][x].Y; got != want {
+			t.Errorf("distance %d: got %d, want %d", x, got, want)
+		}
+		if (x > 0) && (confidence[0][x].Y > confidence[0][x-1].Y) {
+			t.Errorf("distance %d: confidence rises from %d to %d", x, confidence[0][x-1].Y, confidence[0][x].Y)
+		}
+	}
+	if confidence[0][CONFIDENCE_HALF_DISTANCE].Y != 127 {
+		t.Errorf("got %d at the half distance, want 127", confidence[0][CONFIDENCE_HALF_DISTANCE].Y)
+	}
+	if confidence[2][10].Y != 1 {
+		t.Errorf("got %d for the unconnected pixel, want 1", confidence[2][10].Y)
+	}
+	if confidence[1][5].Y != 0 {
+		t.Errorf("got %d off the edges, want 0", confidence[1][5].Y)
+	}
+}
+
+func TestEdgeConfidenceOfDetectedEdges(t *testing.T) {
+	// A step whose contrast fades from left to right, so only its left end
+	// is strong and the rest is promoted by the hysteresis.
+	pixels := newPixels(48, 12, func(x, y int) uint8 {
+		if y >= 6 {
+			return uint8(60 + 140*(48-x)/48)
+		}
+		return 50
+	})
+	stages, err := DetectStages(context.Background(), pixels, Options{MinRatio: 0.1, MaxRatio: 0.8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	confidence := EdgeConfidence(stages.Edges, stages.Strong)
+
+	strongIter := stages.Strong.Iterator()
+	for p := range strongIter.C {
+		point := p.(image.Point)
+		if (stages.Edges[point.Y][point.X].Y != 0) && (confidence[point.Y][point.X].Y != 255) {
+			t.Errorf("strong %v: got confidence %d, want 255", point, confidence[point.Y][point.X].Y)
+		}
+	}
+	dimmer := 0
+	for y := range confidence {
+		for x := range confidence[y] {
+			if (confidence[y][x].Y != 0) != (stages.Edges[y][x].Y != 0) {
+				t.Errorf("(%d, %d): got confidence %d for edge %d", x, y, confidence[y][x].Y, stages.Edges[y][x].Y)
+			}
+			if (confidence[y][x].Y != 0) && (confidence[y][x].Y < 255) {
+				dimmer++
+			}
+		}
+	}
+	if dimmer == 0 {
+		t.Error("expected promoted weak pixels with a lower confidence")
+	}
+}
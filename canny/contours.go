@@ -0,0 +1,100 @@
+package canny
+
+import (
+	"image"
+)
+
+// ExtractContours traces the edge pixels of an edge map into chains of
+// 8-connected points. Chains start at edge endpoints where possible, closed
+// contours start at their first pixel in scan order. Every edge pixel belongs
+// to exactly one chain.
+func ExtractContours(pixels [][]GrayPixel) [][]image.Point {
+	mask := EdgeMask(pixels)
+	visited := make([][]bool, len(mask))
+	for y := range mask {
+		visited[y] = make([]bool, len(mask[y]))
+	}
+	var contours [][]image.Point
+
+	for _, endpointsOnly := range []bool{true, false} {
+		for y := range mask {
+			for x := range mask[y] {
+				if !mask[y][x] || visited[y][x] {
+					continue
+				}
+				if endpointsOnly && (countNeighbours(mask, x, y) != 1) {
+					continue
+				}
+				contours = append(contours, traceContour(mask, visited, image.Point{x, y}))
+			}
+		}
+	}
+
+	return contours
+}
+
+// NEIGHBOUR_OFFSETS lists the 8-neighbourhood, direct neighbours first.
+var NEIGHBOUR_OFFSETS = []image.Point{
+	{1, 0}, {0, 1}, {-1, 0}, {0, -1},
+	{1, 1}, {-1, 1}, {-1, -1}, {1, -1},
+}
+
+func traceContour(mask, visited [][]bool, start image.Point) []image.Point {
+	contour := []image.Point{start}
+	visited[start.Y][start.X] = true
+	current := start
+
+	for {
+		next, ok := nextUnvisited(mask, visited, current)
+		if !ok {
+			return contour
+		}
+		visited[next.Y][next.X] = true
+		contour = append(contour, next)
+		current = next
+	}
+}
+
+func nextUnvisited(mask, visited [][]bool, p image.Point) (image.Point, bool) {
+	for _, offset := range NEIGHBOUR_OFFSETS {
+		n := p.Add(offset)
+		if (n.Y < 0) || (n.Y >= len(mask)) || (n.X < 0) || (n.X >= len(mask[n.Y])) {
+			continue
+		}
+		if mask[n.Y][n.X] && !visited[n.Y][n.X] {
+			return n, true
+		}
+	}
+
+	return image.Point{}, false
+}
+
+func countNeighbours(mask [][]bool, x, y int) int {
+	count := 0
+	for _, offset := range NEIGHBOUR_OFFSETS {
+		i, j := y+offset.Y, x+offset.X
+		if (i < 0) || (i >= len(mask)) || (j < 0) || (j >= len(mask[i])) {
+			continue
+		}
+		if mask[i][j] {
+			count++
+		}
+	}
+
+	return count
+}
+
+// FilterShortContours clears the edge pixels of every contour with fewer than
+// minLength points.
+func FilterShortContours(pixels [][]GrayPixel, minLength int) [][]GrayPixel {
+	for _, contour := range ExtractContours(pixels) {
+		if len(contour) >= minLength {
+			continue
+		}
+		for _, p := range contour {
+			pixels[p.Y][p.X].Y = uint8(0)
+		}
+	}
+
+	return pixels
+}
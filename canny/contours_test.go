@@ -0,0 +1,80 @@
+package canny
+
+import (
+	"image"
+	"testing"
+)
+
+func TestExtractContours(t *testing.T) {
+	// A diagonal line from (1, 1) to (4, 4), a closed 3x3 ring and a dot.
+	edges := newPixels(12, 8, func(x, y int) uint8 {
+		switch {
+		case (x == y) && (x >= 1) && (x <= 4):
+			return 255
+		case (x >= 7) && (x <= 9) && (y >= 1) && (y <= 3) && !((x == 8) && (y == 2)):
+			return 255
+		case (x == 10) && (y == 6):
+			return 255
+		}
+		return 0
+	})
+	contours := ExtractContours(edges)
+	if len(contours) != 3 {
+		t.Fatalf("got %d contours, want 3", len(contours))
+	}
+
+	line := contours[0]
+	if (len(line) != 4) || (line[0] != image.Point{1, 1}) || (line[3] != image.Point{4, 4}) {
+		t.Errorf("got line %v, want it traced from (1, 1) to (4, 4)", line)
+	}
+	if ring := contours[1]; (len(ring) != 8) || (ring[0] != image.Point{7, 1}) {
+		t.Errorf("got ring %v, want 8 points starting at (7, 1)", ring)
+	}
+	if dot := contours[2]; (len(dot) != 1) || (dot[0] != image.Point{10, 6}) {
+		t.Errorf("got %v, want the dot", dot)
+	}
+
+	seen := map[image.Point]bool{}
+	for _, contour := range contours {
+		for i, p := range contour {
+			if seen[p] {
+				t.Errorf("%v is part of more than one chain", p)
+			}
+			seen[p] = true
+			if i > 0 {
+				d := p.Sub(contour[i-1])
+				if (abs(d.X) > 1) || (abs(d.Y) > 1) {
+					t.Errorf("%v doesn't touch %v", p, contour[i-1])
+				}
+			}
+		}
+	}
+}
+
+func TestFilterShortContours(t *testing.T) {
+	// A long line along y = 5 with a stub attached below it, a separate short
+	// segment and a dot.
+	isLine := func(x, y int) bool { return (y == 5) && (x >= 1) && (x <= 20) }
+	edges := newPixels(24, 10, func(x, y int) uint8 {
+		switch {
+		case isLine(x, y):
+			return 255
+		case (x == 10) && ((y == 6) || (y == 7)):
+			return 255
+		case (y == 1) && (x >= 3) && (x <= 5):
+			return 255
+		case (x == 15) && (y == 8):
+			return 255
+		}
+		return 0
+	})
+
+	filtered := FilterShortContours(edges, 5)
+	for y := range filtered {
+		for x := range filtered[y] {
+			if want := isLine(x, y); (filtered[y][x].Y != 0) != want {
+				t.Errorf("(%d, %d): got %d, want edge %v", x, y, filtered[y][x].Y, want)
+			}
+		}
+	}
+}
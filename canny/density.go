@@ -0,0 +1,35 @@
+package canny
+
+import "errors"
+
+// EdgeDensity downsamples an edge map by the given block size. Every output
+// pixel is the fraction of edge pixels in its block of the input, scaled to
+// [0, 255]. Blocks at the right and bottom border may be smaller.
+func EdgeDensity(edges [][]GrayPixel, block int) ([][]GrayPixel, error) {
+	if block <= 0 {
+		return nil, errors.New("density block size must be positive")
+	}
+	height := len(edges)
+	width := len(edges[0])
+	var result [][]GrayPixel
+
+	for by := 0; by < height; by += block {
+		var resultRow []GrayPixel
+		for bx := 0; bx < width; bx += block {
+			count, total := 0, 0
+			for y := by; (y < by+block) && (y < height); y++ {
+				for x := bx; (x < bx+block) && (x < width); x++ {
+					if edges[y][x].Y != 0 {
+						count++
+					}
+					total++
+				}
+			}
+			density := uint8((255*count + total/2) / total)
+			resultRow = append(resultRow, GrayPixel{density, uint8(255)})
+		}
+		result = append(result, resultRow)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,42 @@
+package canny
+
+import (
+	"testing"
+)
+
+func TestEdgeDensity(t *testing.T) {
+	// The top left 4x4 block is full of edges, the top right one is empty
+	// and the bottom left one has a quarter of edge pixels. The bottom
+	// right block is cut to 1x1 by the border.
+	edges := newPixels(5, 5, func(x, y int) uint8 {
+		switch {
+		case (x < 4) && (y < 4):
+			return 255
+		case (y == 4) && (x < 2):
+			return 255
+		}
+		return 0
+	})
+
+	density, err := EdgeDensity(edges, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]uint8{{255, 0}, {128, 0}}
+	if (len(density) != 2) || (len(density[0]) != 2) {
+		t.Fatalf("got a %dx%d map, want 2x2", len(density[0]), len(density))
+	}
+	for y := range want {
+		for x := range want[y] {
+			if density[y][x].Y != want[y][x] {
+				t.Errorf("block (%d, %d): got %d, want %d", x, y, density[y][x].Y, want[y][x])
+			}
+		}
+	}
+
+	for _, block := range []int{0, -3} {
+		if _, err := EdgeDensity(edges, block); err == nil {
+			t.Errorf("expected an error for block size %d", block)
+		}
+	}
+}
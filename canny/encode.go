@@ -0,0 +1,52 @@
+package canny
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+// EncodeOptions configures Encode.
+type EncodeOptions struct {
+	// Quality is the JPEG quality in [1, 100], zero selects 95.
+	Quality int
+	// Grayscale converts color images to grayscale before JPEG encoding, so
+	// the output has a single luma component and no chroma planes. Grayscale
+	// images are always encoded that way.
+	Grayscale bool
+}
+
+// Encode writes img to w in the given format, one of "jpeg", "png" or "gif".
+func Encode(w io.Writer, img image.Image, format string, opts EncodeOptions) error {
+	switch format {
+	case "jpeg", "jpg":
+		quality := opts.Quality
+		if quality == 0 {
+			quality = 95
+		}
+		if opts.Grayscale {
+			img = toGray(img)
+		}
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case "png":
+		return png.Encode(w, img)
+	case "gif":
+		return gif.Encode(w, img, nil)
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+}
+
+func toGray(img image.Image) *image.Gray {
+	if gray, ok := img.(*image.Gray); ok {
+		return gray
+	}
+	bounds := img.Bounds()
+	gray := image.NewGray(bounds)
+	draw.Draw(gray, bounds, img, bounds.Min, draw.Src)
+	return gray
+}
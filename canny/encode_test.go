@@ -0,0 +1,66 @@
+package canny
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+func TestEncodeFormats(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 8, 6))
+	for _, format := range []string{"jpeg", "jpg", "png", "gif"} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, img, format, EncodeOptions{}); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		decoded, name, err := image.Decode(&buf)
+		if err != nil {
+			t.Fatalf("%s: decoding the output: %v", format, err)
+		}
+		want := format
+		if want == "jpg" {
+			want = "jpeg"
+		}
+		if name != want {
+			t.Errorf("%s: output decoded as %s", format, name)
+		}
+		if decoded.Bounds() != img.Bounds() {
+			t.Errorf("%s: bounds %v, want %v", format, decoded.Bounds(), img.Bounds())
+		}
+	}
+
+	if err := Encode(&bytes.Buffer{}, img, "bmp", EncodeOptions{}); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
+
+func TestEncodeGrayscaleJPEG(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			img.Set(x, y, color.RGBA{uint8(16 * x), 0, uint8(16 * y), 255})
+		}
+	}
+
+	for _, c := range []struct {
+		grayscale bool
+		want      color.Model
+	}{
+		{false, color.YCbCrModel},
+		{true, color.GrayModel},
+	} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, img, "jpeg", EncodeOptions{Grayscale: c.grayscale}); err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := jpeg.Decode(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.ColorModel() != c.want {
+			t.Errorf("grayscale %t: decoded with a different color model", c.grayscale)
+		}
+	}
+}
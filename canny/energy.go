@@ -0,0 +1,24 @@
+package canny
+
+// EdgeEnergy summarizes the edge content of an image in a single value: the
+// sum of the gradient magnitudes at the edge pixels of edges divided by the
+// number of pixels. Sharp, detailed images score high, blank or blurry ones
+// low, which makes it usable for quality gating. The magnitudes must have the
+// dimensions of the edges.
+func EdgeEnergy(edges, magnitudes [][]GrayPixel) float64 {
+	var sum float64
+	count := 0
+	for y := range edges {
+		for x := range edges[y] {
+			if edges[y][x].Y != 0 {
+				sum += float64(magnitudes[y][x].Y)
+			}
+			count++
+		}
+	}
+	if count == 0 {
+		return float64(0)
+	}
+
+	return sum / float64(count)
+}
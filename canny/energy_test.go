@@ -0,0 +1,39 @@
+package canny
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEdgeEnergy(t *testing.T) {
+	edges := newPixels(4, 2, func(x, y int) uint8 {
+		if x == 1 {
+			return 255
+		}
+		return 0
+	})
+	magnitudes := newPixels(4, 2, func(x, y int) uint8 { return uint8(10 * (x + 1)) })
+	// Only the two edge pixels with a magnitude of 20 count, over 8 pixels.
+	if got := EdgeEnergy(edges, magnitudes); got != 5 {
+		t.Errorf("got %v, want 5", got)
+	}
+	if got := EdgeEnergy(nil, nil); got != 0 {
+		t.Errorf("got %v for no pixels, want 0", got)
+	}
+}
+
+func TestEdgeEnergyOfSharpAndBlankImages(t *testing.T) {
+	energy := func(pixels [][]GrayPixel) float64 {
+		stages, err := DetectStages(context.Background(), pixels, Options{Blur: true, MinRatio: 0.2, MaxRatio: 0.6})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return EdgeEnergy(stages.Edges, stages.Magnitudes)
+	}
+	if blank := energy(newPixels(16, 16, func(x, y int) uint8 { return 0 })); blank != 0 {
+		t.Errorf("got %v for a blank image, want 0", blank)
+	}
+	if sharp := energy(square(16)); sharp <= 0 {
+		t.Errorf("got %v for a square, want a positive energy", sharp)
+	}
+}
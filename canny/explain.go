@@ -0,0 +1,86 @@
+package canny
+
+import (
+	"context"
+	"errors"
+	"image"
+)
+
+// PixelReport holds the intermediate values of the pipeline at one pixel.
+type PixelReport struct {
+	Gray      uint8
+	Blurred   uint8
+	GradientX float64
+	GradientY float64
+	Magnitude uint8
+	// Angle is the gradient direction in degrees.
+	Angle float64
+	// DirectionBin names the neighbour pair compared during non-maximum
+	// suppression.
+	DirectionBin string
+	Suppressed   bool
+	// Classification is "strong", "weak" or "none" after the double
+	// threshold.
+	Classification string
+	Edge           bool
+}
+
+// ExplainPixel runs the pipeline on pixels and reports the intermediate values
+// at (x, y). The magnitude, the direction, the suppression and the edge are
+// taken from DetectStages, so they honour every option in opts.
+func ExplainPixel(ctx context.Context, pixels [][]GrayPixel, opts Options, x, y int) (PixelReport, error) {
+	var report PixelReport
+	if err := checkRectangular(pixels); err != nil {
+		return report, err
+	}
+	if (y < 0) || (y >= len(pixels)) || (x < 0) || (x >= len(pixels[y])) {
+		return report, errors.New("coordinates out of image bounds")
+	}
+	report.Gray = pixels[y][x].Y
+
+	blurred, err := blurPixels(ctx, pixels, opts)
+	if err != nil {
+		return report, err
+	}
+	report.Blurred = blurred[y][x].Y
+
+	op := opts.Operator
+	if op.X == nil {
+		op = SOBEL
+	}
+	size := op.Size()
+	imagePane := getSurroundingPixelMatrix(pixelValues(blurred), y, x, size)
+	report.GradientX = convolve(imagePane, newMatrix(size, size, op.X))
+	report.GradientY = convolve(imagePane, newMatrix(size, size, op.Y))
+
+	stages, err := DetectStages(ctx, pixels, opts)
+	if err != nil {
+		return report, err
+	}
+	report.Magnitude = stages.Magnitudes[y][x].Y
+	report.Angle = stages.Directions[y][x]
+	report.DirectionBin = directionBin(report.Angle, opts.BinRule)
+	report.Suppressed = isSuppressedWith(stages.Magnitudes, stages.Directions, x, y, nmsTieTolerance(opts), opts.BinRule, opts.NMSInterpolation)
+
+	point := image.Point{x, y}
+	switch {
+	case stages.Strong.Contains(point):
+		report.Classification = "strong"
+	case stages.Weak.Contains(point):
+		report.Classification = "weak"
+	default:
+		report.Classification = "none"
+	}
+	report.Edge = stages.Edges[y][x].Y != 0
+
+	return report, nil
+}
+
+// DIRECTION_BIN_NAMES names the neighbour pair of every direction bin.
+var DIRECTION_BIN_NAMES = []string{"vertical", "anti-diagonal", "horizontal", "diagonal"}
+
+// directionBin names the neighbour pair getPixelInGradientDirection selects
+// for a gradient angle in degrees.
+func directionBin(angle float64, rule BinRule) string {
+	return DIRECTION_BIN_NAMES[directionBinIndex(angle, rule)]
+}
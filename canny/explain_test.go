@@ -0,0 +1,101 @@
+package canny
+
+import (
+	"context"
+	"testing"
+)
+
+func TestExplainPixelStep(t *testing.T) {
+	pixels := newPixels(10, 7, func(x, y int) uint8 {
+		if x < 5 {
+			return 0
+		}
+		return 40
+	})
+
+	report, err := ExplainPixel(context.Background(), pixels, Options{}, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The Sobel operator weighs the columns left and right of (5, 3) with
+	// 1, 2, 1 and -1, -2, -1.
+	if (report.GradientX != -160) || (report.GradientY != 0) {
+		t.Errorf("gradient: got (%v, %v), want (-160, 0)", report.GradientX, report.GradientY)
+	}
+	if (report.Magnitude != 160) || (report.Angle != 0) || (report.DirectionBin != "horizontal") {
+		t.Errorf("got magnitude %d, angle %v and bin %q, want 160, 0 and horizontal", report.Magnitude, report.Angle, report.DirectionBin)
+	}
+
+	report, err = ExplainPixel(context.Background(), pixels, Options{MagnitudeFloor: 200}, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Magnitude != 0 {
+		t.Errorf("magnitude below the floor: got %d, want 0", report.Magnitude)
+	}
+
+	if _, err := ExplainPixel(context.Background(), pixels, Options{}, 10, 3); err == nil {
+		t.Error("expected an error for a pixel out of bounds")
+	}
+}
+
+func TestExplainPixelMatchesDetectStages(t *testing.T) {
+	pixels := testImage()
+	for i, opts := range []Options{
+		{},
+		{Blur: true, MinRatio: 0.2, MaxRatio: 0.6, NMSTieBreak: true, NMSTolerance: 2},
+		{Operator: CENTRAL_DIFFERENCE, MagnitudeFloor: 20, MinRatio: 0.1, MaxRatio: 0.3},
+	} {
+		stages, err := DetectStages(context.Background(), pixels, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		thinned, err := nonMaximumSuppression(context.Background(), stages.Magnitudes, stages.Directions, nmsTieTolerance(opts), opts.BinRule, opts.NMSInterpolation)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Every other pixel keeps the test fast.
+		for y := 0; y < len(pixels); y += 2 {
+			for x := 0; x < len(pixels[y]); x += 2 {
+				report, err := ExplainPixel(context.Background(), pixels, opts, x, y)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if report.Magnitude != stages.Magnitudes[y][x].Y {
+					t.Fatalf("options %d at (%d, %d): magnitude %d, want %d", i, x, y, report.Magnitude, stages.Magnitudes[y][x].Y)
+				}
+				want := report.Magnitude
+				if report.Suppressed {
+					want = 0
+				}
+				if thinned[y][x].Y != want {
+					t.Fatalf("options %d at (%d, %d): suppressed %v disagrees with the non-maximum suppression", i, x, y, report.Suppressed)
+				}
+				if report.Edge != (stages.Edges[y][x].Y != 0) {
+					t.Fatalf("options %d at (%d, %d): edge %v disagrees with DetectStages", i, x, y, report.Edge)
+				}
+			}
+		}
+	}
+}
+
+func TestDirectionBinMatchesNonMaximumSuppression(t *testing.T) {
+	// A 3x3 magnitude map whose neighbours of the center are distinct, so
+	// the compared pair identifies the bin.
+	pixels := newPixels(3, 3, func(x, y int) uint8 { return uint8(10*y + x) })
+	pairs := map[string][2]uint8{
+		"vertical":      {1, 21},
+		"anti-diagonal": {2, 20},
+		"horizontal":    {10, 12},
+		"diagonal":      {0, 22},
+	}
+	for angle := -90.0; angle < 90; angle += 22.5 / 2 {
+		directions := [][]float64{{0, 0, 0}, {0, angle, 0}, {0, 0, 0}}
+		p, q := getPixelInGradientDirection(pixels, directions, 1, 1, ROUND_HALF_UP)
+		want := pairs[directionBin(angle, ROUND_HALF_UP)]
+		if ((p.Y != want[0]) || (q.Y != want[1])) && ((p.Y != want[1]) || (q.Y != want[0])) {
+			t.Errorf("angle %v: bin %s, but compared %d and %d", angle, directionBin(angle, ROUND_HALF_UP), p.Y, q.Y)
+		}
+	}
+}
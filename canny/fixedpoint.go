@@ -0,0 +1,202 @@
+package canny
+
+import (
+	"context"
+	"fmt"
+)
+
+// The fixed-point pipeline computes the blur and the gradients with integer
+// arithmetic only, so results are bit-identical on every platform. It matches
+// the floating-point pipeline except that gradient directions are quantized
+// to the centers of the direction bins (-90, -45, 0 and 45 degrees). Rounding
+// to nearest is done exactly on integers too.
+
+// TAN_22_5 is tan(22.5°) scaled by TAN_SCALE, used to bin gradient directions
+// without trigonometry.
+const (
+	TAN_SCALE = 100000000
+	TAN_22_5  = 41421356
+)
+
+// gaussianBlurFixed is the integer counterpart of gaussianBlur. The binomial
+// kernel weights are kept unnormalized in both passes and the square of their
+// sum is divided out at the end.
+func gaussianBlurFixed(ctx context.Context, pixels [][]GrayPixel, kernelSize uint, rounding RoundingMode, workers int) ([][]GrayPixel, error) {
+	if kernelSize%2 == 0 {
+		return nil, fmt.Errorf("size of kernel must be odd, got %d", kernelSize)
+	}
+	kernel := binomialRow(int(kernelSize - 1))
+	radius := len(kernel) / 2
+	var weight int64
+	for _, k := range kernel {
+		weight += k
+	}
+	height := len(pixels)
+	width := len(pixels[0])
+
+	horizontal := make([][]int64, height)
+	result := make([][]GrayPixel, height)
+	for y := range result {
+		horizontal[y] = make([]int64, width)
+		result[y] = make([]GrayPixel, width)
+	}
+
+	forEachRowBand(height, workers, func(minY, maxY int) {
+		for y := minY; (y < maxY) && !canceled(ctx); y++ {
+			for x := 0; x < width; x++ {
+				var sum int64
+				for i, k := range kernel {
+					sum += k * int64(pixels[y][mirrorIndex(x+i-radius, x, width)].Y)
+				}
+				horizontal[y][x] = sum
+			}
+		}
+	})
+	forEachRowBand(width, workers, func(minX, maxX int) {
+		for x := minX; (x < maxX) && !canceled(ctx); x++ {
+			for y := 0; y < height; y++ {
+				var sum int64
+				for i, k := range kernel {
+					sum += k * horizontal[mirrorIndex(y+i-radius, y, height)][x]
+				}
+				result[y][x] = GrayPixel{clampUint8(int(divRound(sum, weight*weight, rounding))), 255}
+			}
+		}
+	})
+
+	return result, nil
+}
+
+// divRound divides the non-negative n by d with the given rounding.
+func divRound(n, d int64, rounding RoundingMode) int64 {
+	if rounding == ROUND_NEAREST {
+		return (2*n + d) / (2 * d)
+	}
+	return n / d
+}
+
+// gradientFixed is the integer counterpart of gradient. The kernels of op must
+// have integer coefficients.
+func gradientFixed(ctx context.Context, pixels [][]GrayPixel, op Operator, rounding RoundingMode, norm GradientNorm) ([][]GrayPixel, [][]float64, error) {
+	if op.X == nil {
+		op = SOBEL
+	}
+	var result [][]GrayPixel
+	var directions [][]float64
+
+	size := op.Size()
+	kernelX, err := toIntKernel(op.X)
+	if err != nil {
+		return nil, nil, err
+	}
+	kernelY, err := toIntKernel(op.Y)
+	if err != nil {
+		return nil, nil, err
+	}
+	values := pixelValues(pixels)
+
+	for y := 0; y < len(pixels); y++ {
+		if canceled(ctx) {
+			break
+		}
+		var resultRow []GrayPixel
+		var angleRow []float64
+		for x := 0; x < len(pixels[y]); x++ {
+			imagePane := getSurroundingPixelMatrix(values, y, x, size)
+			var gx, gy int64
+			for i := 0; i < size; i++ {
+				for j := 0; j < size; j++ {
+					v := int64(imagePane.At(i, j))
+					gx += kernelX[i*size+j] * v
+					gy += kernelY[i*size+j] * v
+				}
+			}
+
+			var magnitude int64
+			if norm == NORM_L1 {
+				magnitude = absInt64(gx) + absInt64(gy)
+			} else {
+				magnitude = sqrtDiv(gx*gx+gy*gy, 1, rounding)
+			}
+			resultRow = append(resultRow, GrayPixel{clampUint8(int(magnitude)), uint8(255)})
+			angleRow = append(angleRow, fixedDirection(gx, gy))
+		}
+		result = append(result, resultRow)
+		directions = append(directions, angleRow)
+	}
+
+	return result, directions, nil
+}
+
+// fixedDirection returns the center of the direction bin gradientDirection
+// of (gx, gy) falls into. Like gradient, it reports 0 for a zero gradient and
+// -90 for vertical gradients.
+func fixedDirection(gx, gy int64) float64 {
+	if (gx == 0) && (gy == 0) {
+		return float64(0)
+	}
+	ax, ay := absInt64(gx), absInt64(gy)
+
+	// |angle| < 22.5 <=> ay/ax < tan(22.5), |angle| >= 67.5 <=> ax/ay <= tan(22.5)
+	switch {
+	case ay*TAN_SCALE < ax*TAN_22_5:
+		return float64(0)
+	case ax*TAN_SCALE <= ay*TAN_22_5:
+		return float64(-90)
+	case (gx > 0) == (gy > 0):
+		return float64(45)
+	default:
+		return float64(-45)
+	}
+}
+
+func binomialRow(index int) []int64 {
+	row := make([]int64, index+1)
+	row[0] = 1
+	for i := 1; i <= index; i++ {
+		row[i] = row[i-1] * int64(index-i+1) / int64(i)
+	}
+	return row
+}
+
+func toIntKernel(kernel []float64) ([]int64, error) {
+	result := make([]int64, len(kernel))
+	for i, k := range kernel {
+		if k != float64(int64(k)) {
+			return nil, fmt.Errorf("fixed-point pipeline requires integer kernel coefficients, got %v at index %d", k, i)
+		}
+		result[i] = int64(k)
+	}
+	return result, nil
+}
+
+// sqrtDiv returns sqrt(n)/d quantized with the given rounding. Since
+// floor((floor(a)+k)/m) == floor((a+k)/m) for integers k and m, rounding to
+// nearest is floor((sqrt(4n)+d)/(2d)).
+func sqrtDiv(n, d int64, rounding RoundingMode) int64 {
+	if rounding == ROUND_NEAREST {
+		return (isqrt(4*n) + d) / (2 * d)
+	}
+	return isqrt(n) / d
+}
+
+func absInt64(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// isqrt returns the floor of the square root of a non-negative n.
+func isqrt(n int64) int64 {
+	if n < 2 {
+		return n
+	}
+	x := n
+	y := (x + 1) / 2
+	for y < x {
+		x = y
+		y = (x + n/x) / 2
+	}
+	return x
+}
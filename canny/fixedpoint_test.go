@@ -0,0 +1,98 @@
+package canny
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestIsqrt(t *testing.T) {
+	for n := int64(0); n < 100000; n++ {
+		r := isqrt(n)
+		if (r*r > n) || ((r+1)*(r+1) <= n) {
+			t.Fatalf("isqrt(%d) = %d", n, r)
+		}
+	}
+}
+
+func TestFixedDirectionMatchesBin(t *testing.T) {
+	for gx := int64(-40); gx <= 40; gx++ {
+		for gy := int64(-40); gy <= 40; gy++ {
+			want := directionBinIndex(gradientDirection(float64(gx), float64(gy)), ROUND_HALF_UP)
+			if got := directionBinIndex(fixedDirection(gx, gy), ROUND_HALF_UP); got != want {
+				t.Errorf("(%d, %d): got bin %d, want %d", gx, gy, got, want)
+			}
+		}
+	}
+}
+
+func TestGradientFixedMatchesGradient(t *testing.T) {
+	// Low contrast keeps the magnitudes below 256, where neither pipeline
+	// clamps.
+	pixels := newPixels(9, 7, func(x, y int) uint8 { return uint8((x*7 + y*11 + x*y) % 12) })
+	for _, op := range []Operator{SOBEL, SCHARR, PREWITT, CENTRAL_DIFFERENCE} {
+		magnitudes, directions := gradient(context.Background(), pixels, op, ROUND_NEAREST, NORM_L2)
+		fixedMagnitudes, fixedDirections, err := gradientFixed(context.Background(), pixels, op, ROUND_NEAREST, NORM_L2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for y := range pixels {
+			for x := range pixels[y] {
+				if fixedMagnitudes[y][x] != magnitudes[y][x] {
+					t.Errorf("%s (%d, %d): got magnitude %v, want %v", op.Name, x, y, fixedMagnitudes[y][x], magnitudes[y][x])
+				}
+				want := directionBinIndex(directions[y][x], ROUND_HALF_UP)
+				if got := directionBinIndex(fixedDirections[y][x], ROUND_HALF_UP); got != want {
+					t.Errorf("%s (%d, %d): got bin %d, want %d", op.Name, x, y, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestGaussianBlurFixedMatchesGaussianBlur(t *testing.T) {
+	// The blur combines both passes as a vector norm, which stays below 256
+	// for values up to 180.
+	pixels := newPixels(12, 10, func(x, y int) uint8 { return uint8((x*31 + y*17) % 180) })
+	blurred := gaussianBlur(context.Background(), pixels, BLUR_KERNEL_SIZE, ROUND_NEAREST, 0)
+	fixed, err := gaussianBlurFixed(context.Background(), pixels, BLUR_KERNEL_SIZE, ROUND_NEAREST, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := range pixels {
+		for x := range pixels[y] {
+			// The floating-point blur can round a hair below an integer.
+			if math.Abs(float64(fixed[y][x].Y)-float64(blurred[y][x].Y)) > 1 {
+				t.Errorf("(%d, %d): got %d, want %d", x, y, fixed[y][x].Y, blurred[y][x].Y)
+			}
+		}
+	}
+}
+
+func TestSqrtDivMatchesQuantize(t *testing.T) {
+	for n := int64(0); n < 5000; n += 7 {
+		for _, d := range []int64{1, 3, 16} {
+			for _, rounding := range []RoundingMode{ROUND_NEAREST, ROUND_TRUNCATE} {
+				want := quantize(math.Sqrt(float64(n))/float64(d), rounding)
+				if got := sqrtDiv(n, d, rounding); got != int64(want) {
+					t.Errorf("sqrtDiv(%d, %d, %d): got %d, want %d", n, d, rounding, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestFixedPointRejectsInvalidKernels(t *testing.T) {
+	pixels := square(8)
+	if _, err := gaussianBlurFixed(context.Background(), pixels, 4, ROUND_NEAREST, 0); (err == nil) || !strings.Contains(err.Error(), "got 4") {
+		t.Errorf("even kernel size: got error %v", err)
+	}
+	if _, err := toIntKernel([]float64{1, 0.5, 1}); (err == nil) || !strings.Contains(err.Error(), "0.5 at index 1") {
+		t.Errorf("non-integer kernel: got error %v", err)
+	}
+	op := Operator{Name: "half", X: []float64{0, 0, 0, -0.5, 0, 0.5, 0, 0, 0}, Y: SOBEL_Y}
+	if _, _, err := gradientFixed(context.Background(), pixels, op, ROUND_NEAREST, NORM_L2); err == nil {
+		t.Error("expected an error for a non-integer operator")
+	}
+}
@@ -0,0 +1,69 @@
+package canny
+
+import (
+	"image"
+	"image/color"
+	"strings"
+)
+
+// FONT_WIDTH and FONT_HEIGHT are the glyph dimensions of the built-in bitmap
+// font, without spacing.
+const (
+	FONT_WIDTH  = 3
+	FONT_HEIGHT = 5
+)
+
+// FONT is a minimal 3×5 bitmap font for annotations. Every glyph is a row-major
+// string of FONT_WIDTH*FONT_HEIGHT characters, '#' marking a set pixel.
+var FONT = map[rune]string{
+	'A': ".#.#.#####.##.#", 'B': "##.#.###.#.###.", 'C': ".###..#..#...##",
+	'D': "##.#.##.##.###.", 'E': "####..##.#..###", 'F': "####..##.#..#..",
+	'G': ".###..#.##.#.##", 'H': "#.##.#####.##.#", 'I': "###.#..#..#.###",
+	'J': "..#..#..##.#.#.", 'K': "#.##.###.#.##.#", 'L': "#..#..#..#..###",
+	'M': "#.########.##.#", 'N': "##.#.##.##.##.#", 'O': ".#.#.##.##.#.#.",
+	'P': "##.#.###.#..#..", 'Q': ".#.#.##.###..##", 'R': "##.#.###.#.##.#",
+	'S': ".###...#...###.", 'T': "###.#..#..#..#.", 'U': "#.##.##.##.####",
+	'V': "#.##.##.##.#.#.", 'W': "#.##.########.#", 'X': "#.##.#.#.#.##.#",
+	'Y': "#.##.#.#..#..#.", 'Z': "###..#.#.#..###", '0': "####.##.##.####",
+	'1': ".#.##..#..#.###", '2': "##...#.#.#..###", '3': "##...#.#...###.",
+	'4': "#.##.####..#..#", '5': "####..##...###.", '6': ".###..####.####",
+	'7': "###..#.#..#..#.", '8': "####.#####.####", '9': "####.####..###.",
+	' ': "...............", '.': ".............#.", '-': "......###......",
+	'%': "#.#..#.#.#..#.#", ':': "....#.....#....", '/': "..#..#.#.#..#..",
+	'(': "..#.#..#..#...#", ')': "#...#..#..#.#..",
+}
+
+// textWidth returns the width of text drawn with drawText at the given scale.
+func textWidth(text string, scale int) int {
+	if text == "" {
+		return 0
+	}
+	return (len([]rune(text))*(FONT_WIDTH+1) - 1) * scale
+}
+
+// drawText draws text with the built-in font, its top left corner at (x, y).
+// Lower case letters are drawn as upper case, unknown runes as blanks.
+func drawText(img *image.RGBA, x, y int, text string, c color.RGBA, scale int) {
+	for i, r := range []rune(strings.ToUpper(text)) {
+		glyph, ok := FONT[r]
+		if !ok {
+			continue
+		}
+		originX := x + i*(FONT_WIDTH+1)*scale
+		for gy := 0; gy < FONT_HEIGHT; gy++ {
+			for gx := 0; gx < FONT_WIDTH; gx++ {
+				if glyph[gy*FONT_WIDTH+gx] != '#' {
+					continue
+				}
+				for sy := 0; sy < scale; sy++ {
+					for sx := 0; sx < scale; sx++ {
+						p := image.Point{originX + gx*scale + sx, y + gy*scale + sy}
+						if p.In(img.Rect) {
+							img.SetRGBA(p.X, p.Y, c)
+						}
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package canny
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// Gray values of typical 8-bit images are gamma encoded with the sRGB
+// transfer function, so they are roughly proportional to perceived lightness
+// rather than to light intensity. Averaging them, as blurring does, darkens
+// high contrast edges: half black and half white blurs to a gray of 128,
+// while the physically correct mix of the light is about 188. Blurring in
+// linear light avoids the bias, but the thresholds of the detection are
+// usually tuned on the gamma encoded values, so gaussianBlurLinear encodes
+// the result again before the gradients are computed.
+
+// srgbToLinear decodes an sRGB value in [0, 1] to linear light.
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB encodes a linear light value in [0, 1] with the sRGB transfer
+// function.
+func linearToSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// gaussianBlurLinear is gaussianBlur in linear light: the gray values are
+// decoded to linear light, blurred with the same separable kernel and encoded
+// to sRGB again.
+func gaussianBlurLinear(ctx context.Context, pixels [][]GrayPixel, kernelSize uint, rounding RoundingMode, workers int) [][]GrayPixel {
+	if kernelSize%2 == 0 {
+		panic(fmt.Errorf("size of kernel must be odd, got %d", kernelSize))
+	}
+	height := len(pixels)
+	width := len(pixels[0])
+
+	linear := make([][]float64, height)
+	for y := range pixels {
+		linear[y] = make([]float64, width)
+		for x := range pixels[y] {
+			linear[y][x] = srgbToLinear(float64(pixels[y][x].Y) / 255)
+		}
+	}
+
+	blurred := separableBlur(ctx, linear, binomialKernel(kernelSize), workers)
+
+	result := make([][]GrayPixel, height)
+	for y := range blurred {
+		result[y] = make([]GrayPixel, width)
+		for x := range blurred[y] {
+			result[y][x] = GrayPixel{quantize(255*linearToSRGB(math.Min(1, blurred[y][x])), rounding), 255}
+		}
+	}
+
+	return result
+}
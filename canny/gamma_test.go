@@ -0,0 +1,44 @@
+package canny
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestSRGBRoundTrip(t *testing.T) {
+	for i := 0; i <= 255; i++ {
+		v := float64(i) / 255
+		if got := linearToSRGB(srgbToLinear(v)); math.Abs(got-v) > 1e-12 {
+			t.Errorf("%d: got %v back, want %v", i, got, v)
+		}
+	}
+	// Half black and half white light is a gray of about 188 once encoded.
+	if got := math.Round(255 * linearToSRGB(0.5)); got != 188 {
+		t.Errorf("got %v for half the light, want 188", got)
+	}
+}
+
+func TestLinearBlurBrightensDarkSideOfEdges(t *testing.T) {
+	pixels := newPixels(12, 8, func(x, y int) uint8 {
+		if x >= 6 {
+			return 160
+		}
+		return 10
+	})
+	plain := gaussianBlur(context.Background(), pixels, BLUR_KERNEL_SIZE, ROUND_NEAREST, 0)
+	linear := gaussianBlurLinear(context.Background(), pixels, BLUR_KERNEL_SIZE, ROUND_NEAREST, 0)
+	for x := 4; x < 6; x++ {
+		if linear[4][x].Y <= plain[4][x].Y {
+			t.Errorf("x = %d: got %d in linear light, want above the %d of the plain blur", x, linear[4][x].Y, plain[4][x].Y)
+		}
+	}
+
+	stages, err := DetectStages(context.Background(), pixels, Options{Blur: true, LinearBlur: true, Deterministic: true, MinRatio: 0.1, MaxRatio: 0.3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (stages.Edges[4][5].Y == 0) && (stages.Edges[4][6].Y == 0) {
+		t.Error("missing the edge of the step")
+	}
+}
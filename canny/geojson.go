@@ -0,0 +1,55 @@
+package canny
+
+import (
+	"encoding/json"
+	"image"
+)
+
+// IDENTITY_GEOTRANSFORM maps pixel coordinates to themselves.
+var IDENTITY_GEOTRANSFORM = [6]float64{0, 1, 0, 0, 0, 1}
+
+type geoJSONGeometry struct {
+	Type        string       `json:"type"`
+	Coordinates [][2]float64 `json:"coordinates"`
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   geoJSONGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+// ContoursToGeoJSON encodes contours as a GeoJSON FeatureCollection with one
+// LineString per contour. Pixel coordinates are mapped to world coordinates
+// with a GDAL style affine transform:
+//
+//	X = t[0] + x*t[1] + y*t[2]
+//	Y = t[3] + x*t[4] + y*t[5]
+//
+// Single pixel contours are emitted as a degenerate two point LineString.
+func ContoursToGeoJSON(contours [][]image.Point, t [6]float64) ([]byte, error) {
+	collection := geoJSONFeatureCollection{Type: "FeatureCollection", Features: []geoJSONFeature{}}
+
+	for _, contour := range contours {
+		coordinates := make([][2]float64, 0, len(contour))
+		for _, p := range contour {
+			x, y := float64(p.X), float64(p.Y)
+			coordinates = append(coordinates, [2]float64{t[0] + x*t[1] + y*t[2], t[3] + x*t[4] + y*t[5]})
+		}
+		if len(coordinates) == 1 {
+			coordinates = append(coordinates, coordinates[0])
+		}
+		collection.Features = append(collection.Features, geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONGeometry{Type: "LineString", Coordinates: coordinates},
+			Properties: map[string]interface{}{"length": len(contour)},
+		})
+	}
+
+	return json.Marshal(collection)
+}
@@ -0,0 +1,40 @@
+package canny
+
+import (
+	"encoding/json"
+	"image"
+	"testing"
+)
+
+func TestContoursToGeoJSON(t *testing.T) {
+	contours := [][]image.Point{{{0, 0}, {1, 0}, {2, 1}}, {{5, 5}}}
+	data, err := ContoursToGeoJSON(contours, [6]float64{100, 0.5, 0, 200, 0, -0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var collection geoJSONFeatureCollection
+	if err := json.Unmarshal(data, &collection); err != nil {
+		t.Fatal(err)
+	}
+	if (collection.Type != "FeatureCollection") || (len(collection.Features) != 2) {
+		t.Fatalf("got %s with %d features", collection.Type, len(collection.Features))
+	}
+	line := collection.Features[0].Geometry
+	want := [][2]float64{{100, 200}, {100.5, 200}, {101, 199.5}}
+	if (line.Type != "LineString") || (len(line.Coordinates) != len(want)) {
+		t.Fatalf("got %+v, want a LineString of %v", line, want)
+	}
+	for i := range want {
+		if line.Coordinates[i] != want[i] {
+			t.Errorf("point %d: got %v, want %v", i, line.Coordinates[i], want[i])
+		}
+	}
+	dot := collection.Features[1].Geometry.Coordinates
+	if (len(dot) != 2) || (dot[0] != [2]float64{102.5, 197.5}) || (dot[1] != dot[0]) {
+		t.Errorf("got single pixel contour %v, want a degenerate LineString", dot)
+	}
+	if length := collection.Features[0].Properties["length"]; length != float64(3) {
+		t.Errorf("got length %v, want 3", length)
+	}
+}
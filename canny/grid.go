@@ -0,0 +1,44 @@
+package canny
+
+import (
+	"context"
+	"image"
+	"image/color"
+)
+
+// GRID_LABEL_HEIGHT is the height of the label bar above every tile of an
+// operator grid.
+const GRID_LABEL_HEIGHT = FONT_HEIGHT*2 + 4
+
+// OperatorGrid runs the detection once per operator and tiles the edge maps
+// side by side, each below a bar labeled with the operator's name.
+func OperatorGrid(ctx context.Context, pixels [][]GrayPixel, opts Options, operators []Operator) (*image.RGBA, error) {
+	width := len(pixels[0])
+	height := len(pixels)
+	img := image.NewRGBA(image.Rect(0, 0, width*len(operators), height+GRID_LABEL_HEIGHT))
+	white := color.RGBA{255, 255, 255, 255}
+
+	for i, op := range operators {
+		opts.Operator = op
+		edges, err := CannyEdgeDetectContext(ctx, pixels, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		originX := i * width
+		for y := 0; y < GRID_LABEL_HEIGHT; y++ {
+			for x := 0; x < width; x++ {
+				img.SetRGBA(originX+x, y, color.RGBA{0, 0, 0, 255})
+			}
+		}
+		drawText(img, originX+2, 2, op.Name, white, 2)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				v := edges[y][x].Y
+				img.SetRGBA(originX+x, GRID_LABEL_HEIGHT+y, color.RGBA{v, v, v, 255})
+			}
+		}
+	}
+
+	return img, nil
+}
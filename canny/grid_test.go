@@ -0,0 +1,50 @@
+package canny
+
+import (
+	"context"
+	"image/color"
+	"testing"
+)
+
+func TestOperatorGrid(t *testing.T) {
+	pixels := testImage()
+	width, height := len(pixels[0]), len(pixels)
+	operators := []Operator{SOBEL, SCHARR, PREWITT}
+	opts := Options{MinRatio: 0.1, MaxRatio: 0.3}
+	grid, err := OperatorGrid(context.Background(), pixels, opts, operators)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (grid.Bounds().Dx() != width*len(operators)) || (grid.Bounds().Dy() != height+GRID_LABEL_HEIGHT) {
+		t.Fatalf("got a %v grid", grid.Bounds())
+	}
+
+	for i, op := range operators {
+		opts.Operator = op
+		edges, err := CannyEdgeDetectContext(context.Background(), pixels, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for y := range edges {
+			for x := range edges[y] {
+				v := edges[y][x].Y
+				if got := grid.RGBAAt(i*width+x, GRID_LABEL_HEIGHT+y); got != (color.RGBA{v, v, v, 255}) {
+					t.Fatalf("%s tile (%d, %d): got %v, want %d", op.Name, x, y, got, v)
+				}
+			}
+		}
+
+		// The label is drawn in white into the bar above the tile.
+		labeled := false
+		for y := 0; y < GRID_LABEL_HEIGHT; y++ {
+			for x := 0; x < width; x++ {
+				if grid.RGBAAt(i*width+x, y) == (color.RGBA{255, 255, 255, 255}) {
+					labeled = true
+				}
+			}
+		}
+		if !labeled {
+			t.Errorf("%s tile has no label", op.Name)
+		}
+	}
+}
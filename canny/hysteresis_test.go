@@ -0,0 +1,119 @@
+package canny
+
+import (
+	"context"
+	"image"
+	"math/rand"
+	"testing"
+)
+
+// noisePixels returns a pixel array of random gray values.
+func noisePixels(width, height int, seed int64) [][]GrayPixel {
+	rng := rand.New(rand.NewSource(seed))
+	return newPixels(width, height, func(x, y int) uint8 { return uint8(rng.Intn(256)) })
+}
+
+// copyPixels returns a deep copy of pixels.
+func copyPixels(pixels [][]GrayPixel) [][]GrayPixel {
+	result := make([][]GrayPixel, len(pixels))
+	for y := range pixels {
+		result[y] = append([]GrayPixel(nil), pixels[y]...)
+	}
+	return result
+}
+
+func TestEdgeTrackingFollowsChains(t *testing.T) {
+	// A strong point at the left end of a chain of weak points that turns a
+	// corner, and an isolated chain of weak points.
+	pixels := newPixels(10, 6, func(x, y int) uint8 {
+		switch {
+		case (y == 1) && (x == 1):
+			return 200
+		case (y == 1) && (x > 1) && (x < 6):
+			return 100
+		case (x == 6) && (y >= 1) && (y < 5):
+			return 100
+		case (y == 4) && (x >= 8):
+			return 100
+		}
+		return 0
+	})
+	strong, weak := doublethreshold(context.Background(), pixels, 150, 50)
+	edgeTracking(context.Background(), pixels, strong, weak)
+
+	for y := range pixels {
+		for x := range pixels[y] {
+			connected := ((y == 1) && (x >= 1) && (x <= 6)) || ((x == 6) && (y >= 1) && (y < 5))
+			if (pixels[y][x].Y != 0) != connected {
+				t.Errorf("(%d, %d): got %d, want an edge: %t", x, y, pixels[y][x].Y, connected)
+			}
+			if strong.Contains(image.Point{x, y}) != connected {
+				t.Errorf("(%d, %d): strong is %t", x, y, !connected)
+			}
+		}
+	}
+}
+
+func TestEdgeTrackingComponentsMatchesEdgeTracking(t *testing.T) {
+	for seed := int64(1); seed <= 8; seed++ {
+		pixels := noisePixels(61, 47, seed)
+
+		serial := copyPixels(pixels)
+		strong, weak := doublethreshold(context.Background(), serial, 200, 120)
+		edgeTracking(context.Background(), serial, strong, weak)
+
+		parallel := copyPixels(pixels)
+		parallelStrong, parallelWeak := doublethreshold(context.Background(), parallel, 200, 120)
+		edgeTrackingComponents(context.Background(), parallel, parallelStrong, parallelWeak, 0)
+
+		if !equalPixels(serial, parallel) {
+			t.Errorf("seed %d: edges differ", seed)
+		}
+		if !strong.Equal(parallelStrong) {
+			t.Errorf("seed %d: strong points differ", seed)
+		}
+	}
+}
+
+func benchmarkEdgeTracking(b *testing.B, track func(pixels [][]GrayPixel)) {
+	pixels := noisePixels(512, 512, 1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		thresholded := copyPixels(pixels)
+		b.StartTimer()
+		track(thresholded)
+	}
+}
+
+func BenchmarkEdgeTracking(b *testing.B) {
+	benchmarkEdgeTracking(b, func(pixels [][]GrayPixel) {
+		strong, weak := doublethreshold(context.Background(), pixels, 200, 120)
+		edgeTracking(context.Background(), pixels, strong, weak)
+	})
+}
+
+func BenchmarkEdgeTrackingComponents(b *testing.B) {
+	benchmarkEdgeTracking(b, func(pixels [][]GrayPixel) {
+		strong, weak := doublethreshold(context.Background(), pixels, 200, 120)
+		edgeTrackingComponents(context.Background(), pixels, strong, weak, 0)
+	})
+}
+
+func TestHysteresisRecoversWeakPixels(t *testing.T) {
+	stages, err := DetectStages(context.Background(), noisePixels(40, 30, 3), Options{Blur: true, MinRatio: 0.2, MaxRatio: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	strong := PointsToPixels(stages.Strong, 40, 30)
+	for y := range strong {
+		for x := range strong[y] {
+			if (strong[y][x].Y != 0) && (stages.Edges[y][x].Y == 0) {
+				t.Errorf("strong pixel (%d, %d) is missing from the hysteresis edges", x, y)
+			}
+		}
+	}
+	if countEdges(stages.Edges) <= countEdges(strong) {
+		t.Error("expected hysteresis to recover weak pixels")
+	}
+}
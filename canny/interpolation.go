@@ -0,0 +1,110 @@
+package canny
+
+import (
+	"math"
+)
+
+// NMSInterpolation selects how non-maximum suppression samples the two
+// neighbour magnitudes along the gradient direction.
+type NMSInterpolation int
+
+const (
+	// NMS_DISCRETE compares against the neighbour pair of the direction bin.
+	// This is the default.
+	NMS_DISCRETE NMSInterpolation = iota
+	// NMS_LINEAR interpolates linearly between the two neighbours enclosing
+	// the gradient direction.
+	NMS_LINEAR
+	// NMS_CUBIC interpolates bicubically on the 4x4 neighbourhood.
+	NMS_CUBIC
+)
+
+// NMS_INTERPOLATIONS maps the names of the interpolations to their values.
+var NMS_INTERPOLATIONS = map[string]NMSInterpolation{
+	"discrete": NMS_DISCRETE,
+	"linear":   NMS_LINEAR,
+	"cubic":    NMS_CUBIC,
+}
+
+// isSuppressedWith is isSuppressed, or isSuppressedInterpolated unless
+// interpolation is NMS_DISCRETE.
+func isSuppressedWith(pixels [][]GrayPixel, directions [][]float64, x, y int, tieTolerance int, rule BinRule, interpolation NMSInterpolation) bool {
+	if interpolation == NMS_DISCRETE {
+		return isSuppressed(pixels, directions, x, y, tieTolerance, rule)
+	}
+	return isSuppressedInterpolated(pixels, directions, x, y, tieTolerance, interpolation)
+}
+
+// isSuppressedInterpolated is isSuppressed with neighbour magnitudes sampled
+// where the gradient direction through (x, y) leaves its 3x3 neighbourhood.
+// At exact multiples of 45 degrees the samples are the discrete neighbours.
+func isSuppressedInterpolated(pixels [][]GrayPixel, directions [][]float64, x, y int, tieTolerance int, mode NMSInterpolation) bool {
+	r := float64(pixels[y][x].Y)
+	angle := directions[y][x] * (math.Pi / 180)
+	dx, dy := math.Cos(angle), math.Sin(angle)
+	scale := math.Max(math.Abs(dx), math.Abs(dy))
+	dx, dy = dx/scale, dy/scale
+
+	for _, sign := range []float64{1, -1} {
+		sx, sy := float64(x)+sign*dx, float64(y)+sign*dy
+		var v float64
+		if mode == NMS_CUBIC {
+			v = sampleCubic(pixels, sx, sy)
+		} else {
+			v = sampleLinear(pixels, sx, sy)
+		}
+		if tieTolerance < 0 {
+			if v > r {
+				return true
+			}
+			continue
+		}
+		if v > r+float64(tieTolerance) {
+			return true
+		}
+		earlier := (sy < float64(y)) || ((sy == float64(y)) && (sx < float64(x)))
+		if earlier && (math.Abs(v-r) <= float64(tieTolerance)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sampleLinear interpolates the gray values of pixels bilinearly at (x, y),
+// clamping coordinates to the image.
+func sampleLinear(pixels [][]GrayPixel, x, y float64) float64 {
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := x-x0, y-y0
+	at := func(i, j int) float64 {
+		return float64(pixels[clampIndex(j, len(pixels))][clampIndex(i, len(pixels[0]))].Y)
+	}
+	ix, iy := int(x0), int(y0)
+
+	top := at(ix, iy)*(1-fx) + at(ix+1, iy)*fx
+	bottom := at(ix, iy+1)*(1-fx) + at(ix+1, iy+1)*fx
+	return top*(1-fy) + bottom*fy
+}
+
+// sampleCubic interpolates the gray values of pixels bicubically with the
+// Catmull-Rom spline at (x, y), clamping coordinates to the image.
+func sampleCubic(pixels [][]GrayPixel, x, y float64) float64 {
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := x-x0, y-y0
+	ix, iy := int(x0), int(y0)
+
+	var rows [4]float64
+	for j := 0; j < 4; j++ {
+		row := pixels[clampIndex(iy+j-1, len(pixels))]
+		var p [4]float64
+		for i := 0; i < 4; i++ {
+			p[i] = float64(row[clampIndex(ix+i-1, len(row))].Y)
+		}
+		rows[j] = catmullRom(p, fx)
+	}
+	return catmullRom(rows, fy)
+}
+
+func catmullRom(p [4]float64, t float64) float64 {
+	return p[1] + 0.5*t*(p[2]-p[0]+t*(2*p[0]-5*p[1]+4*p[2]-p[3]+t*(3*(p[1]-p[2])+p[3]-p[0])))
+}
@@ -0,0 +1,55 @@
+package canny
+
+import (
+	"testing"
+)
+
+func TestInterpolatedNMSMatchesDiscreteOnAxes(t *testing.T) {
+	pixels := noisePixels(7, 7, 3)
+	directions := make([][]float64, 7)
+	for y := range directions {
+		directions[y] = make([]float64, 7)
+	}
+	for _, angle := range []float64{-90, -45, 0, 45} {
+		directions[3][3] = angle
+		for _, center := range []uint8{0, 60, 120, 180, 255} {
+			pixels[3][3].Y = center
+			want := isSuppressed(pixels, directions, 3, 3, -1, ROUND_HALF_UP)
+			for _, mode := range []NMSInterpolation{NMS_LINEAR, NMS_CUBIC} {
+				if got := isSuppressedWith(pixels, directions, 3, 3, -1, ROUND_HALF_UP, mode); got != want {
+					t.Errorf("angle %v, value %d, mode %d: got suppressed %t, want %t", angle, center, mode, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestInterpolatedNMSDivergesOffAxis(t *testing.T) {
+	// At 30 degrees the discrete rule compares the diagonal neighbours, the
+	// interpolation mostly the stronger horizontal ones.
+	pixels := newPixels(7, 7, func(x, y int) uint8 {
+		switch {
+		case (x == 3) && (y == 3):
+			return 100
+		case (y == 3) && ((x == 2) || (x == 4)):
+			return 200
+		case ((x == 4) && (y == 4)) || ((x == 2) && (y == 2)):
+			return 90
+		}
+		return 0
+	})
+	directions := make([][]float64, 7)
+	for y := range directions {
+		directions[y] = make([]float64, 7)
+	}
+	directions[3][3] = 30
+
+	if isSuppressedWith(pixels, directions, 3, 3, -1, ROUND_HALF_UP, NMS_DISCRETE) {
+		t.Error("expected the discrete rule to keep the pixel")
+	}
+	for _, mode := range []NMSInterpolation{NMS_LINEAR, NMS_CUBIC} {
+		if !isSuppressedWith(pixels, directions, 3, 3, -1, ROUND_HALF_UP, mode) {
+			t.Errorf("mode %d: expected the interpolated neighbours to suppress the pixel", mode)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package canny
+
+import (
+	"math"
+	"sync"
+)
+
+// kernelKey identifies a 1D blur kernel. A sigma of zero selects the
+// normalized binomial kernel of gaussianBlur.
+type kernelKey struct {
+	size  int
+	sigma float64
+}
+
+// KERNEL_CACHE_SIZE is the number of kernels kernelCache holds. Scale maps
+// with many sigmas evict the oldest kernels instead of growing the cache.
+const KERNEL_CACHE_SIZE = 32
+
+// kernelCache holds the most recently computed blur kernels, so repeated
+// blurs of the same size, as in batch and streaming mode, compute their
+// kernel once. Cached kernels are shared and must not be modified.
+var kernelCache = struct {
+	sync.Mutex
+	kernels map[kernelKey][]float64
+	// order lists the keys of kernels from the oldest to the newest.
+	order []kernelKey
+}{kernels: make(map[kernelKey][]float64)}
+
+func cachedKernel(key kernelKey, compute func() []float64) []float64 {
+	kernelCache.Lock()
+	defer kernelCache.Unlock()
+
+	kernel, ok := kernelCache.kernels[key]
+	if !ok {
+		kernel = compute()
+		if len(kernelCache.order) >= KERNEL_CACHE_SIZE {
+			delete(kernelCache.kernels, kernelCache.order[0])
+			kernelCache.order = kernelCache.order[1:]
+		}
+		kernelCache.kernels[key] = kernel
+		kernelCache.order = append(kernelCache.order, key)
+	}
+	return kernel
+}
+
+// binomialKernel returns the normalized binomial kernel of the given odd
+// size used by gaussianBlur.
+func binomialKernel(size uint) []float64 {
+	return cachedKernel(kernelKey{int(size), 0}, func() []float64 {
+		kernel := normalizeVec(getPascalTriangleRow(size - 1))
+		values := make([]float64, kernel.Len())
+		for i := range values {
+			values[i] = kernel.At(i, 0)
+		}
+		return values
+	})
+}
+
+// gaussianKernel returns the normalized Gaussian kernel with the given
+// standard deviation, sampled within 3 sigma of the center.
+func gaussianKernel(sigma float64) []float64 {
+	radius := int(math.Ceil(3 * sigma))
+	return cachedKernel(kernelKey{2*radius + 1, sigma}, func() []float64 {
+		kernel := make([]float64, 2*radius+1)
+		var sum float64
+		for i := range kernel {
+			d := float64(i - radius)
+			kernel[i] = math.Exp(-d * d / (2 * sigma * sigma))
+			sum += kernel[i]
+		}
+		for i := range kernel {
+			kernel[i] /= sum
+		}
+		return kernel
+	})
+}
@@ -0,0 +1,57 @@
+package canny
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCachedKernelsEqualFreshOnes(t *testing.T) {
+	for _, size := range []uint{3, 5, 7} {
+		fresh := normalizeVec(getPascalTriangleRow(size - 1))
+		// The second call is served from the cache.
+		for i := 0; i < 2; i++ {
+			kernel := binomialKernel(size)
+			if len(kernel) != fresh.Len() {
+				t.Fatalf("size %d: got %d values, want %d", size, len(kernel), fresh.Len())
+			}
+			for j := range kernel {
+				if kernel[j] != fresh.At(j, 0) {
+					t.Errorf("size %d value %d: got %v, want %v", size, j, kernel[j], fresh.At(j, 0))
+				}
+			}
+		}
+	}
+
+	for _, sigma := range []float64{0.5, 1.4, 3} {
+		radius := int(math.Ceil(3 * sigma))
+		fresh := make([]float64, 2*radius+1)
+		var sum float64
+		for i := range fresh {
+			d := float64(i - radius)
+			fresh[i] = math.Exp(-d * d / (2 * sigma * sigma))
+			sum += fresh[i]
+		}
+		for i := 0; i < 2; i++ {
+			kernel := gaussianKernel(sigma)
+			if len(kernel) != len(fresh) {
+				t.Fatalf("sigma %v: got %d values, want %d", sigma, len(kernel), len(fresh))
+			}
+			for j := range kernel {
+				if kernel[j] != fresh[j]/sum {
+					t.Errorf("sigma %v value %d: got %v, want %v", sigma, j, kernel[j], fresh[j]/sum)
+				}
+			}
+		}
+	}
+}
+
+func TestKernelCacheIsBounded(t *testing.T) {
+	for i := 0; i < 2*KERNEL_CACHE_SIZE; i++ {
+		gaussianKernel(0.1 * float64(i+1))
+	}
+	kernelCache.Lock()
+	defer kernelCache.Unlock()
+	if (len(kernelCache.kernels) > KERNEL_CACHE_SIZE) || (len(kernelCache.order) != len(kernelCache.kernels)) {
+		t.Errorf("got %d kernels and %d keys, want at most %d", len(kernelCache.kernels), len(kernelCache.order), KERNEL_CACHE_SIZE)
+	}
+}
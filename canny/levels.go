@@ -0,0 +1,58 @@
+package canny
+
+import (
+	"context"
+)
+
+// LayeredEdges detects edges at the given number of increasing threshold
+// levels and encodes every edge pixel with a brightness proportional to the
+// highest level it passes, so the strongest edges are the brightest. The
+// first level uses the thresholds of opts, the upper threshold of the others
+// rises in equal steps towards the maximum magnitude of 255 while the lower
+// one keeps its proportion. The gradient and non-maximum suppression run only
+// once, every level repeats the double threshold and the edge tracking on the
+// suppressed magnitudes.
+func LayeredEdges(ctx context.Context, pixels [][]GrayPixel, opts Options, levels int) ([][]GrayPixel, error) {
+	stages, err := DetectStages(ctx, pixels, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	high, low := thresholds(stages.Suppressed, opts)
+	lowRatio := float64(0)
+	if high > 0 {
+		lowRatio = low / high
+	}
+
+	result := clonePixels(stages.Suppressed)
+	for y := range result {
+		for x := range result[y] {
+			result[y][x].Y = uint8(0)
+		}
+	}
+
+	for level := 0; level < levels; level++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		levelHigh := high + (255-high)*float64(level)/float64(levels)
+		edges := clonePixels(stages.Suppressed)
+		strong, weak := doublethreshold(ctx, edges, levelHigh, levelHigh*lowRatio)
+		if opts.ParallelHysteresis {
+			edgeTrackingComponents(ctx, edges, strong, weak, opts.Workers)
+		} else {
+			edgeTracking(ctx, edges, strong, weak)
+		}
+
+		brightness := uint8(255 * (level + 1) / levels)
+		for y := range edges {
+			for x := range edges[y] {
+				if edges[y][x].Y != 0 {
+					result[y][x].Y = brightness
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,54 @@
+package canny
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLayeredEdgesStrongerIsBrighter(t *testing.T) {
+	// A faint step at x = 8 and a strong one at x = 20.
+	pixels := newPixels(28, 12, func(x, y int) uint8 {
+		switch {
+		case x >= 20:
+			return 200
+		case x >= 8:
+			return 90
+		}
+		return 50
+	})
+	opts := Options{Blur: true, MinRatio: 0.1, MaxRatio: 0.3}
+	layers, err := LayeredEdges(context.Background(), pixels, opts, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := CannyEdgeDetectContext(context.Background(), pixels, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	brightest := func(minX, maxX int) uint8 {
+		var b uint8
+		for x := minX; x < maxX; x++ {
+			if v := layers[6][x].Y; v > b {
+				b = v
+			}
+		}
+		return b
+	}
+	faint, strong := brightest(5, 12), brightest(17, 24)
+	if (faint == 0) || (strong <= faint) {
+		t.Errorf("got brightness %d at the faint and %d at the strong step", faint, strong)
+	}
+	if strong != 255 {
+		t.Errorf("got brightness %d at the strong step, want 255", strong)
+	}
+
+	// The lowest level uses the thresholds of opts.
+	for y := range plain {
+		for x := range plain[y] {
+			if (plain[y][x].Y != 0) != (layers[y][x].Y != 0) {
+				t.Errorf("(%d, %d): got layer %d, edge %d", x, y, layers[y][x].Y, plain[y][x].Y)
+			}
+		}
+	}
+}
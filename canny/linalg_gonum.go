@@ -0,0 +1,44 @@
+//go:build !nogonum
+// +build !nogonum
+
+package canny
+
+import (
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat/combin"
+)
+
+type matrix = mat.Dense
+type vector = mat.VecDense
+
+func newMatrix(rows, cols int, values []float64) matrix {
+	return *mat.NewDense(rows, cols, values)
+}
+
+func newVector(size int, values []float64) vector {
+	return *mat.NewVecDense(size, values)
+}
+
+func getPascalTriangleRow(index uint) vector {
+	size := int(index + 1)
+	values := make([]float64, size)
+
+	for i := 0; i < size; i++ {
+		values[i] = float64(combin.Binomial(int(index), i))
+	}
+
+	result := mat.NewVecDense(size, values)
+	return *result
+}
+
+func normalizeVec(v vector) vector {
+
+	var sum float64 = 0
+	for i := 0; i < v.Len(); i++ {
+		sum += v.At(i, 0)
+	}
+
+	var result mat.VecDense
+	result.ScaleVec(1/sum, v.SliceVec(0, v.Len()))
+	return result
+}
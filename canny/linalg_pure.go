@@ -0,0 +1,79 @@
+//go:build nogonum
+// +build nogonum
+
+package canny
+
+import (
+	"fmt"
+)
+
+// The nogonum build tag replaces the gonum matrix and vector types with plain
+// slices, for minimal builds that only need the small kernels used here.
+
+type matrix struct {
+	rows, cols int
+	values     []float64
+}
+
+func newMatrix(rows, cols int, values []float64) matrix {
+	if len(values) != rows*cols {
+		panic(fmt.Sprintf("matrix: dimension mismatch, %d values for %dx%d", len(values), rows, cols))
+	}
+	return matrix{rows, cols, values}
+}
+
+func (m *matrix) Dims() (int, int) {
+	return m.rows, m.cols
+}
+
+func (m *matrix) At(i, j int) float64 {
+	return m.values[i*m.cols+j]
+}
+
+type vector struct {
+	values []float64
+}
+
+func newVector(size int, values []float64) vector {
+	if len(values) != size {
+		panic(fmt.Sprintf("vector: dimension mismatch, %d values for size %d", len(values), size))
+	}
+	return vector{values}
+}
+
+func (v *vector) Len() int {
+	return len(v.values)
+}
+
+func (v *vector) At(i, j int) float64 {
+	if j != 0 {
+		panic(fmt.Sprintf("vector: column index %d out of range", j))
+	}
+	return v.values[i]
+}
+
+func getPascalTriangleRow(index uint) vector {
+	size := int(index + 1)
+	values := make([]float64, size)
+
+	values[0] = 1
+	for i := 1; i < size; i++ {
+		values[i] = values[i-1] * float64(size-i) / float64(i)
+	}
+
+	return newVector(size, values)
+}
+
+func normalizeVec(v vector) vector {
+
+	var sum float64 = 0
+	for i := 0; i < v.Len(); i++ {
+		sum += v.At(i, 0)
+	}
+
+	values := make([]float64, v.Len())
+	for i := range values {
+		values[i] = v.At(i, 0) / sum
+	}
+	return newVector(len(values), values)
+}
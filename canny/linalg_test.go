@@ -0,0 +1,82 @@
+package canny
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+// The linear algebra helpers exist once on top of gonum and once on plain
+// slices under the nogonum build tag. These tests compare them against plain
+// slice computations, run them with and without -tags nogonum to check that
+// both implementations agree.
+
+func TestPascalTriangleRow(t *testing.T) {
+	for index := uint(0); index <= 8; index++ {
+		row := getPascalTriangleRow(index)
+		if row.Len() != int(index+1) {
+			t.Fatalf("row %d has %d values", index, row.Len())
+		}
+		want := float64(1)
+		for k := 0; k < row.Len(); k++ {
+			if row.At(k, 0) != want {
+				t.Errorf("row %d value %d: got %v, want %v", index, k, row.At(k, 0), want)
+			}
+			want = want * float64(int(index)-k) / float64(k+1)
+		}
+	}
+}
+
+func TestNormalizeVec(t *testing.T) {
+	v := normalizeVec(getPascalTriangleRow(4))
+	want := []float64{1, 4, 6, 4, 1}
+	for i := range want {
+		if math.Abs(v.At(i, 0)-want[i]/16) > 1e-15 {
+			t.Errorf("value %d: got %v, want %v", i, v.At(i, 0), want[i]/16)
+		}
+	}
+}
+
+// mirror maps a neighbour index one step outside of [0, length) back into it
+// the way the gradient does at the image border.
+func mirror(i, length int) int {
+	if i < 0 {
+		return 1
+	}
+	if i >= length {
+		return length - 2
+	}
+	return i
+}
+
+func TestGradientMatchesPlainConvolution(t *testing.T) {
+	pixels := newPixels(7, 6, func(x, y int) uint8 { return uint8((x*37 + y*91 + x*y*13) % 64) })
+	magnitudes, directions := gradient(context.Background(), pixels, SOBEL, ROUND_NEAREST, NORM_L2)
+
+	for y := range pixels {
+		for x := range pixels[y] {
+			var gx, gy float64
+			for i := 0; i < 3; i++ {
+				for j := 0; j < 3; j++ {
+					v := float64(pixels[mirror(y+i-1, len(pixels))][mirror(x+j-1, len(pixels[0]))].Y)
+					gx += SOBEL_X[i*3+j] * v
+					gy += SOBEL_Y[i*3+j] * v
+				}
+			}
+			if want := uint8(math.Round(math.Sqrt(gx*gx + gy*gy))); magnitudes[y][x].Y != want {
+				t.Errorf("(%d, %d): got magnitude %d, want %d", x, y, magnitudes[y][x].Y, want)
+			}
+			if want := gradientDirection(gx, gy); math.Abs(directions[y][x]-want) > 1e-9 {
+				t.Errorf("(%d, %d): got direction %v, want %v", x, y, directions[y][x], want)
+			}
+		}
+	}
+}
+
+func TestInnerProduct(t *testing.T) {
+	a := newVector(3, []float64{1, 2, 3})
+	b := newVector(3, []float64{4, -5, 6})
+	if got := innerProduct(a, b); got != 12 {
+		t.Errorf("got %v, want 12", got)
+	}
+}
@@ -0,0 +1,40 @@
+package canny
+
+import (
+	"image"
+	"unsafe"
+)
+
+// MemoryUsage is the size of the payload of one intermediate array of the
+// pipeline. Slice headers and map overhead are not included.
+type MemoryUsage struct {
+	Stage       string
+	Elements    int
+	ElementSize int
+	Bytes       int
+}
+
+// StageMemory reports the memory of the intermediate arrays of a detection
+// run. The blur buffer, which isn't kept in the stages, has the dimensions of
+// the magnitudes and is only reported if blur is set.
+func StageMemory(stages *Stages, blur bool) []MemoryUsage {
+	pixels := len(stages.Magnitudes) * len(stages.Magnitudes[0])
+	graySize := int(unsafe.Sizeof(GrayPixel{}))
+	pointSize := int(unsafe.Sizeof(image.Point{}))
+
+	var usage []MemoryUsage
+	add := func(stage string, elements, elementSize int) {
+		usage = append(usage, MemoryUsage{stage, elements, elementSize, elements * elementSize})
+	}
+	if blur {
+		add("blur", pixels, graySize)
+	}
+	add("magnitudes", pixels, graySize)
+	add("directions", pixels, int(unsafe.Sizeof(float64(0))))
+	add("suppressed", pixels, graySize)
+	add("strong", stages.Strong.Cardinality(), pointSize)
+	add("weak", stages.Weak.Cardinality(), pointSize)
+	add("edges", pixels, graySize)
+
+	return usage
+}
@@ -0,0 +1,43 @@
+package canny
+
+import (
+	"context"
+	"testing"
+	"unsafe"
+)
+
+func TestStageMemory(t *testing.T) {
+	stages, err := DetectStages(context.Background(), square(12), Options{Blur: true, MinRatio: 0.1, MaxRatio: 0.3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// GrayPixel is two bytes, a direction is a float64 and a point two ints.
+	pointSize := 2 * int(unsafe.Sizeof(0))
+	want := map[string]int{
+		"blur":       12 * 12 * 2,
+		"magnitudes": 12 * 12 * 2,
+		"directions": 12 * 12 * 8,
+		"suppressed": 12 * 12 * 2,
+		"strong":     stages.Strong.Cardinality() * pointSize,
+		"weak":       stages.Weak.Cardinality() * pointSize,
+		"edges":      12 * 12 * 2,
+	}
+	usage := StageMemory(stages, true)
+	if len(usage) != len(want) {
+		t.Fatalf("got %d stages, want %d", len(usage), len(want))
+	}
+	for _, u := range usage {
+		if (u.Bytes != want[u.Stage]) || (u.Bytes != u.Elements*u.ElementSize) {
+			t.Errorf("%s: got %d x %d = %d bytes, want %d", u.Stage, u.Elements, u.ElementSize, u.Bytes, want[u.Stage])
+		}
+	}
+	if stages.Strong.Cardinality() == 0 {
+		t.Error("expected strong points on the square")
+	}
+
+	for _, u := range StageMemory(stages, false) {
+		if u.Stage == "blur" {
+			t.Error("expected no blur buffer without blur")
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package canny
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+var PNG_SIGNATURE = []byte("\x89PNG\r\n\x1a\n")
+var JPEG_SOI = []byte{0xff, 0xd8}
+var EXIF_HEADER = []byte("Exif\x00\x00")
+
+// PNG_METADATA_CHUNKS are the ancillary PNG chunk types carried over by
+// CopyMetadata.
+var PNG_METADATA_CHUNKS = []string{"tEXt", "zTXt", "iTXt", "eXIf"}
+
+// Metadata holds the ancillary data of an encoded image.
+type Metadata struct {
+	// Exif is the raw EXIF payload, starting with the TIFF header.
+	Exif []byte
+	// PNGChunks are complete text chunks (length, type, data and CRC) of a
+	// PNG source.
+	PNGChunks [][]byte
+}
+
+// ReadMetadata extracts the EXIF and text metadata of an encoded PNG or JPEG.
+func ReadMetadata(data []byte) (Metadata, error) {
+	var md Metadata
+
+	switch {
+	case bytes.HasPrefix(data, PNG_SIGNATURE):
+		err := forEachPNGChunk(data, func(chunkType string, chunk []byte) {
+			for _, t := range PNG_METADATA_CHUNKS {
+				if chunkType == t {
+					md.PNGChunks = append(md.PNGChunks, chunk)
+				}
+			}
+			if chunkType == "eXIf" {
+				md.Exif = chunk[8 : len(chunk)-4]
+			}
+		})
+		return md, err
+	case bytes.HasPrefix(data, JPEG_SOI):
+		for pos := 2; pos+4 <= len(data); {
+			if data[pos] != 0xff {
+				return md, errors.New("invalid jpeg marker")
+			}
+			marker := data[pos+1]
+			if (marker == 0xda) || (marker == 0xd9) {
+				break
+			}
+			length := int(binary.BigEndian.Uint16(data[pos+2:]))
+			if pos+2+length > len(data) {
+				return md, errors.New("truncated jpeg segment")
+			}
+			segment := data[pos+4 : pos+2+length]
+			if (marker == 0xe1) && bytes.HasPrefix(segment, EXIF_HEADER) {
+				md.Exif = segment[len(EXIF_HEADER):]
+			}
+			pos += 2 + length
+		}
+		return md, nil
+	default:
+		return md, errors.New("unsupported format for metadata")
+	}
+}
+
+// CopyMetadata adds md to an encoded PNG or JPEG. PNG text chunks are only
+// kept for PNG outputs, EXIF is carried over to both formats.
+func CopyMetadata(data []byte, md Metadata) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, PNG_SIGNATURE):
+		var chunks []byte
+		for _, chunk := range md.PNGChunks {
+			chunks = append(chunks, chunk...)
+		}
+		if (md.Exif != nil) && !containsEXIfChunk(md.PNGChunks) {
+			chunks = append(chunks, pngChunk("eXIf", md.Exif)...)
+		}
+		// Metadata chunks go right after the IHDR chunk, which always has
+		// 13 bytes of data.
+		ihdrEnd := len(PNG_SIGNATURE) + 8 + 13 + 4
+		if len(data) < ihdrEnd {
+			return nil, errors.New("truncated png")
+		}
+		return concat(data[:ihdrEnd], chunks, data[ihdrEnd:]), nil
+	case bytes.HasPrefix(data, JPEG_SOI):
+		if md.Exif == nil {
+			return data, nil
+		}
+		length := 2 + len(EXIF_HEADER) + len(md.Exif)
+		if length > 0xffff {
+			return nil, errors.New("exif data too large for jpeg")
+		}
+		segment := []byte{0xff, 0xe1, byte(length >> 8), byte(length)}
+		segment = concat(segment, EXIF_HEADER, md.Exif)
+		return concat(data[:2], segment, data[2:]), nil
+	default:
+		return nil, errors.New("unsupported format for metadata")
+	}
+}
+
+func forEachPNGChunk(data []byte, fn func(chunkType string, chunk []byte)) error {
+	for pos := len(PNG_SIGNATURE); pos < len(data); {
+		if pos+12 > len(data) {
+			return errors.New("truncated png chunk")
+		}
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		end := pos + 12 + length
+		if (length < 0) || (end > len(data)) {
+			return errors.New("truncated png chunk")
+		}
+		fn(string(data[pos+4:pos+8]), data[pos:end])
+		pos = end
+	}
+	return nil
+}
+
+func containsEXIfChunk(chunks [][]byte) bool {
+	for _, chunk := range chunks {
+		if string(chunk[4:8]) == "eXIf" {
+			return true
+		}
+	}
+	return false
+}
+
+func pngChunk(chunkType string, payload []byte) []byte {
+	chunk := make([]byte, 8, 12+len(payload))
+	binary.BigEndian.PutUint32(chunk, uint32(len(payload)))
+	copy(chunk[4:], chunkType)
+	chunk = append(chunk, payload...)
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.ChecksumIEEE(chunk[4:]))
+	return append(chunk, crc...)
+}
+
+func concat(parts ...[]byte) []byte {
+	var result []byte
+	for _, part := range parts {
+		result = append(result, part...)
+	}
+	return result
+}
@@ -0,0 +1,58 @@
+package canny
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestCopyMetadataRoundTrip(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 4, 4))
+	exif := []byte("MM\x00*\x00\x00\x00\x08\x00\x00")
+	text := pngChunk("tEXt", []byte("Comment\x00edges"))
+
+	var png bytes.Buffer
+	if err := Encode(&png, img, "png", EncodeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	withText, err := CopyMetadata(png.Bytes(), Metadata{Exif: exif, PNGChunks: [][]byte{text}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := image.Decode(bytes.NewReader(withText)); err != nil {
+		t.Fatalf("png with metadata doesn't decode: %v", err)
+	}
+	md, err := ReadMetadata(withText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (len(md.PNGChunks) != 2) || !bytes.Equal(md.PNGChunks[0], text) {
+		t.Errorf("got png chunks %q, want the text chunk and an eXIf chunk", md.PNGChunks)
+	}
+	if !bytes.Equal(md.Exif, exif) {
+		t.Errorf("png: got exif %q, want %q", md.Exif, exif)
+	}
+
+	var jpeg bytes.Buffer
+	if err := Encode(&jpeg, img, "jpeg", EncodeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	withExif, err := CopyMetadata(jpeg.Bytes(), Metadata{Exif: exif})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := image.Decode(bytes.NewReader(withExif)); err != nil {
+		t.Fatalf("jpeg with metadata doesn't decode: %v", err)
+	}
+	md, err = ReadMetadata(withExif)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(md.Exif, exif) {
+		t.Errorf("jpeg: got exif %q, want %q", md.Exif, exif)
+	}
+
+	if _, err := CopyMetadata([]byte("GIF89a"), md); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
@@ -0,0 +1,155 @@
+package canny
+
+import (
+	"image"
+)
+
+// erode returns the binary erosion of mask with a 3x3 square: a pixel stays
+// set only if it and all its 8 neighbours are set. Pixels outside the image
+// count as unset.
+func erode(mask [][]bool) [][]bool {
+	result := make([][]bool, len(mask))
+	for y := range mask {
+		result[y] = make([]bool, len(mask[y]))
+		for x := range mask[y] {
+			result[y][x] = mask[y][x] && (countNeighbours(mask, x, y) == len(NEIGHBOUR_OFFSETS))
+		}
+	}
+
+	return result
+}
+
+// erodeCandidates erodes the mask of the gradient magnitudes in pixels above
+// the low threshold opts.PreErode times and zeroes the magnitudes that don't
+// survive. It runs before the non-maximum suppression, whose 1 pixel wide
+// output no erosion would leave anything of, so the low threshold is taken
+// from the unthinned magnitudes here. Only responses at least
+// 2*opts.PreErode+1 pixels thick in every direction survive, so this trades
+// sensitivity for cleanliness: it prunes thin noise, but also faint or sharp
+// true edges whose gradient response is narrow.
+func erodeCandidates(pixels [][]GrayPixel, opts Options) {
+	_, low := thresholds(pixels, opts)
+	mask := make([][]bool, len(pixels))
+	for y := range pixels {
+		mask[y] = make([]bool, len(pixels[y]))
+		for x := range pixels[y] {
+			mask[y][x] = float64(pixels[y][x].Y) > low
+		}
+	}
+	eroded := mask
+	for i := 0; i < opts.PreErode; i++ {
+		eroded = erode(eroded)
+	}
+
+	for y := range pixels {
+		for x := range pixels[y] {
+			if mask[y][x] && !eroded[y][x] {
+				pixels[y][x].Y = uint8(0)
+			}
+		}
+	}
+}
+
+// ZHANG_SUEN_OFFSETS lists the neighbours P2 to P9 of the Zhang-Suen
+// thinning, clockwise starting above the pixel.
+var ZHANG_SUEN_OFFSETS = []image.Point{
+	{0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1},
+}
+
+// skeletonize thins mask in place to a skeleton one pixel wide with the
+// Zhang-Suen algorithm. Each iteration runs two sub-passes that peel
+// boundary pixels from the south-east and the north-west, keeping pixels
+// whose removal would break connectivity or shorten an end point, until
+// nothing changes. Pixels outside the image count as unset.
+func skeletonize(mask [][]bool) {
+	at := func(x, y int) bool {
+		return (y >= 0) && (y < len(mask)) && (x >= 0) && (x < len(mask[y])) && mask[y][x]
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for pass := 0; pass < 2; pass++ {
+			var remove []image.Point
+			for y := range mask {
+				for x := range mask[y] {
+					if !mask[y][x] {
+						continue
+					}
+					var p [8]bool
+					count, transitions := 0, 0
+					for i, offset := range ZHANG_SUEN_OFFSETS {
+						p[i] = at(x+offset.X, y+offset.Y)
+						if p[i] {
+							count++
+						}
+					}
+					for i := range p {
+						if !p[i] && p[(i+1)%len(p)] {
+							transitions++
+						}
+					}
+					if (count < 2) || (count > 6) || (transitions != 1) {
+						continue
+					}
+					// p[0], p[2], p[4] and p[6] are P2, P4, P6 and P8.
+					if (pass == 0) && (p[0] && p[2] && p[4] || p[2] && p[4] && p[6]) {
+						continue
+					}
+					if (pass == 1) && (p[0] && p[2] && p[6] || p[0] && p[4] && p[6]) {
+						continue
+					}
+					remove = append(remove, image.Point{x, y})
+				}
+			}
+			for _, r := range remove {
+				mask[r.Y][r.X] = false
+			}
+			if len(remove) > 0 {
+				changed = true
+			}
+		}
+	}
+}
+
+// skeletonizeEdges thins the edges of pixels in place with skeletonize and
+// clears the removed pixels.
+func skeletonizeEdges(pixels [][]GrayPixel) {
+	mask := EdgeMask(pixels)
+	skeletonize(mask)
+	for y := range mask {
+		for x := range mask[y] {
+			if !mask[y][x] {
+				pixels[y][x].Y = uint8(0)
+			}
+		}
+	}
+}
+
+// dilate returns the binary dilation of mask with a square of the given
+// radius: a pixel is set if any pixel within radius in both directions is.
+func dilate(mask [][]bool, radius int) [][]bool {
+	result := make([][]bool, len(mask))
+	for y := range mask {
+		result[y] = make([]bool, len(mask[y]))
+	}
+
+	for y := range mask {
+		for x := range mask[y] {
+			if !mask[y][x] {
+				continue
+			}
+			for i := y - radius; i <= y+radius; i++ {
+				if (i < 0) || (i >= len(result)) {
+					continue
+				}
+				for j := x - radius; j <= x+radius; j++ {
+					if (j >= 0) && (j < len(result[i])) {
+						result[i][j] = true
+					}
+				}
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,145 @@
+package canny
+
+import (
+	"context"
+	"testing"
+)
+
+// barWithNoise has a bright vertical bar between x = 16 and 28 and a few
+// isolated bright pixels.
+func barWithNoise() [][]GrayPixel {
+	return newPixels(44, 40, func(x, y int) uint8 {
+		if (x >= 16) && (x < 28) {
+			return 200
+		}
+		if ((x == 5) && (y == 8)) || ((x == 36) && (y == 30)) || ((x == 6) && (y == 26)) {
+			return 200
+		}
+		return 20
+	})
+}
+
+func TestPreErodeRemovesNoiseKeepsThickEdges(t *testing.T) {
+	opts := Options{Blur: true, MinRatio: 0.05, MaxRatio: 0.2}
+	plain, err := CannyEdgeDetectContext(context.Background(), barWithNoise(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.PreErode = 1
+	eroded, err := CannyEdgeDetectContext(context.Background(), barWithNoise(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	noise := func(edges [][]GrayPixel) int {
+		count := 0
+		for y := range edges {
+			for x := range edges[y] {
+				if (edges[y][x].Y != 0) && ((x < 12) || (x >= 32)) {
+					count++
+				}
+			}
+		}
+		return count
+	}
+	if noise(plain) == 0 {
+		t.Fatal("expected edges around the isolated pixels without erosion")
+	}
+	if n := noise(eroded); n != 0 {
+		t.Errorf("got %d edge pixels around the isolated pixels after erosion", n)
+	}
+
+	// The bar edges survive the erosion unchanged away from the image border.
+	for y := 2; y < 38; y++ {
+		for x := 12; x < 32; x++ {
+			if eroded[y][x] != plain[y][x] {
+				t.Errorf("(%d, %d): got %d after erosion, %d without", x, y, eroded[y][x].Y, plain[y][x].Y)
+			}
+		}
+	}
+	if countEdges(eroded) == 0 {
+		t.Error("expected the bar edges to survive")
+	}
+}
+
+func TestDetectBandsRejectsPreErode(t *testing.T) {
+	pixels := barWithNoise()
+	read := func(minY, maxY int) ([][]GrayPixel, error) { return pixels[minY:maxY], nil }
+	_, err := DetectBands(context.Background(), 44, 40, 16, read, Options{Blur: true, MinRatio: 0.1, MaxRatio: 0.3, PreErode: 1})
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
+
+// blocks counts the 2x2 blocks of edge pixels in pixels.
+func blocks(pixels [][]GrayPixel) int {
+	count := 0
+	for y := 1; y < len(pixels); y++ {
+		for x := 1; x < len(pixels[y]); x++ {
+			if (pixels[y][x].Y != 0) && (pixels[y-1][x].Y != 0) && (pixels[y][x-1].Y != 0) && (pixels[y-1][x-1].Y != 0) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func TestSkeletonizeThinsBar(t *testing.T) {
+	mask := make([][]bool, 9)
+	for y := range mask {
+		mask[y] = make([]bool, 20)
+		for x := range mask[y] {
+			mask[y][x] = (y >= 2) && (y < 7) && (x >= 2) && (x < 18)
+		}
+	}
+	skeletonize(mask)
+	// Zhang-Suen shortens the ends of the bar by about its half width.
+	for x := 5; x < 15; x++ {
+		set := 0
+		for y := range mask {
+			if mask[y][x] {
+				set++
+			}
+		}
+		if set != 1 {
+			t.Errorf("column %d: got %d skeleton pixels, want 1", x, set)
+		}
+	}
+}
+
+func TestThinning(t *testing.T) {
+	// A cross of two 7 pixel wide bars.
+	cross := func() [][]GrayPixel {
+		return newPixels(40, 40, func(x, y int) uint8 {
+			if ((x >= 17) && (x < 24)) || ((y >= 17) && (y < 24)) {
+				return 200
+			}
+			return 20
+		})
+	}
+	edges := make(map[Thinning][][]GrayPixel)
+	for _, thinning := range THINNINGS {
+		stages, err := DetectStages(context.Background(), cross(), Options{Blur: true, MinRatio: 0.2, MaxRatio: 0.6, Thinning: thinning})
+		if err != nil {
+			t.Fatal(err)
+		}
+		edges[thinning] = stages.Edges
+	}
+
+	if got := blocks(edges[THINNING_SKELETON]); got != 0 {
+		t.Errorf("got %d 2x2 blocks in the skeleton, want none", got)
+	}
+	if countEdges(edges[THINNING_SKELETON]) == 0 {
+		t.Error("the skeleton is empty")
+	}
+	if blocks(edges[THINNING_NONE]) <= blocks(edges[THINNING_NMS]) {
+		t.Errorf("got %d 2x2 blocks without thinning, want more than the %d of NMS", blocks(edges[THINNING_NONE]), blocks(edges[THINNING_NMS]))
+	}
+	plain, err := DetectStages(context.Background(), cross(), Options{Blur: true, MinRatio: 0.2, MaxRatio: 0.6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalPixels(plain.Edges, edges[THINNING_NMS]) {
+		t.Error("THINNING_NMS differs from the default")
+	}
+}
@@ -0,0 +1,290 @@
+package canny
+
+import (
+	"context"
+	"image"
+	"math"
+)
+
+// Operator is a pair of square kernels, in row-major order, approximating the
+// horizontal and vertical derivatives of the image.
+type Operator struct {
+	Name string
+	X    []float64
+	Y    []float64
+}
+
+// CENTRAL_X and CENTRAL_Y are plain central differences without the smoothing
+// rows of Sobel. They are cheap and sufficient for clean synthetic inputs but
+// much more sensitive to noise.
+var CENTRAL_X = []float64{0, 0, 0, 1, 0, -1, 0, 0, 0}
+var CENTRAL_Y = []float64{0, 1, 0, 0, 0, 0, 0, -1, 0}
+
+var SCHARR_X = []float64{3, 0, -3, 10, 0, -10, 3, 0, -3}
+var SCHARR_Y = []float64{3, 10, 3, 0, 0, 0, -3, -10, -3}
+var PREWITT_X = []float64{1, 0, -1, 1, 0, -1, 1, 0, -1}
+var PREWITT_Y = []float64{1, 1, 1, 0, 0, 0, -1, -1, -1}
+
+var SOBEL = Operator{"sobel", SOBEL_X, SOBEL_Y}
+var CENTRAL_DIFFERENCE = Operator{"central", CENTRAL_X, CENTRAL_Y}
+var SCHARR = Operator{"scharr", SCHARR_X, SCHARR_Y}
+var PREWITT = Operator{"prewitt", PREWITT_X, PREWITT_Y}
+
+// GD5_SIGMA is the standard deviation of the Gaussian underlying GD5.
+const GD5_SIGMA = 1.0
+
+// GD5 is the 5x5 derivative of a Gaussian. It is rotationally symmetric and
+// smooths more than the 3x3 operators, which gives smoother and more
+// accurate gradients on high resolution images at about three times the cost.
+var GD5 = GaussianDerivativeOperator("gd5", 5, GD5_SIGMA)
+
+// OPERATORS maps the names accepted on the command line to their operators.
+var OPERATORS = map[string]Operator{
+	SOBEL.Name:              SOBEL,
+	CENTRAL_DIFFERENCE.Name: CENTRAL_DIFFERENCE,
+	SCHARR.Name:             SCHARR,
+	PREWITT.Name:            PREWITT,
+	GD5.Name:                GD5,
+}
+
+// GaussianDerivativeOperator returns the x and y derivatives of a Gaussian
+// with the given standard deviation, sampled on size x size pixels. Like
+// SOBEL, the kernels compute left minus right and top minus bottom, and they
+// are scaled to the same response of 8 to a unit ramp, so magnitudes and
+// thresholds are comparable between the operators.
+func GaussianDerivativeOperator(name string, size int, sigma float64) Operator {
+	center := size / 2
+	x := make([]float64, size*size)
+	y := make([]float64, size*size)
+	var ramp float64
+
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			dx, dy := float64(j-center), float64(i-center)
+			g := math.Exp(-(dx*dx + dy*dy) / (2 * sigma * sigma))
+			x[i*size+j] = -dx * g
+			y[i*size+j] = -dy * g
+			ramp += dx * dx * g
+		}
+	}
+	for i := range x {
+		x[i] *= 8 / ramp
+		y[i] *= 8 / ramp
+	}
+
+	return Operator{name, x, y}
+}
+
+// Size returns the side length of the operator's kernels.
+func (op Operator) Size() int {
+	return int(math.Sqrt(float64(len(op.X))))
+}
+
+// Integer reports whether all kernel coefficients of the operator are
+// integers, as the fixed-point pipeline requires.
+func (op Operator) Integer() bool {
+	for i := range op.X {
+		if (op.X[i] != math.Trunc(op.X[i])) || (op.Y[i] != math.Trunc(op.Y[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// AngleConvention selects how exported gradient angles are expressed.
+type AngleConvention int
+
+const (
+	// UNSIGNED_ORIENTATION reports the gradient orientation in [0, 180)
+	// degrees. Edges have no polarity, so this is usually what's wanted.
+	UNSIGNED_ORIENTATION AngleConvention = iota
+	// SIGNED_DIRECTION reports the full gradient direction in (-180, 180]
+	// degrees, pointing towards increasing brightness.
+	SIGNED_DIRECTION
+)
+
+// Gradients returns the Sobel gradient magnitude and angle (in degrees) of
+// every pixel. Angles are measured in image coordinates, with x to the right
+// and y down, in the given convention.
+//
+// Internally non-maximum suppression bins the direction modulo 180 degrees,
+// in [-90, 90) as returned by gradientDirection, instead.
+func Gradients(pixels [][]GrayPixel, convention AngleConvention) ([][]GrayPixel, [][]float64) {
+	gx, gy := gradientVectors(context.Background(), pixelValues(pixels), SOBEL)
+	var magnitudes [][]GrayPixel
+	var angles [][]float64
+
+	for y := range gx {
+		magnitudeRow := make([]GrayPixel, len(gx[y]))
+		angleRow := make([]float64, len(gx[y]))
+		for x := range gx[y] {
+			magnitudeRow[x] = GrayPixel{magnitude(gx[y][x], gy[y][x], ROUND_NEAREST, NORM_L2), uint8(255)}
+			// The kernels compute left minus right and top minus bottom,
+			// negate them to point towards increasing brightness.
+			angle := math.Atan2(-gy[y][x], -gx[y][x]) * (180 / math.Pi)
+			if convention == UNSIGNED_ORIENTATION {
+				angle = math.Mod(angle+180, 180)
+			} else if angle == -180 {
+				angle = 180
+			}
+			angleRow[x] = angle
+		}
+		magnitudes = append(magnitudes, magnitudeRow)
+		angles = append(angles, angleRow)
+	}
+
+	return magnitudes, angles
+}
+
+// filterAngleRange zeroes every pixel whose gradient orientation lies outside
+// r. directions are in the convention of gradientDirection, orientations in
+// [0, 180) degrees in the convention of Gradients with UNSIGNED_ORIENTATION,
+// so horizontal edges have a gradient orientation of 90. If r[0] > r[1] the
+// range wraps around 0.
+func filterAngleRange(pixels [][]GrayPixel, directions [][]float64, r [2]float64) {
+	for y := range pixels {
+		for x := range pixels[y] {
+			if pixels[y][x].Y == 0 {
+				continue
+			}
+			angle := math.Mod(directions[y][x]+180, 180)
+			inside := (angle >= r[0]) && (angle <= r[1])
+			if r[0] > r[1] {
+				inside = (angle >= r[0]) || (angle <= r[1])
+			}
+			if !inside {
+				pixels[y][x].Y = uint8(0)
+			}
+		}
+	}
+}
+
+// gradientVectors convolves every pixel with the X and Y kernels of op.
+func gradientVectors(ctx context.Context, pixels [][]float64, op Operator) ([][]float64, [][]float64) {
+	if op.X == nil {
+		op = SOBEL
+	}
+	var gx, gy [][]float64
+
+	size := op.Size()
+	kernel_X := newMatrix(size, size, op.X)
+	kernel_Y := newMatrix(size, size, op.Y)
+
+	for y := 0; y < len(pixels); y++ {
+		if canceled(ctx) {
+			break
+		}
+		rowX := make([]float64, 0, len(pixels[y]))
+		rowY := make([]float64, 0, len(pixels[y]))
+		for x := 0; x < len(pixels[y]); x++ {
+			imagePane := getSurroundingPixelMatrix(pixels, y, x, size)
+			rowX = append(rowX, convolve(imagePane, kernel_X))
+			rowY = append(rowY, convolve(imagePane, kernel_Y))
+		}
+		gx = append(gx, rowX)
+		gy = append(gy, rowY)
+	}
+
+	return gx, gy
+}
+
+// gradient computes the gradient magnitude and direction (in degrees) of every
+// pixel with the given operator.
+func gradient(ctx context.Context, pixels [][]GrayPixel, op Operator, rounding RoundingMode, norm GradientNorm) ([][]GrayPixel, [][]float64) {
+	return gradientValues(ctx, pixelValues(pixels), op, rounding, norm)
+}
+
+// gradientValues is like gradient for gray values that needn't be integers.
+func gradientValues(ctx context.Context, pixels [][]float64, op Operator, rounding RoundingMode, norm GradientNorm) ([][]GrayPixel, [][]float64) {
+	gx, gy := gradientVectors(ctx, pixels, op)
+	var result [][]GrayPixel
+	var directions [][]float64
+
+	for y := range gx {
+		var resultRow []GrayPixel
+		var angleRow []float64
+		for x := range gx[y] {
+			res_X := gx[y][x]
+			res_Y := gy[y][x]
+
+			resultRow = append(resultRow, GrayPixel{magnitude(res_X, res_Y, rounding, norm), uint8(255)})
+			angleRow = append(angleRow, gradientDirection(res_X, res_Y))
+		}
+		result = append(result, resultRow)
+		directions = append(directions, angleRow)
+	}
+
+	return result, directions
+}
+
+// gradientDirection returns the direction of the gradient (gx, gy) in
+// degrees. Edges have no polarity, so it is reduced modulo 180 to [-90, 90),
+// and vertical gradients are -90 whatever their sign. A zero gradient has
+// direction 0.
+func gradientDirection(gx, gy float64) float64 {
+	angle := math.Atan2(gy, gx) * (180 / math.Pi)
+	if angle >= 90 {
+		angle -= 180
+	} else if angle < -90 {
+		angle += 180
+	}
+	return angle
+}
+
+// radialGradient computes the component of the gradient along the direction
+// from center to every pixel, emphasizing edges perpendicular to the radii of
+// circles around center. Directions are the radial directions, in the same
+// convention as gradient. The pixel at center keeps its full gradient.
+func radialGradient(ctx context.Context, pixels [][]GrayPixel, op Operator, center image.Point, rounding RoundingMode, norm GradientNorm) ([][]GrayPixel, [][]float64) {
+	return radialGradientValues(ctx, pixelValues(pixels), op, center, rounding, norm)
+}
+
+// radialGradientValues is like radialGradient for gray values that needn't be
+// integers.
+func radialGradientValues(ctx context.Context, pixels [][]float64, op Operator, center image.Point, rounding RoundingMode, norm GradientNorm) ([][]GrayPixel, [][]float64) {
+	gx, gy := gradientVectors(ctx, pixels, op)
+	var result [][]GrayPixel
+	var directions [][]float64
+
+	for y := range gx {
+		var resultRow []GrayPixel
+		var angleRow []float64
+		for x := range gx[y] {
+			rx := float64(x - center.X)
+			ry := float64(y - center.Y)
+			length := math.Hypot(rx, ry)
+			if length == 0 {
+				resultRow = append(resultRow, GrayPixel{magnitude(gx[y][x], gy[y][x], rounding, norm), uint8(255)})
+				angleRow = append(angleRow, gradientDirection(gx[y][x], gy[y][x]))
+				continue
+			}
+
+			projection := math.Abs(gx[y][x]*rx+gy[y][x]*ry) / length
+			resultRow = append(resultRow, GrayPixel{quantize(projection, rounding), uint8(255)})
+			angleRow = append(angleRow, gradientDirection(rx, ry))
+		}
+		result = append(result, resultRow)
+		directions = append(directions, angleRow)
+	}
+
+	return result, directions
+}
+
+// GradientNorm selects how the X and Y gradients are combined into the
+// magnitude.
+type GradientNorm int
+
+const (
+	// NORM_L2 is the euclidean length sqrt(gx² + gy²). This is the default.
+	NORM_L2 GradientNorm = iota
+	// NORM_L1 is |gx| + |gy|, which avoids the square root but depends on
+	// the gradient direction.
+	NORM_L1
+)
+
+func magnitude(gx, gy float64, rounding RoundingMode, norm GradientNorm) uint8 {
+	if norm == NORM_L1 {
+		return quantize(math.Abs(gx)+math.Abs(gy), rounding)
+	}
+	return quantize(math.Sqrt(math.Pow(gx, 2)+math.Pow(gy, 2)), rounding)
+}
@@ -0,0 +1,267 @@
+package canny
+
+import (
+	"context"
+	"image"
+	"math"
+	"testing"
+)
+
+func TestGradientOperatorsOnRamp(t *testing.T) {
+	pixels := newPixels(8, 8, func(x, y int) uint8 { return uint8(10 * x) })
+	for _, c := range []struct {
+		op   Operator
+		want uint8
+	}{
+		{SOBEL, 80},
+		{CENTRAL_DIFFERENCE, 20},
+		{Operator{}, 80},
+	} {
+		magnitudes, directions := gradient(context.Background(), pixels, c.op, ROUND_NEAREST, NORM_L2)
+		for y := 1; y < 7; y++ {
+			for x := 1; x < 7; x++ {
+				if magnitudes[y][x].Y != c.want {
+					t.Errorf("%q at (%d, %d): got magnitude %d, want %d", c.op.Name, x, y, magnitudes[y][x].Y, c.want)
+				}
+				if directions[y][x] != 0 {
+					t.Errorf("%q at (%d, %d): got direction %v, want 0", c.op.Name, x, y, directions[y][x])
+				}
+			}
+		}
+	}
+}
+
+func TestDetectStagesUsesOperator(t *testing.T) {
+	pixels := square(24)
+	sobel, err := DetectStages(context.Background(), pixels, Options{MinRatio: 0.2, MaxRatio: 0.6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	explicit, err := DetectStages(context.Background(), pixels, Options{MinRatio: 0.2, MaxRatio: 0.6, Operator: SOBEL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	central, err := DetectStages(context.Background(), pixels, Options{MinRatio: 0.2, MaxRatio: 0.6, Operator: CENTRAL_DIFFERENCE})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalPixels(sobel.Edges, explicit.Edges) {
+		t.Error("the zero operator differs from SOBEL")
+	}
+	if equalPixels(sobel.Edges, central.Edges) {
+		t.Error("CENTRAL_DIFFERENCE found the same edges as SOBEL")
+	}
+}
+
+func TestGradientsAngleConventions(t *testing.T) {
+	cases := []struct {
+		name             string
+		value            func(x, y int) uint8
+		signed, unsigned float64
+	}{
+		{"brighter to the right", func(x, y int) uint8 { return uint8(10 * x) }, 0, 0},
+		{"brighter downwards", func(x, y int) uint8 { return uint8(10 * y) }, 90, 90},
+		{"brighter to the left", func(x, y int) uint8 { return uint8(100 - 10*x) }, 180, 0},
+		{"brighter upwards", func(x, y int) uint8 { return uint8(100 - 10*y) }, -90, 90},
+		{"brighter to the bottom right", func(x, y int) uint8 { return uint8(10 * (x + y)) }, 45, 45},
+	}
+
+	for _, c := range cases {
+		pixels := newPixels(7, 7, c.value)
+		for _, convention := range []struct {
+			convention AngleConvention
+			want       float64
+		}{{SIGNED_DIRECTION, c.signed}, {UNSIGNED_ORIENTATION, c.unsigned}} {
+			magnitudes, angles := Gradients(pixels, convention.convention)
+			if magnitudes[3][3].Y == 0 {
+				t.Errorf("%s: zero magnitude", c.name)
+			}
+			if math.Abs(angles[3][3]-convention.want) > 1e-9 {
+				t.Errorf("%s, convention %d: got %v degrees, want %v", c.name, convention.convention, angles[3][3], convention.want)
+			}
+		}
+	}
+}
+
+func TestRadialCenterFavoursCircularEdges(t *testing.T) {
+	// A disc around (32, 32) and a bar that runs along one of its radii.
+	center := image.Point{32, 32}
+	pixels := newPixels(64, 64, func(x, y int) uint8 {
+		dx, dy := x-center.X, y-center.Y
+		switch {
+		case dx*dx+dy*dy <= 12*12:
+			return 80
+		case (x >= 48) && (y >= 30) && (y < 35):
+			return 80
+		}
+		return 50
+	})
+	// contrast returns the ratio of the strongest magnitude on the disc
+	// outline to the strongest one along the bar.
+	contrast := func(opts Options) float64 {
+		stages, err := DetectStages(context.Background(), pixels, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var circle, bar uint8
+		for y := range stages.Magnitudes {
+			for x, m := range stages.Magnitudes[y] {
+				dx, dy := x-center.X, y-center.Y
+				if r := dx*dx + dy*dy; (r >= 10*10) && (r <= 14*14) && (m.Y > circle) {
+					circle = m.Y
+				}
+				if (x >= 52) && (x < 60) && (m.Y > bar) {
+					bar = m.Y
+				}
+			}
+		}
+		if bar == 0 {
+			return math.Inf(1)
+		}
+		return float64(circle) / float64(bar)
+	}
+
+	plain := contrast(Options{})
+	radial := contrast(Options{RadialCenter: &center})
+	if radial <= 2*plain {
+		t.Errorf("got circle to bar contrast %v with a radial center, want well above %v", radial, plain)
+	}
+
+	stages, err := DetectStages(context.Background(), pixels, Options{RadialCenter: &center})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := directionBin(stages.Directions[center.Y][center.X+20], ROUND_HALF_UP); got != "horizontal" {
+		t.Errorf("got direction bin %q right of the center, want the radial one", got)
+	}
+}
+
+func TestGradientNorms(t *testing.T) {
+	// A linear ramp with gx = 4 * -20 and gy = 4 * -10 under SOBEL.
+	pixels := newPixels(5, 5, func(x, y int) uint8 { return uint8(10*x + 5*y) })
+	for _, c := range []struct {
+		norm GradientNorm
+		want uint8
+	}{
+		{NORM_L2, 89},
+		{NORM_L1, 120},
+	} {
+		magnitudes, _ := gradient(context.Background(), pixels, SOBEL, ROUND_NEAREST, c.norm)
+		fixed, _, err := gradientFixed(context.Background(), pixels, SOBEL, ROUND_NEAREST, c.norm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (magnitudes[2][2].Y != c.want) || (fixed[2][2].Y != c.want) {
+			t.Errorf("norm %d: got %d and fixed-point %d, want %d", c.norm, magnitudes[2][2].Y, fixed[2][2].Y, c.want)
+		}
+	}
+
+	for _, norm := range []GradientNorm{NORM_L2, NORM_L1} {
+		edges, err := CannyEdgeDetectContext(context.Background(), square(16), Options{Blur: true, MinRatio: 0.1, MaxRatio: 0.3, Norm: norm})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for y := 6; y < 10; y++ {
+			if (edges[y][4].Y == 0) && (edges[y][3].Y == 0) {
+				t.Errorf("norm %d: missing the left edge of the square at row %d", norm, y)
+			}
+		}
+		if edges[8][8].Y != 0 {
+			t.Errorf("norm %d: unexpected edge inside the square", norm)
+		}
+	}
+}
+
+func TestAngleRangeKeepsOrientation(t *testing.T) {
+	for _, c := range []struct {
+		r          [2]float64
+		horizontal bool
+	}{
+		{[2]float64{80, 100}, true},
+		{[2]float64{170, 10}, false},
+	} {
+		r := c.r
+		stages, err := DetectStages(context.Background(), square(24), Options{MinRatio: 0.1, MaxRatio: 0.3, AngleRange: &r})
+		if err != nil {
+			t.Fatal(err)
+		}
+		kept := 0
+		for y := range stages.Edges {
+			for x := range stages.Edges[y] {
+				if stages.Edges[y][x].Y == 0 {
+					continue
+				}
+				kept++
+				// The edges of the square closer to its top or bottom are
+				// horizontal.
+				dx := math.Abs(float64(x) - 11.5)
+				dy := math.Abs(float64(y) - 11.5)
+				if (dy > dx) != c.horizontal {
+					t.Errorf("range %v: kept edge at (%d, %d)", c.r, x, y)
+				}
+			}
+		}
+		if kept == 0 {
+			t.Errorf("range %v: expected edges", c.r)
+		}
+	}
+}
+
+func TestGD5OnRamp(t *testing.T) {
+	pixels := newPixels(10, 10, func(x, y int) uint8 { return uint8(10 * x) })
+	magnitudes, directions := gradient(context.Background(), pixels, GD5, ROUND_NEAREST, NORM_L2)
+	for y := 2; y < 8; y++ {
+		for x := 2; x < 8; x++ {
+			if magnitudes[y][x].Y != 80 {
+				t.Errorf("(%d, %d): got magnitude %d, want the 80 of SOBEL", x, y, magnitudes[y][x].Y)
+			}
+			if math.Abs(directions[y][x]) > 1e-9 {
+				t.Errorf("(%d, %d): got direction %v, want 0", x, y, directions[y][x])
+			}
+		}
+	}
+}
+
+func TestGD5IsWiderAndSmootherThanSobel(t *testing.T) {
+	pixels := newPixels(20, 9, func(x, y int) uint8 {
+		if x >= 10 {
+			return 110
+		}
+		return 50
+	})
+	profile := func(op Operator) (width int, maxJump int) {
+		magnitudes, _ := gradient(context.Background(), pixels, op, ROUND_NEAREST, NORM_L2)
+		row := magnitudes[4]
+		for x := range row {
+			if row[x].Y != 0 {
+				width++
+			}
+			if x > 0 {
+				if jump := abs(int(row[x].Y) - int(row[x-1].Y)); jump > maxJump {
+					maxJump = jump
+				}
+			}
+		}
+		return width, maxJump
+	}
+	sobelWidth, sobelJump := profile(SOBEL)
+	gd5Width, gd5Jump := profile(GD5)
+	if gd5Width <= sobelWidth {
+		t.Errorf("got a response %d pixels wide with GD5, %d with SOBEL", gd5Width, sobelWidth)
+	}
+	if gd5Jump >= sobelJump {
+		t.Errorf("got steps of up to %d between neighbours with GD5, %d with SOBEL", gd5Jump, sobelJump)
+	}
+}
+
+func TestDeterministicRejectsGD5(t *testing.T) {
+	_, err := DetectStages(context.Background(), square(16), Options{MinRatio: 0.1, MaxRatio: 0.3, Operator: GD5, Deterministic: true})
+	if err != ErrNonIntegerOperator {
+		t.Errorf("got %v, want %v", err, ErrNonIntegerOperator)
+	}
+	for _, op := range []Operator{SOBEL, SCHARR, PREWITT, CENTRAL_DIFFERENCE} {
+		if !op.Integer() {
+			t.Errorf("%s: expected integer coefficients", op.Name)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package canny
+
+import (
+	"context"
+	"math"
+)
+
+// OrientationHistogram returns a histogram of edge orientations with one bin
+// per degree in [0, 180), weighted by the magnitude of the edge pixels. The
+// orientation of an edge is perpendicular to its gradient, so an edge along a
+// vertical line falls into bin 90.
+func OrientationHistogram(edges [][]GrayPixel, directions [][]float64) []float64 {
+	histogram := make([]float64, 180)
+
+	for y := 0; y < len(edges); y++ {
+		for x := 0; x < len(edges[y]); x++ {
+			magnitude := edges[y][x].Y
+			if magnitude == 0 {
+				continue
+			}
+			orientation := math.Mod(directions[y][x]+90, 180)
+			if orientation < 0 {
+				orientation += 180
+			}
+			bin := int(math.Round(orientation)) % 180
+			histogram[bin] += float64(magnitude)
+		}
+	}
+
+	return histogram
+}
+
+// DominantOrientation returns the peak of the orientation histogram in
+// degrees, or zero if there are no edges.
+func DominantOrientation(edges [][]GrayPixel, directions [][]float64) float64 {
+	histogram := OrientationHistogram(edges, directions)
+
+	peak := 0
+	for bin := range histogram {
+		if histogram[bin] > histogram[peak] {
+			peak = bin
+		}
+	}
+
+	return float64(peak)
+}
+
+// EstimateSkew returns the angle in degrees, in [-45, 45), by which the edges
+// deviate from the nearest image axis. It is the circular mean of the
+// orientation histogram with a period of 90 degrees, so horizontal and
+// vertical edges support the same skew and the staircase of aliased lines
+// averages out. Rotating the image by the result with RotateBilinear aligns
+// the dominant edges with the axes.
+func EstimateSkew(edges [][]GrayPixel, directions [][]float64) float64 {
+	histogram := OrientationHistogram(edges, directions)
+
+	var sumSin, sumCos float64
+	for bin, weight := range histogram {
+		angle := float64(4*bin) * (math.Pi / 180)
+		sumSin += weight * math.Sin(angle)
+		sumCos += weight * math.Cos(angle)
+	}
+	if (sumSin == 0) && (sumCos == 0) {
+		return float64(0)
+	}
+
+	skew := math.Atan2(sumSin, sumCos) * (180 / math.Pi) / 4
+	if skew >= 45 {
+		skew -= 90
+	}
+	return skew
+}
+
+// DESKEW_PASSES bounds how often Deskew re-estimates the skew of the rotated
+// image. A single estimate falls short on aliased lines, whose staircase
+// pulls the orientations towards the axes.
+const DESKEW_PASSES = 3
+
+// Deskew rotates pixels so that their dominant edges align with the image
+// axes and returns the rotated pixels along with the total rotation in
+// degrees. Every pass detects the edges of the current result with opts and
+// refines the rotation of the original pixels by EstimateSkew, until the
+// correction drops below half a degree.
+func Deskew(ctx context.Context, pixels [][]GrayPixel, opts Options) ([][]GrayPixel, float64, error) {
+	result := pixels
+	var total float64
+	for pass := 0; pass < DESKEW_PASSES; pass++ {
+		stages, err := DetectStages(ctx, result, opts)
+		if err != nil {
+			return nil, 0, err
+		}
+		skew := EstimateSkew(stages.Edges, stages.Directions)
+		if math.Abs(skew) < 0.5 {
+			break
+		}
+		total += skew
+		result = RotateBilinear(pixels, total)
+	}
+
+	return result, total, nil
+}
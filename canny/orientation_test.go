@@ -0,0 +1,119 @@
+package canny
+
+import (
+	"context"
+	"image"
+	"math"
+	"testing"
+)
+
+// stripes returns a 40x40 image of 4 pixel wide stripes, vertical stripes if
+// vertical is set and horizontal ones otherwise.
+func stripes(vertical bool) [][]GrayPixel {
+	return newPixels(40, 40, func(x, y int) uint8 {
+		i := y
+		if vertical {
+			i = x
+		}
+		if (i/4)%2 == 0 {
+			return 0
+		}
+		return 255
+	})
+}
+
+// angleDistance returns the distance of orientations a and b in degrees,
+// modulo 180.
+func angleDistance(a, b float64) float64 {
+	d := math.Mod(math.Abs(a-b), 180)
+	return math.Min(d, 180-d)
+}
+
+func TestDominantOrientation(t *testing.T) {
+	cases := []struct {
+		name     string
+		vertical bool
+		want     float64
+	}{
+		{"vertical lines", true, 90},
+		{"horizontal lines", false, 0},
+	}
+
+	for _, c := range cases {
+		stages, err := DetectStages(context.Background(), stripes(c.vertical), Options{Blur: true, MinRatio: 0.2, MaxRatio: 0.6})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := DominantOrientation(stages.Edges, stages.Directions)
+		if angleDistance(got, c.want) > 2 {
+			t.Errorf("%s: got %v degrees, want about %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestDirectionSeam(t *testing.T) {
+	directions := [][]float64{make([]float64, 3), make([]float64, 3), make([]float64, 3)}
+	for _, angle := range []float64{89.9, 90, -90, -89.9, 269.9, -269.9} {
+		for _, rule := range []BinRule{ROUND_HALF_UP, ROUND_HALF_DOWN} {
+			if index := directionBinIndex(angle, rule); index != 0 {
+				t.Errorf("angle %v: bin %d, want the vertical bin 0", angle, index)
+			}
+			directions[1][1] = angle
+			p, q := getPointsInGradientDirection(directions, 1, 1, rule)
+			if (p != image.Point{1, 0}) || (q != image.Point{1, 2}) {
+				t.Errorf("angle %v: neighbours %v and %v, want (1,0) and (1,2)", angle, p, q)
+			}
+		}
+	}
+}
+
+func TestVerticalGradientDirection(t *testing.T) {
+	for _, gy := range []float64{-3, 5} {
+		if angle := gradientDirection(0, gy); angle != -90 {
+			t.Errorf("gradientDirection(0, %v) = %v, want -90", gy, angle)
+		}
+		if angle := fixedDirection(0, int64(gy)); angle != -90 {
+			t.Errorf("fixedDirection(0, %v) = %v, want -90", gy, angle)
+		}
+	}
+	// Just off the vertical, on either side of the seam.
+	for _, gx := range []float64{-0.001, 0.001} {
+		if angle := gradientDirection(gx, 1); angleDistance(angle, 90) > 0.1 {
+			t.Errorf("gradientDirection(%v, 1) = %v, want about ±90", gx, angle)
+		}
+		if angle := fixedDirection(int64(gx*1000), 1000); angle != -90 {
+			t.Errorf("fixedDirection(%v, 1000) = %v, want -90", int64(gx*1000), angle)
+		}
+	}
+	if angle := gradientDirection(0, 0); angle != 0 {
+		t.Errorf("gradientDirection(0, 0) = %v, want 0", angle)
+	}
+}
+
+func TestDeskewAlignsSkewedLine(t *testing.T) {
+	// A dark horizontal band across a light page, skewed by 10 degrees.
+	band := newPixels(64, 64, func(x, y int) uint8 {
+		if (y >= 28) && (y < 36) {
+			return 40
+		}
+		return 220
+	})
+	skewed := RotateBilinear(band, 10)
+	opts := Options{Blur: true, MinRatio: 0.2, MaxRatio: 0.6}
+
+	deskewed, rotation, err := Deskew(context.Background(), skewed, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(rotation+10) > 1.5 {
+		t.Errorf("got a rotation of %.2f degrees, want about -10", rotation)
+	}
+
+	stages, err := DetectStages(context.Background(), deskewed, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if skew := EstimateSkew(stages.Edges, stages.Directions); math.Abs(skew) > 1 {
+		t.Errorf("got a remaining skew of %.2f degrees, want the edges aligned with the axes", skew)
+	}
+}
@@ -0,0 +1,148 @@
+package canny
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+)
+
+// BlendEdges composites the edges in the given color over src at the given
+// opacity in [0, 1]. The background is src darkened by the fraction dim in
+// [0, 1] first, so every channel of an edge pixel is the linear interpolation
+// (1-alpha)*(1-dim)*src + alpha*c. The edges must have the dimensions of src.
+func BlendEdges(edges [][]GrayPixel, src image.Image, c color.RGBA, alpha, dim float64) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, len(edges[0]), len(edges)))
+	min := src.Bounds().Min
+	scale := 1 - dim
+
+	blend := func(background, edge uint8) uint8 {
+		return uint8(math.Round((1-alpha)*float64(background) + alpha*float64(edge)))
+	}
+
+	for y := 0; y < len(edges); y++ {
+		for x := 0; x < len(edges[y]); x++ {
+			s := color.RGBAModel.Convert(src.At(min.X+x, min.Y+y)).(color.RGBA)
+			background := color.RGBA{
+				uint8(math.Round(float64(s.R) * scale)),
+				uint8(math.Round(float64(s.G) * scale)),
+				uint8(math.Round(float64(s.B) * scale)),
+				255,
+			}
+			if edges[y][x].Y != 0 {
+				background = color.RGBA{
+					blend(background.R, c.R),
+					blend(background.G, c.G),
+					blend(background.B, c.B),
+					255,
+				}
+			}
+			img.SetRGBA(x, y, background)
+		}
+	}
+
+	return img
+}
+
+// DualChannelEdges packs the gradient magnitudes into the red channel and
+// the edges into the green channel of one image, so the raw response and the
+// thresholded result can be inspected together. Edge pixels have a green
+// value of 255, all others 0. The magnitudes must have the dimensions of the
+// edges.
+func DualChannelEdges(magnitudes, edges [][]GrayPixel) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, len(edges[0]), len(edges)))
+
+	for y := 0; y < len(edges); y++ {
+		for x := 0; x < len(edges[y]); x++ {
+			c := color.RGBA{magnitudes[y][x].Y, uint8(0), uint8(0), uint8(255)}
+			if edges[y][x].Y != 0 {
+				c.G = uint8(255)
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+
+	return img
+}
+
+// OVERLAY_PALETTE holds the colors used for successive parameter sets by
+// OverlayParameterSets.
+var OVERLAY_PALETTE = []color.RGBA{
+	{255, 0, 0, 255},
+	{0, 255, 0, 255},
+	{0, 0, 255, 255},
+	{255, 255, 0, 255},
+	{255, 0, 255, 255},
+	{0, 255, 255, 255},
+}
+
+// OverlayEdges paints every edge pixel of edges in the given color onto img.
+func OverlayEdges(img *image.RGBA, edges [][]GrayPixel, c color.RGBA) {
+	for y := 0; y < len(edges); y++ {
+		for x := 0; x < len(edges[y]); x++ {
+			if edges[y][x].Y != 0 {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+}
+
+// TransparentEdges renders every edge pixel of edges in the given opaque color
+// on a fully transparent background, ready to be composited.
+func TransparentEdges(edges [][]GrayPixel, c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, len(edges[0]), len(edges)))
+	c.A = 255
+	for y := 0; y < len(edges); y++ {
+		for x := 0; x < len(edges[y]); x++ {
+			if edges[y][x].Y != 0 {
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+
+	return img
+}
+
+// SourceColorEdges renders every edge pixel of edges in the color of src at
+// the same position on a black background, darkened by the given fraction in
+// [0, 1]. The edges must have the dimensions of src.
+func SourceColorEdges(edges [][]GrayPixel, src image.Image, darken float64) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, len(edges[0]), len(edges)))
+	min := src.Bounds().Min
+	scale := 1 - darken
+
+	for y := 0; y < len(edges); y++ {
+		for x := 0; x < len(edges[y]); x++ {
+			if edges[y][x].Y == 0 {
+				img.SetRGBA(x, y, color.RGBA{0, 0, 0, 255})
+				continue
+			}
+			c := color.RGBAModel.Convert(src.At(min.X+x, min.Y+y)).(color.RGBA)
+			img.SetRGBA(x, y, color.RGBA{
+				uint8(float64(c.R) * scale),
+				uint8(float64(c.G) * scale),
+				uint8(float64(c.B) * scale),
+				255,
+			})
+		}
+	}
+
+	return img
+}
+
+// OverlayParameterSets runs the detection once per parameter set and overlays
+// the results on the grayscale image, each in the color of OVERLAY_PALETTE at
+// the same index. Later sets are drawn on top of earlier ones.
+func OverlayParameterSets(ctx context.Context, pixels [][]GrayPixel, sets []Options) (*image.RGBA, error) {
+	img := GrayToRGBA(pixels)
+
+	for i, opts := range sets {
+		edges, err := CannyEdgeDetectContext(ctx, pixels, opts)
+		if err != nil {
+			return nil, err
+		}
+		OverlayEdges(img, edges, OVERLAY_PALETTE[i%len(OVERLAY_PALETTE)])
+	}
+
+	return img, nil
+}
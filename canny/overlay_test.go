@@ -0,0 +1,153 @@
+package canny
+
+import (
+	"context"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	"math"
+	"testing"
+)
+
+func TestOverlayParameterSets(t *testing.T) {
+	pixels := square(16)
+	sets := []Options{{MinRatio: 0.1, MaxRatio: 0.3}, {MinRatio: 0.2, MaxRatio: 0.6}}
+	img, err := OverlayParameterSets(context.Background(), pixels, sets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var edges [][][]GrayPixel
+	for _, opts := range sets {
+		e, err := CannyEdgeDetect(pixels, opts.Blur, opts.MinRatio, opts.MaxRatio)
+		if err != nil {
+			t.Fatal(err)
+		}
+		edges = append(edges, e)
+	}
+	for y := range pixels {
+		for x := range pixels[y] {
+			v := pixels[y][x].Y
+			var want color.RGBA
+			switch {
+			case edges[1][y][x].Y != 0:
+				want = OVERLAY_PALETTE[1]
+			case edges[0][y][x].Y != 0:
+				want = OVERLAY_PALETTE[0]
+			default:
+				want = color.RGBA{v, v, v, 255}
+			}
+			if got := img.RGBAAt(x, y); got != want {
+				t.Errorf("(%d, %d): got %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestTransparentEdges(t *testing.T) {
+	edges := newPixels(5, 4, func(x, y int) uint8 {
+		if x == 2 {
+			return 255
+		}
+		return 0
+	})
+	// The alpha of the given color is ignored, edges are always opaque.
+	img := TransparentEdges(edges, color.NRGBA{10, 200, 30, 7})
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 5; x++ {
+			want := color.NRGBA{}
+			if x == 2 {
+				want = color.NRGBA{10, 200, 30, 255}
+			}
+			if got := img.NRGBAAt(x, y); got != want {
+				t.Errorf("(%d, %d): got %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestSourceColorEdges(t *testing.T) {
+	// A red left half and a blue right half, with its origin away from (0, 0).
+	src := image.NewRGBA(image.Rect(5, 7, 13, 11))
+	for y := 7; y < 11; y++ {
+		for x := 5; x < 13; x++ {
+			if x < 9 {
+				src.SetRGBA(x, y, color.RGBA{200, 0, 0, 255})
+			} else {
+				src.SetRGBA(x, y, color.RGBA{0, 0, 100, 255})
+			}
+		}
+	}
+	edges := newPixels(8, 4, func(x, y int) uint8 {
+		if (x == 3) || (x == 4) {
+			return 255
+		}
+		return 0
+	})
+
+	img := SourceColorEdges(edges, src, 0.5)
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 8; x++ {
+			want := color.RGBA{0, 0, 0, 255}
+			switch x {
+			case 3:
+				want = color.RGBA{100, 0, 0, 255}
+			case 4:
+				want = color.RGBA{0, 0, 50, 255}
+			}
+			if got := img.RGBAAt(x, y); got != want {
+				t.Errorf("(%d, %d): got %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestBlendEdgesInterpolates(t *testing.T) {
+	src := image.NewRGBA(image.Rect(10, 20, 13, 21))
+	src.SetRGBA(10, 20, color.RGBA{200, 100, 50, 255})
+	src.SetRGBA(11, 20, color.RGBA{200, 100, 50, 255})
+	src.SetRGBA(12, 20, color.RGBA{0, 0, 0, 255})
+	edges := [][]GrayPixel{{{255, 255}, {0, 255}, {255, 255}}}
+	c := color.RGBA{0, 255, 0, 255}
+
+	img := BlendEdges(edges, src, c, 0.6, 0.5)
+	// (1-alpha)*(1-dim)*src + alpha*c for every channel of an edge pixel.
+	want := []color.RGBA{
+		{uint8(math.Round(0.4 * 0.5 * 200)), uint8(math.Round(0.4*0.5*100 + 0.6*255)), uint8(math.Round(0.4 * 0.5 * 50)), 255},
+		{100, 50, 25, 255},
+		{0, uint8(math.Round(0.6 * 255)), 0, 255},
+	}
+	for x := range want {
+		if got := img.RGBAAt(x, 0); got != want[x] {
+			t.Errorf("pixel %d: got %v, want %v", x, got, want[x])
+		}
+	}
+}
+
+func TestDualChannelEdges(t *testing.T) {
+	stages, err := DetectStages(context.Background(), square(16), Options{MinRatio: 0.1, MaxRatio: 0.3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := DualChannelEdges(stages.Magnitudes, stages.Edges)
+	edges := 0
+	for y := range stages.Edges {
+		for x := range stages.Edges[y] {
+			c := img.RGBAAt(x, y)
+			if c.R != stages.Magnitudes[y][x].Y {
+				t.Errorf("(%d, %d): got red %d, want magnitude %d", x, y, c.R, stages.Magnitudes[y][x].Y)
+			}
+			want := uint8(0)
+			if stages.Edges[y][x].Y != 0 {
+				want = 255
+				edges++
+			}
+			if (c.G != want) || (c.B != 0) || (c.A != 255) {
+				t.Errorf("(%d, %d): got %v, want green %d", x, y, c, want)
+			}
+		}
+	}
+	if edges == 0 {
+		t.Error("expected edges")
+	}
+}
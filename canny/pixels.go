@@ -0,0 +1,192 @@
+package canny
+
+import (
+	"image"
+	"image/color"
+)
+
+// GrayPixel is a gray value Y with the alpha A of the source pixel. Pixel
+// arrays are [][]GrayPixel indexed by row and column.
+type GrayPixel struct {
+	Y uint8
+	A uint8
+}
+
+// ImageToPixelArray converts img to a pixel array of its gray values. The
+// array starts at img.Bounds().Min, which needn't be the origin. Images with
+// 16 bits per channel are rounded to the nearest 8-bit value.
+func ImageToPixelArray(img image.Image) [][]GrayPixel {
+	var pixelArr [][]GrayPixel
+
+	if paletted, ok := img.(*image.Paletted); ok {
+		return palettedToPixelArray(paletted)
+	}
+	if gray16, ok := img.(*image.Gray16); ok {
+		return gray16ToPixelArray(gray16)
+	}
+	if is16BitColor(img) {
+		return color64ToPixelArray(img)
+	}
+	bounds := img.Bounds()
+
+	for y := 0; y < bounds.Dy(); y++ {
+		var row []GrayPixel
+		for x := 0; x < bounds.Dx(); x++ {
+			pixel := img.At(bounds.Min.X+x, bounds.Min.Y+y)
+			grayPixel := rgbaToGrayPixel(pixel)
+			row = append(row, grayPixel)
+		}
+		pixelArr = append(pixelArr, row)
+	}
+
+	return pixelArr
+}
+
+// palettedToPixelArray converts every palette entry once and maps the color
+// indices of img through the result. Fully transparent entries become
+// transparent black regardless of their color.
+func palettedToPixelArray(img *image.Paletted) [][]GrayPixel {
+	var pixelArr [][]GrayPixel
+
+	palette := make([]GrayPixel, len(img.Palette))
+	for i, c := range img.Palette {
+		palette[i] = rgbaToGrayPixel(c)
+		if palette[i].A == 0 {
+			palette[i] = GrayPixel{0, 0}
+		}
+	}
+
+	bounds := img.Bounds()
+
+	for y := 0; y < bounds.Dy(); y++ {
+		row := make([]GrayPixel, 0, bounds.Dx())
+		for x := 0; x < bounds.Dx(); x++ {
+			index := int(img.ColorIndexAt(bounds.Min.X+x, bounds.Min.Y+y))
+			if index < len(palette) {
+				row = append(row, palette[index])
+			} else {
+				row = append(row, GrayPixel{0, 0})
+			}
+		}
+		pixelArr = append(pixelArr, row)
+	}
+
+	return pixelArr
+}
+
+// gray16ToPixelArray converts a 16-bit grayscale image by rounding every
+// sample to the nearest 8-bit value, instead of dropping the low byte.
+func gray16ToPixelArray(img *image.Gray16) [][]GrayPixel {
+	var pixelArr [][]GrayPixel
+
+	bounds := img.Bounds()
+
+	for y := 0; y < bounds.Dy(); y++ {
+		row := make([]GrayPixel, 0, bounds.Dx())
+		for x := 0; x < bounds.Dx(); x++ {
+			row = append(row, GrayPixel{scale16To8(img.Gray16At(bounds.Min.X+x, bounds.Min.Y+y).Y), uint8(255)})
+		}
+		pixelArr = append(pixelArr, row)
+	}
+
+	return pixelArr
+}
+
+// Gray16Samples returns the samples of a 16-bit grayscale image from its
+// Bounds().Min on, or nil for any other image.
+func Gray16Samples(img image.Image) [][]uint16 {
+	gray16, ok := img.(*image.Gray16)
+	if !ok {
+		return nil
+	}
+	var samples [][]uint16
+
+	bounds := gray16.Bounds()
+
+	for y := 0; y < bounds.Dy(); y++ {
+		row := make([]uint16, 0, bounds.Dx())
+		for x := 0; x < bounds.Dx(); x++ {
+			row = append(row, gray16.Gray16At(bounds.Min.X+x, bounds.Min.Y+y).Y)
+		}
+		samples = append(samples, row)
+	}
+
+	return samples
+}
+
+// isGray16 reports whether img is a 16-bit grayscale image.
+func isGray16(img image.Image) bool {
+	_, ok := img.(*image.Gray16)
+	return ok
+}
+
+// Is16Bit reports whether img stores 16 bits per channel, so that a window of
+// its range can be mapped to gray values with Luma16 and WindowLevelSamples16.
+func Is16Bit(img image.Image) bool {
+	return is16BitColor(img) || isGray16(img)
+}
+
+// is16BitColor reports whether img stores 16 bits per color channel.
+func is16BitColor(img image.Image) bool {
+	switch img.(type) {
+	case *image.NRGBA64, *image.RGBA64:
+		return true
+	}
+	return false
+}
+
+// color64ToPixelArray converts a 16-bit color image through its 16-bit luma,
+// rounded to the nearest 8-bit value like gray16ToPixelArray, instead of the
+// truncating 8-bit color.GrayModel.
+func color64ToPixelArray(img image.Image) [][]GrayPixel {
+	var pixelArr [][]GrayPixel
+
+	bounds := img.Bounds()
+
+	for y := 0; y < bounds.Dy(); y++ {
+		row := make([]GrayPixel, 0, bounds.Dx())
+		for x := 0; x < bounds.Dx(); x++ {
+			c := img.At(bounds.Min.X+x, bounds.Min.Y+y)
+			_, _, _, a := c.RGBA()
+			luma := color.Gray16Model.Convert(c).(color.Gray16).Y
+			row = append(row, GrayPixel{scale16To8(luma), uint8(a >> 8)})
+		}
+		pixelArr = append(pixelArr, row)
+	}
+
+	return pixelArr
+}
+
+func scale16To8(v uint16) uint8 {
+	return uint8((uint32(v)*255 + 32767) / 65535)
+}
+
+// PixelArrayToImage converts the gray values of pixels to an image. It
+// returns an error if pixels is ragged or its first row is empty.
+func PixelArrayToImage(pixels [][]GrayPixel) (*image.Gray, error) {
+	if err := checkRectangular(pixels); err != nil {
+		return nil, err
+	}
+
+	width := 0
+	if len(pixels) > 0 {
+		width = len(pixels[0])
+	}
+	bounds := image.Rect(0, 0, width, len(pixels))
+	img := image.NewGray(bounds)
+
+	for y := 0; y < len(pixels); y++ {
+		for x := 0; x < len(pixels[y]); x++ {
+			img.SetGray(x, y, color.Gray{pixels[y][x].Y})
+		}
+	}
+
+	return img, nil
+}
+
+func rgbaToGrayPixel(pixel color.Color) GrayPixel {
+	_, _, _, a := pixel.RGBA()
+	gray := color.GrayModel.Convert(pixel).(color.Gray).Y
+
+	return GrayPixel{gray, uint8(a >> 8)}
+}
@@ -0,0 +1,117 @@
+package canny
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestGray16Samples(t *testing.T) {
+	img := image.NewGray16(image.Rect(0, 0, 3, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			img.SetGray16(x, y, color.Gray16{uint16(1000*x + 7*y)})
+		}
+	}
+	samples := Gray16Samples(img)
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			if want := uint16(1000*x + 7*y); samples[y][x] != want {
+				t.Errorf("(%d, %d): got %d, want %d", x, y, samples[y][x], want)
+			}
+		}
+	}
+	if pixels := ImageToPixelArray(img); pixels[1][2].Y != scale16To8(2007) {
+		t.Errorf("got 8-bit value %d, want %d", pixels[1][2].Y, scale16To8(2007))
+	}
+	if Gray16Samples(image.NewGray(image.Rect(0, 0, 1, 1))) != nil {
+		t.Error("expected no samples of an 8-bit image")
+	}
+}
+
+func TestPalettedMatchesGenericConversion(t *testing.T) {
+	palette := color.Palette{
+		color.RGBA{255, 0, 0, 255},
+		color.RGBA{0, 128, 255, 255},
+		color.RGBA{90, 90, 90, 255},
+		color.NRGBA{200, 10, 10, 0},
+	}
+	paletted := image.NewPaletted(image.Rect(0, 0, 4, 3), palette)
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 4; x++ {
+			paletted.SetColorIndex(x, y, uint8((x+y)%len(palette)))
+		}
+	}
+	rgba := image.NewRGBA(paletted.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), paletted, image.Point{}, draw.Src)
+	want := ImageToPixelArray(rgba)
+	want[2][3] = GrayPixel{}
+
+	paletted.SetColorIndex(3, 2, 7)
+	got := ImageToPixelArray(paletted)
+	for y := range want {
+		for x := range want[y] {
+			if got[y][x] != want[y][x] {
+				t.Errorf("(%d, %d): got %v, want %v", x, y, got[y][x], want[y][x])
+			}
+		}
+	}
+	if got[0][3] != (GrayPixel{}) {
+		t.Errorf("transparent entry: got %v, want transparent black", got[0][3])
+	}
+	if got[2][3] != (GrayPixel{}) {
+		t.Errorf("index outside of palette: got %v, want transparent black", got[2][3])
+	}
+}
+
+func TestImageToPixelArrayOffsetBounds(t *testing.T) {
+	// Every image type is converted from a sub-image whose origin lies
+	// inside a larger image with different values outside of it.
+	bounds := image.Rect(0, 0, 10, 8)
+	value := func(x, y int) uint8 {
+		return uint8(20*x + y)
+	}
+	gray16 := image.NewGray16(bounds)
+	rgba := image.NewRGBA(bounds)
+	nrgba64 := image.NewNRGBA64(bounds)
+	paletted := image.NewPaletted(bounds, color.Palette{})
+	for i := 0; i < 256; i++ {
+		paletted.Palette = append(paletted.Palette, color.Gray{uint8(i)})
+	}
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 10; x++ {
+			v := value(x, y)
+			gray16.SetGray16(x, y, color.Gray16{uint16(v) * 0x101})
+			rgba.SetRGBA(x, y, color.RGBA{v, v, v, 255})
+			nrgba64.SetNRGBA64(x, y, color.NRGBA64{uint16(v) * 0x101, uint16(v) * 0x101, uint16(v) * 0x101, 0xffff})
+			paletted.SetColorIndex(x, y, v)
+		}
+	}
+
+	sub := image.Rect(3, 2, 9, 7)
+	for name, img := range map[string]image.Image{
+		"gray16":   gray16.SubImage(sub),
+		"rgba":     rgba.SubImage(sub),
+		"nrgba64":  nrgba64.SubImage(sub),
+		"paletted": paletted.SubImage(sub),
+	} {
+		pixels := ImageToPixelArray(img)
+		if (len(pixels) != sub.Dy()) || (len(pixels[0]) != sub.Dx()) {
+			t.Errorf("%s: got %dx%d pixels, want %dx%d", name, len(pixels[0]), len(pixels), sub.Dx(), sub.Dy())
+			continue
+		}
+		for y := range pixels {
+			for x := range pixels[y] {
+				if want := value(sub.Min.X+x, sub.Min.Y+y); pixels[y][x].Y != want {
+					t.Errorf("%s (%d, %d): got %d, want %d", name, x, y, pixels[y][x].Y, want)
+				}
+			}
+		}
+	}
+
+	samples := Gray16Samples(gray16.SubImage(sub))
+	if got, want := samples[0][0], uint16(value(3, 2))*0x101; got != want {
+		t.Errorf("got first 16-bit sample %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,137 @@
+package canny
+
+import (
+	"errors"
+	"math"
+)
+
+// DifferencePixels returns the absolute per-pixel difference between the gray
+// values of a and b. Both arrays must have the same dimensions.
+func DifferencePixels(a, b [][]GrayPixel) ([][]GrayPixel, error) {
+	if len(a) != len(b) {
+		return nil, errors.New("dimensions of images to diff must match")
+	}
+	var result [][]GrayPixel
+
+	for y := 0; y < len(a); y++ {
+		if len(a[y]) != len(b[y]) {
+			return nil, errors.New("dimensions of images to diff must match")
+		}
+		resultRow := make([]GrayPixel, 0, len(a[y]))
+		for x := 0; x < len(a[y]); x++ {
+			diff := abs(int(a[y][x].Y) - int(b[y][x].Y))
+			resultRow = append(resultRow, GrayPixel{uint8(diff), a[y][x].A})
+		}
+		result = append(result, resultRow)
+	}
+
+	return result, nil
+}
+
+// FlattenIllumination removes low-frequency brightness variations such as
+// shadows by subtracting a box blur of the given radius from every pixel. The
+// result is shifted to mid gray so that darker and brighter details survive.
+func FlattenIllumination(pixels [][]GrayPixel, radius int) [][]GrayPixel {
+	background := boxBlur(pixels, radius)
+	var result [][]GrayPixel
+
+	for y := 0; y < len(pixels); y++ {
+		resultRow := make([]GrayPixel, 0, len(pixels[y]))
+		for x := 0; x < len(pixels[y]); x++ {
+			value := int(pixels[y][x].Y) - int(background[y][x]) + 128
+			resultRow = append(resultRow, GrayPixel{clampUint8(value), pixels[y][x].A})
+		}
+		result = append(result, resultRow)
+	}
+
+	return result
+}
+
+// boxBlur returns the mean gray value of the (2*radius+1)² window around every
+// pixel, clipped at the image borders. It uses a summed-area table so the cost
+// doesn't depend on the radius.
+func boxBlur(pixels [][]GrayPixel, radius int) [][]float64 {
+	height := len(pixels)
+	width := len(pixels[0])
+
+	sums := make([][]int, height+1)
+	sums[0] = make([]int, width+1)
+	for y := 0; y < height; y++ {
+		sums[y+1] = make([]int, width+1)
+		rowSum := 0
+		for x := 0; x < width; x++ {
+			rowSum += int(pixels[y][x].Y)
+			sums[y+1][x+1] = sums[y][x+1] + rowSum
+		}
+	}
+
+	result := make([][]float64, height)
+	for y := 0; y < height; y++ {
+		result[y] = make([]float64, width)
+		minY := int(math.Max(float64(0), float64(y-radius)))
+		maxY := int(math.Min(float64(height), float64(y+radius+1)))
+		for x := 0; x < width; x++ {
+			minX := int(math.Max(float64(0), float64(x-radius)))
+			maxX := int(math.Min(float64(width), float64(x+radius+1)))
+			sum := sums[maxY][maxX] - sums[minY][maxX] - sums[maxY][minX] + sums[minY][minX]
+			result[y][x] = float64(sum) / float64((maxY-minY)*(maxX-minX))
+		}
+	}
+
+	return result
+}
+
+func clampUint8(x int) uint8 {
+	if x < 0 {
+		return uint8(0)
+	} else if x > 255 {
+		return uint8(255)
+	}
+	return uint8(x)
+}
+
+// NormalizePixels standardizes the gray values of pixels to the given mean and
+// standard deviation, clamping the result to [0, 255]. Images without any
+// contrast are shifted to the mean.
+func NormalizePixels(pixels [][]GrayPixel, mean, stdDev float64) [][]GrayPixel {
+	var sum, sumSquares, count float64
+	for y := 0; y < len(pixels); y++ {
+		for x := 0; x < len(pixels[y]); x++ {
+			v := float64(pixels[y][x].Y)
+			sum += v
+			sumSquares += v * v
+			count++
+		}
+	}
+	imageMean := sum / count
+	imageStdDev := math.Sqrt(math.Max(0, sumSquares/count-imageMean*imageMean))
+
+	var result [][]GrayPixel
+	for y := 0; y < len(pixels); y++ {
+		resultRow := make([]GrayPixel, 0, len(pixels[y]))
+		for x := 0; x < len(pixels[y]); x++ {
+			v := mean
+			if imageStdDev > 0 {
+				v = (float64(pixels[y][x].Y)-imageMean)/imageStdDev*stdDev + mean
+			}
+			resultRow = append(resultRow, GrayPixel{clampUint8(int(math.Round(v))), pixels[y][x].A})
+		}
+		result = append(result, resultRow)
+	}
+
+	return result
+}
+
+// ApplyLUT remaps the gray value of every pixel through lut.
+func ApplyLUT(pixels [][]GrayPixel, lut [256]uint8) [][]GrayPixel {
+	var result [][]GrayPixel
+	for y := 0; y < len(pixels); y++ {
+		resultRow := make([]GrayPixel, 0, len(pixels[y]))
+		for x := 0; x < len(pixels[y]); x++ {
+			resultRow = append(resultRow, GrayPixel{lut[pixels[y][x].Y], pixels[y][x].A})
+		}
+		result = append(result, resultRow)
+	}
+
+	return result
+}
@@ -0,0 +1,116 @@
+package canny
+
+import (
+	"testing"
+)
+
+func TestDifferencePixels(t *testing.T) {
+	a := newPixels(3, 2, func(x, y int) uint8 { return uint8(10 * x) })
+	b := newPixels(3, 2, func(x, y int) uint8 { return uint8(5 * (x + y)) })
+	diff, err := DifferencePixels(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := newPixels(3, 2, func(x, y int) uint8 { return uint8(abs(10*x - 5*(x+y))) })
+	if !equalPixels(diff, want) {
+		t.Errorf("got %v, want %v", diff, want)
+	}
+
+	if _, err := DifferencePixels(a, newPixels(3, 1, func(x, y int) uint8 { return 0 })); err == nil {
+		t.Error("expected an error for mismatching heights")
+	}
+	if _, err := DifferencePixels(a, newPixels(2, 2, func(x, y int) uint8 { return 0 })); err == nil {
+		t.Error("expected an error for mismatching widths")
+	}
+}
+
+func TestFlattenIlluminationRemovesShadowEdge(t *testing.T) {
+	// Paper with a soft shadow over its left part and a dark mark in the
+	// lit part.
+	pixels := newPixels(64, 32, func(x, y int) uint8 {
+		if (x >= 44) && (x < 48) && (y >= 14) && (y < 18) {
+			return 80
+		}
+		if x < 16 {
+			return 80
+		} else if x < 24 {
+			return uint8(80 + 14*(x-16))
+		}
+		return 192
+	})
+
+	countEdges := func(pixels [][]GrayPixel, minX, maxX int) int {
+		edges, err := CannyEdgeDetect(pixels, true, 0.1, 0.3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		count := 0
+		for y := range edges {
+			for x := minX; x < maxX; x++ {
+				if edges[y][x].Y != 0 {
+					count++
+				}
+			}
+		}
+		return count
+	}
+
+	if countEdges(pixels, 4, 36) == 0 {
+		t.Fatal("expected edges along the shadow before flattening")
+	}
+	flat := FlattenIllumination(pixels, 8)
+	if n := countEdges(flat, 4, 36); n != 0 {
+		t.Errorf("got %d edge pixels along the shadow after flattening, want none", n)
+	}
+	if countEdges(flat, 40, 52) == 0 {
+		t.Error("expected the edges of the mark to survive flattening")
+	}
+}
+
+func TestNormalizePixels(t *testing.T) {
+	// Half of the pixels are 90 and half 110: mean 100, deviation 10.
+	pixels := newPixels(4, 2, func(x, y int) uint8 {
+		if x%2 == 0 {
+			return 90
+		}
+		return 110
+	})
+	got := NormalizePixels(pixels, 128, 48)
+	for y := range got {
+		for x := range got[y] {
+			want := uint8(80)
+			if x%2 != 0 {
+				want = 176
+			}
+			if got[y][x].Y != want {
+				t.Errorf("(%d, %d): got %d, want %d", x, y, got[y][x].Y, want)
+			}
+		}
+	}
+
+	flat := NormalizePixels(newPixels(3, 3, func(x, y int) uint8 { return 7 }), 128, 48)
+	if flat[1][1].Y != 128 {
+		t.Errorf("flat image normalized to %d, want the mean 128", flat[1][1].Y)
+	}
+
+	clamped := NormalizePixels(pixels, 250, 100)
+	if (clamped[0][0].Y != 150) || (clamped[0][1].Y != 255) {
+		t.Errorf("got %d and %d, want 150 and the clamped 255", clamped[0][0].Y, clamped[0][1].Y)
+	}
+}
+
+func TestApplyLUT(t *testing.T) {
+	pixels := newPixels(4, 3, func(x, y int) uint8 { return uint8(60*x + y) })
+	var identity, invert [256]uint8
+	for i := range identity {
+		identity[i] = uint8(i)
+		invert[i] = uint8(255 - i)
+	}
+	if got := ApplyLUT(pixels, identity); !equalPixels(got, pixels) {
+		t.Errorf("identity: got %v, want %v", got, pixels)
+	}
+	want := newPixels(4, 3, func(x, y int) uint8 { return uint8(255 - (60*x + y)) })
+	if got := ApplyLUT(pixels, invert); !equalPixels(got, want) {
+		t.Errorf("invert: got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,88 @@
+package canny
+
+import (
+	"encoding/json"
+	"image"
+)
+
+// PRIMITIVES_VERSION is the version of the schema written by
+// PrimitivesJSON. It is increased whenever a field changes meaning or is
+// removed, new fields may be added without increasing it.
+const PRIMITIVES_VERSION = 1
+
+// Primitives collects the geometric primitives found in an edge map into one
+// document, in pixel coordinates with x to the right and y down:
+//
+//	{
+//	  "version": 1,
+//	  "width": 640, "height": 480,
+//	  "contours": [{"points": [[x, y], ...], "closed": false}, ...],
+//	  "components": [{"x": 10, "y": 20, "width": 30, "height": 40}, ...]
+//	}
+//
+// Contours are the chains of ExtractContours, a contour is closed if its
+// last point neighbours its first. Components are the bounding boxes of the
+// 8-connected edge components of ComponentBounds. Empty lists are encoded as
+// [] rather than null.
+type Primitives struct {
+	Version    int                  `json:"version"`
+	Width      int                  `json:"width"`
+	Height     int                  `json:"height"`
+	Contours   []PrimitiveContour   `json:"contours"`
+	Components []PrimitiveComponent `json:"components"`
+}
+
+// PrimitiveContour is a traced chain of edge pixels.
+type PrimitiveContour struct {
+	Points [][2]int `json:"points"`
+	Closed bool     `json:"closed"`
+}
+
+// PrimitiveComponent is the bounding box of a connected edge component.
+type PrimitiveComponent struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// ExtractPrimitives runs the contour tracing and component labelling on an
+// edge map and collects their results.
+func ExtractPrimitives(edges [][]GrayPixel) Primitives {
+	primitives := Primitives{
+		Version:    PRIMITIVES_VERSION,
+		Width:      len(edges[0]),
+		Height:     len(edges),
+		Contours:   []PrimitiveContour{},
+		Components: []PrimitiveComponent{},
+	}
+
+	for _, contour := range ExtractContours(edges) {
+		points := make([][2]int, 0, len(contour))
+		for _, p := range contour {
+			points = append(points, [2]int{p.X, p.Y})
+		}
+		primitives.Contours = append(primitives.Contours, PrimitiveContour{points, isClosed(contour)})
+	}
+
+	for _, r := range ComponentBounds(EdgeMask(edges)) {
+		primitives.Components = append(primitives.Components, PrimitiveComponent{r.Min.X, r.Min.Y, r.Dx(), r.Dy()})
+	}
+
+	return primitives
+}
+
+// PrimitivesJSON encodes the primitives of an edge map as JSON.
+func PrimitivesJSON(edges [][]GrayPixel) ([]byte, error) {
+	return json.Marshal(ExtractPrimitives(edges))
+}
+
+// isClosed reports whether the last point of a contour of at least three
+// points neighbours its first one.
+func isClosed(contour []image.Point) bool {
+	if len(contour) < 3 {
+		return false
+	}
+	d := contour[len(contour)-1].Sub(contour[0])
+	return (d.X >= -1) && (d.X <= 1) && (d.Y >= -1) && (d.Y <= 1)
+}
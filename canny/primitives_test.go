@@ -0,0 +1,83 @@
+package canny
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// outlines draws the 1 pixel wide outlines of a 5x4 box at (1, 1) and a 3x3
+// box at (10, 5).
+func outlines() [][]GrayPixel {
+	inBox := func(x, y, minX, minY, maxX, maxY int) bool {
+		if (x < minX) || (x > maxX) || (y < minY) || (y > maxY) {
+			return false
+		}
+		return (x == minX) || (x == maxX) || (y == minY) || (y == maxY)
+	}
+	return newPixels(16, 10, func(x, y int) uint8 {
+		if inBox(x, y, 1, 1, 5, 4) || inBox(x, y, 10, 5, 12, 7) {
+			return 255
+		}
+		return 0
+	})
+}
+
+func TestPrimitivesSchema(t *testing.T) {
+	data, err := PrimitivesJSON(outlines())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(data, &document); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"version", "width", "height", "contours", "components"} {
+		if _, ok := document[key]; !ok {
+			t.Errorf("missing %q in %s", key, data)
+		}
+	}
+
+	var primitives Primitives
+	if err := json.Unmarshal(data, &primitives); err != nil {
+		t.Fatal(err)
+	}
+	if (primitives.Version != PRIMITIVES_VERSION) || (primitives.Width != 16) || (primitives.Height != 10) {
+		t.Errorf("got version %d and %dx%d", primitives.Version, primitives.Width, primitives.Height)
+	}
+	want := []PrimitiveComponent{{1, 1, 5, 4}, {10, 5, 3, 3}}
+	if len(primitives.Components) != len(want) {
+		t.Fatalf("got components %v, want %v", primitives.Components, want)
+	}
+	for _, component := range want {
+		found := false
+		for _, got := range primitives.Components {
+			found = found || (got == component)
+		}
+		if !found {
+			t.Errorf("missing component %v in %v", component, primitives.Components)
+		}
+	}
+
+	points := 0
+	for _, contour := range primitives.Contours {
+		points += len(contour.Points)
+		if !contour.Closed {
+			t.Errorf("got an open contour %v", contour.Points)
+		}
+	}
+	// The outlines have 14 and 8 pixels.
+	if points != 22 {
+		t.Errorf("got %d contour points, want 22", points)
+	}
+}
+
+func TestPrimitivesOfEmptyEdges(t *testing.T) {
+	data, err := PrimitivesJSON(newPixels(4, 3, func(x, y int) uint8 { return 0 }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"contours":[]`) || !strings.Contains(string(data), `"components":[]`) {
+		t.Errorf("got %s, want empty lists", data)
+	}
+}
@@ -0,0 +1,83 @@
+package canny
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"math"
+)
+
+var QUIVER_COLOR = color.RGBA{255, 0, 0, 255}
+
+// DrawQuiver draws the gradient field of pixels as vectors sampled every step
+// pixels on top of the grayscale image. A vector of a pixel with the maximum
+// magnitude is scale*step pixels long. It returns the image and the number of
+// vectors drawn.
+func DrawQuiver(pixels [][]GrayPixel, step int, scale float64) (*image.RGBA, int, error) {
+	if step <= 0 {
+		return nil, 0, errors.New("quiver step must be positive")
+	}
+	magnitudes, directions := Gradients(pixels, SIGNED_DIRECTION)
+	img := GrayToRGBA(pixels)
+	count := 0
+
+	for y := step / 2; y < len(pixels); y += step {
+		for x := step / 2; x < len(pixels[y]); x += step {
+			length := scale * float64(step) * float64(magnitudes[y][x].Y) / 255
+			angle := directions[y][x] * (math.Pi / 180)
+			dx := int(math.Round(length * math.Cos(angle)))
+			dy := int(math.Round(length * math.Sin(angle)))
+			drawLine(img, x, y, x+dx, y+dy, QUIVER_COLOR)
+			count++
+		}
+	}
+
+	return img, count, nil
+}
+
+// GrayToRGBA converts the gray values of pixels to an opaque RGBA image.
+func GrayToRGBA(pixels [][]GrayPixel) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, len(pixels[0]), len(pixels)))
+
+	for y := 0; y < len(pixels); y++ {
+		for x := 0; x < len(pixels[y]); x++ {
+			v := pixels[y][x].Y
+			img.SetRGBA(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+
+	return img
+}
+
+// drawLine draws a line between two points using Bresenham's algorithm.
+// Points outside of the image are clipped.
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA) {
+	dx := abs(x1 - x0)
+	dy := -abs(y1 - y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+
+	for {
+		if (image.Point{x0, y0}).In(img.Rect) {
+			img.SetRGBA(x0, y0, c)
+		}
+		if (x0 == x1) && (y0 == y1) {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package canny
+
+import "testing"
+
+func TestDrawQuiverSamplesGrid(t *testing.T) {
+	pixels := newPixels(10, 7, func(x, y int) uint8 { return uint8(20 * x) })
+	for _, c := range []struct {
+		step, want int
+	}{
+		{1, 10 * 7},
+		{2, 5 * 3},
+		{3, 3 * 2},
+		{20, 0},
+	} {
+		img, count, err := DrawQuiver(pixels, c.step, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != c.want {
+			t.Errorf("step %d: got %d vectors, want %d", c.step, count, c.want)
+		}
+		if (img.Bounds().Dx() != 10) || (img.Bounds().Dy() != 7) {
+			t.Errorf("step %d: got bounds %v", c.step, img.Bounds())
+		}
+	}
+}
+
+func TestDrawQuiverRejectsNonPositiveStep(t *testing.T) {
+	pixels := newPixels(4, 4, func(x, y int) uint8 { return 0 })
+	for _, step := range []int{0, -3} {
+		if _, _, err := DrawQuiver(pixels, step, 1); err == nil {
+			t.Errorf("step %d: expected an error", step)
+		}
+	}
+}
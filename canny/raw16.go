@@ -0,0 +1,92 @@
+package canny
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"math"
+)
+
+// WindowLevel16 interprets data as width x height unsigned 16-bit samples in
+// row-major order and maps them to gray values with the given window and
+// level: samples at level-window/2 and below become 0, samples at
+// level+window/2 and above become 255, and the range in between is mapped
+// linearly. This covers 10 and 12-bit data stored in 16-bit words.
+func WindowLevel16(data []byte, width, height int, littleEndian bool, window, level float64) ([][]GrayPixel, error) {
+	if (width <= 0) || (height <= 0) {
+		return nil, errors.New("invalid raw image dimensions")
+	}
+	if len(data) != width*height*2 {
+		return nil, errors.New("raw data size doesn't match dimensions")
+	}
+	if window <= 0 {
+		return nil, errors.New("window must be positive")
+	}
+
+	var order binary.ByteOrder = binary.BigEndian
+	if littleEndian {
+		order = binary.LittleEndian
+	}
+
+	samples := make([][]uint16, height)
+	for y := 0; y < height; y++ {
+		samples[y] = make([]uint16, width)
+		for x := 0; x < width; x++ {
+			samples[y][x] = order.Uint16(data[2*(y*width+x):])
+		}
+	}
+
+	return WindowLevelSamples16(samples, window, level)
+}
+
+// WindowLevelSamples16 maps 16-bit samples to gray values with the given
+// window and level like WindowLevel16.
+func WindowLevelSamples16(samples [][]uint16, window, level float64) ([][]GrayPixel, error) {
+	if window <= 0 {
+		return nil, errors.New("window must be positive")
+	}
+	lower := level - window/2
+
+	result := make([][]GrayPixel, len(samples))
+	for y := range samples {
+		result[y] = make([]GrayPixel, len(samples[y]))
+		for x, sample := range samples[y] {
+			value := math.Round((float64(sample) - lower) / window * 255)
+			result[y][x] = GrayPixel{uint8(math.Max(0, math.Min(value, 255))), uint8(255)}
+		}
+	}
+
+	return result, nil
+}
+
+// Luma16 returns the 16-bit luma of every pixel of img, computed from the
+// full 16-bit color channels. Unlike a conversion through color.GrayModel it
+// keeps the precision of 16-bit color sources such as *image.NRGBA64, to be
+// mapped to gray values with WindowLevelSamples16.
+func Luma16(img image.Image) [][]uint16 {
+	bounds := img.Bounds()
+	result := make([][]uint16, bounds.Dy())
+
+	for y := range result {
+		result[y] = make([]uint16, bounds.Dx())
+		for x := range result[y] {
+			c := color.Gray16Model.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.Gray16)
+			result[y][x] = c.Y
+		}
+	}
+
+	return result
+}
+
+// DetectRaw16 detects the edges of raw 16-bit samples after mapping them to
+// gray values with WindowLevel16.
+func DetectRaw16(data []byte, width, height int, littleEndian bool, window, level float64, opts Options) ([][]GrayPixel, error) {
+	pixels, err := WindowLevel16(data, width, height, littleEndian, window, level)
+	if err != nil {
+		return nil, err
+	}
+
+	return CannyEdgeDetectContext(context.Background(), pixels, opts)
+}
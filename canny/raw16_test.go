@@ -0,0 +1,123 @@
+package canny
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// raw16 encodes samples as width x 1 16-bit words in the given byte order.
+func raw16(samples []uint16, order binary.ByteOrder) []byte {
+	data := make([]byte, 2*len(samples))
+	for i, s := range samples {
+		order.PutUint16(data[2*i:], s)
+	}
+	return data
+}
+
+func TestWindowLevel16MapsRamp(t *testing.T) {
+	// A 12-bit ramp, windowed to its middle half.
+	samples := make([]uint16, 4096/16)
+	for i := range samples {
+		samples[i] = uint16(16 * i)
+	}
+	for _, littleEndian := range []bool{true, false} {
+		var order binary.ByteOrder = binary.BigEndian
+		if littleEndian {
+			order = binary.LittleEndian
+		}
+		pixels, err := WindowLevel16(raw16(samples, order), len(samples), 1, littleEndian, 2048, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, s := range samples {
+			want := uint8(math.Max(0, math.Min(math.Round((float64(s)-1024)/2048*255), 255)))
+			if got := pixels[0][i].Y; got != want {
+				t.Errorf("little endian %t, sample %d: got %d, want %d", littleEndian, s, got, want)
+			}
+		}
+		if (pixels[0][0].Y != 0) || (pixels[0][64].Y != 0) || (pixels[0][128].Y != 128) || (pixels[0][192].Y != 255) {
+			t.Errorf("little endian %t: got %d, %d, %d, %d at 0, 1024, 2048, 3072", littleEndian, pixels[0][0].Y, pixels[0][64].Y, pixels[0][128].Y, pixels[0][192].Y)
+		}
+	}
+}
+
+func TestWindowLevel16RejectsBadInput(t *testing.T) {
+	data := make([]byte, 8)
+	if _, err := WindowLevel16(data, 2, 2, true, 0, 100); err == nil {
+		t.Error("expected an error for a zero window")
+	}
+	if _, err := WindowLevel16(data, 3, 2, true, 100, 100); err == nil {
+		t.Error("expected an error for mismatched dimensions")
+	}
+}
+
+func TestDetectRaw16FindsWindowedStep(t *testing.T) {
+	// A step of 40 between 12-bit values, which is faint in the full range but
+	// spanning most of a narrow window.
+	samples := make([]uint16, 16*16)
+	for i := range samples {
+		samples[i] = 3000
+		if i%16 >= 8 {
+			samples[i] = 3040
+		}
+	}
+	edges, err := DetectRaw16(raw16(samples, binary.LittleEndian), 16, 16, true, 50, 3020, Options{MinRatio: 0.1, MaxRatio: 0.3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := 2; y < 14; y++ {
+		if (edges[y][7].Y == 0) && (edges[y][8].Y == 0) {
+			t.Errorf("row %d: expected an edge at the step", y)
+		}
+		if edges[y][2].Y != 0 {
+			t.Errorf("row %d: unexpected edge away from the step", y)
+		}
+	}
+}
+
+// square64 returns a 16-bit color image with a square of color inner on a
+// background of color outer.
+func square64(size int, outer, inner uint16) *image.NRGBA64 {
+	img := image.NewNRGBA64(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			v := outer
+			if (x >= size/4) && (x < 3*size/4) && (y >= size/4) && (y < 3*size/4) {
+				v = inner
+			}
+			img.SetNRGBA64(x, y, color.NRGBA64{v, v, v, 0xffff})
+		}
+	}
+	return img
+}
+
+func TestLuma16KeepsFullPrecision(t *testing.T) {
+	img := square64(8, 1000, 1400)
+	luma := Luma16(img)
+	if (luma[0][0] != 1000) || (luma[4][4] != 1400) {
+		t.Errorf("got luma %d and %d, want 1000 and 1400", luma[0][0], luma[4][4])
+	}
+
+	pixels, err := WindowLevelSamples16(luma, 1024, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// (1000 - 512) / 1024 * 255 and (1400 - 512) / 1024 * 255.
+	if (pixels[0][0].Y != 122) || (pixels[4][4].Y != 221) {
+		t.Errorf("got %d and %d, want 122 and 221", pixels[0][0].Y, pixels[4][4].Y)
+	}
+	if _, err := WindowLevelSamples16(luma, 0, 1024); err == nil {
+		t.Error("expected an error for an empty window")
+	}
+}
+
+func TestColor64ToPixelArrayRounds(t *testing.T) {
+	// 25829 is 100.5 in 8 bits, which color.GrayModel truncates to 100.
+	img := square64(4, 25829, 25829)
+	if got := ImageToPixelArray(img)[0][0].Y; got != 101 {
+		t.Errorf("got %d, want 101", got)
+	}
+}
@@ -0,0 +1,144 @@
+package canny
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+)
+
+// RAW_GRADIENTS_MAGIC starts every file written by WriteRawGradients and
+// WriteRawSuppressed.
+const RAW_GRADIENTS_MAGIC = "CGRD"
+
+// RAW_DTYPE_FLOAT64 marks little-endian float64 samples in the header.
+const RAW_DTYPE_FLOAT64 = 1
+
+// RAW_DTYPE_FLOAT32 marks little-endian float32 samples in the header. The
+// dumps are always written as float64, but ReadRawGradients accepts float32
+// for files converted by other tools.
+const RAW_DTYPE_FLOAT32 = 2
+
+// RAW_HEADER_SIZE is the size of the header in bytes: the magic followed by
+// the width, the height and the dtype as little-endian uint32.
+const RAW_HEADER_SIZE = 16
+
+// RAW_MAX_PIXELS caps the width x height ReadRawGradients accepts from a
+// header before allocating.
+const RAW_MAX_PIXELS = 1 << 28
+
+// WriteRawGradients writes the gradient directions and magnitudes of stages
+// as two consecutive row-major height x width arrays of little-endian
+// float64 after a RAW_HEADER_SIZE byte header. With numpy they read back as
+// np.fromfile(path, "<f8", offset=16).reshape(2, height, width).
+func WriteRawGradients(w io.Writer, stages *Stages) error {
+	directions := func(x, y int) float64 { return stages.Directions[y][x] }
+	magnitudes := func(x, y int) float64 { return float64(stages.Magnitudes[y][x].Y) }
+	return writeRawArrays(w, len(stages.Directions[0]), len(stages.Directions), directions, magnitudes)
+}
+
+// WriteRawSuppressed writes the magnitudes after non-maximum suppression of
+// stages in the format of WriteRawGradients, as a single array.
+func WriteRawSuppressed(w io.Writer, stages *Stages) error {
+	suppressed := func(x, y int) float64 { return float64(stages.Suppressed[y][x].Y) }
+	return writeRawArrays(w, len(stages.Suppressed[0]), len(stages.Suppressed), suppressed)
+}
+
+func writeRawArrays(w io.Writer, width, height int, arrays ...func(x, y int) float64) error {
+	bw := bufio.NewWriter(w)
+
+	header := make([]byte, RAW_HEADER_SIZE)
+	copy(header, RAW_GRADIENTS_MAGIC)
+	binary.LittleEndian.PutUint32(header[4:], uint32(width))
+	binary.LittleEndian.PutUint32(header[8:], uint32(height))
+	binary.LittleEndian.PutUint32(header[12:], RAW_DTYPE_FLOAT64)
+	if _, err := bw.Write(header); err != nil {
+		return err
+	}
+
+	sample := make([]byte, 8)
+	for _, at := range arrays {
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				binary.LittleEndian.PutUint64(sample, math.Float64bits(at(x, y)))
+				if _, err := bw.Write(sample); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return bw.Flush()
+}
+
+// ErrNotRawGradients is returned by ReadRawGradients for data that doesn't
+// start with RAW_GRADIENTS_MAGIC.
+var ErrNotRawGradients = errors.New("not a raw gradient dump")
+
+// ReadRawGradients reads the gradient directions and magnitudes written by
+// WriteRawGradients, with float64 or float32 samples. Magnitudes are rounded
+// and clamped to gray values. Headers declaring more than RAW_MAX_PIXELS
+// pixels, or more samples than the data holds, are rejected.
+func ReadRawGradients(r io.Reader) ([][]GrayPixel, [][]float64, error) {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, RAW_HEADER_SIZE)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, nil, err
+	}
+	if string(header[:4]) != RAW_GRADIENTS_MAGIC {
+		return nil, nil, ErrNotRawGradients
+	}
+	width := int(binary.LittleEndian.Uint32(header[4:]))
+	height := int(binary.LittleEndian.Uint32(header[8:]))
+	dtype := binary.LittleEndian.Uint32(header[12:])
+
+	var sampleSize int
+	var decode func([]byte) float64
+	switch dtype {
+	case RAW_DTYPE_FLOAT64:
+		sampleSize = 8
+		decode = func(b []byte) float64 { return math.Float64frombits(binary.LittleEndian.Uint64(b)) }
+	case RAW_DTYPE_FLOAT32:
+		sampleSize = 4
+		decode = func(b []byte) float64 { return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))) }
+	default:
+		return nil, nil, fmt.Errorf("unknown sample type %d in raw gradient dump", dtype)
+	}
+
+	if uint64(width)*uint64(height) > RAW_MAX_PIXELS {
+		return nil, nil, fmt.Errorf("raw gradient dump of %dx%d pixels exceeds %d pixels", width, height, RAW_MAX_PIXELS)
+	}
+	// The samples are read before the arrays are allocated, so a header
+	// declaring more samples than the data holds doesn't allocate for them.
+	size := 2 * int64(width) * int64(height) * int64(sampleSize)
+	data, err := ioutil.ReadAll(io.LimitReader(br, size))
+	if err != nil {
+		return nil, nil, err
+	}
+	if int64(len(data)) < size {
+		return nil, nil, fmt.Errorf("raw gradient dump of %dx%d pixels needs %d bytes of samples, got %d", width, height, size, len(data))
+	}
+
+	directions := make([][]float64, height)
+	for y := range directions {
+		directions[y] = make([]float64, width)
+		for x := range directions[y] {
+			directions[y][x] = decode(data[:sampleSize])
+			data = data[sampleSize:]
+		}
+	}
+	magnitudes := make([][]GrayPixel, height)
+	for y := range magnitudes {
+		magnitudes[y] = make([]GrayPixel, width)
+		for x := range magnitudes[y] {
+			magnitudes[y][x] = GrayPixel{quantize(decode(data[:sampleSize]), ROUND_NEAREST), uint8(255)}
+			data = data[sampleSize:]
+		}
+	}
+
+	return magnitudes, directions, nil
+}
@@ -0,0 +1,200 @@
+package canny
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// readRawGradients parses the output of WriteRawGradients.
+func readRawGradients(t *testing.T, data []byte) (width, height int, directions, magnitudes []float64) {
+	width, height, samples := readRawArrays(t, data, 2)
+	return width, height, samples[:width*height], samples[width*height:]
+}
+
+// readRawArrays parses a raw dump holding count arrays.
+func readRawArrays(t *testing.T, data []byte, count int) (width, height int, samples []float64) {
+	if (len(data) < RAW_HEADER_SIZE) || (string(data[:4]) != RAW_GRADIENTS_MAGIC) {
+		t.Fatalf("missing header in %d bytes", len(data))
+	}
+	width = int(binary.LittleEndian.Uint32(data[4:]))
+	height = int(binary.LittleEndian.Uint32(data[8:]))
+	if dtype := binary.LittleEndian.Uint32(data[12:]); dtype != RAW_DTYPE_FLOAT64 {
+		t.Fatalf("got dtype %d, want %d", dtype, RAW_DTYPE_FLOAT64)
+	}
+	if want := RAW_HEADER_SIZE + count*width*height*8; len(data) != want {
+		t.Fatalf("got %d bytes, want %d", len(data), want)
+	}
+	samples = make([]float64, count*width*height)
+	for i := range samples {
+		samples[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[RAW_HEADER_SIZE+8*i:]))
+	}
+	return width, height, samples
+}
+
+func TestWriteRawGradients(t *testing.T) {
+	stages, err := DetectStages(context.Background(), square(9)[:7], Options{MinRatio: 0.1, MaxRatio: 0.3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteRawGradients(&buf, stages); err != nil {
+		t.Fatal(err)
+	}
+
+	width, height, directions, magnitudes := readRawGradients(t, buf.Bytes())
+	if (width != 9) || (height != 7) {
+		t.Fatalf("got %dx%d, want 9x7", width, height)
+	}
+	for _, p := range [][2]int{{0, 0}, {2, 3}, {8, 6}, {4, 1}} {
+		x, y := p[0], p[1]
+		if got := directions[y*width+x]; got != stages.Directions[y][x] {
+			t.Errorf("(%d, %d): got direction %v, want %v", x, y, got, stages.Directions[y][x])
+		}
+		if got := magnitudes[y*width+x]; got != float64(stages.Magnitudes[y][x].Y) {
+			t.Errorf("(%d, %d): got magnitude %v, want %d", x, y, got, stages.Magnitudes[y][x].Y)
+		}
+	}
+}
+
+func TestWriteRawSuppressedMatchesNMS(t *testing.T) {
+	ctx := context.Background()
+	// A strong step at x = 6 and a faint one below the low threshold at x = 9.
+	step := func(x, y int) uint8 {
+		if x >= 9 {
+			return 121
+		}
+		if x >= 6 {
+			return 120
+		}
+		return 100
+	}
+	pixels := newPixels(12, 12, step)
+	opts := Options{MinRatio: 0.1, MaxRatio: 0.3}
+	magnitudes, directions := gradient(ctx, pixels, SOBEL, ROUND_NEAREST, NORM_L2)
+	want, err := nonMaximumSuppression(ctx, magnitudes, directions, nmsTieTolerance(opts), opts.BinRule, opts.NMSInterpolation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stages, err := DetectStages(ctx, newPixels(12, 12, step), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteRawSuppressed(&buf, stages); err != nil {
+		t.Fatal(err)
+	}
+
+	width, height, suppressed := readRawArrays(t, buf.Bytes(), 1)
+	if (width != 12) || (height != 12) {
+		t.Fatalf("got %dx%d, want 12x12", width, height)
+	}
+	var faint int
+	for y := range want {
+		for x := range want[y] {
+			got := suppressed[y*width+x]
+			if got != float64(want[y][x].Y) {
+				t.Errorf("(%d, %d): got %v, want %d", x, y, got, want[y][x].Y)
+			}
+			if (x >= 8) && (got != 0) {
+				faint++
+			}
+		}
+	}
+	if faint == 0 {
+		t.Error("expected the faint step to survive in the dump before thresholding")
+	}
+}
+
+// rawHeader returns a raw gradient dump header declaring the given size.
+func rawHeader(width, height, dtype uint32) []byte {
+	header := make([]byte, RAW_HEADER_SIZE)
+	copy(header, RAW_GRADIENTS_MAGIC)
+	binary.LittleEndian.PutUint32(header[4:], width)
+	binary.LittleEndian.PutUint32(header[8:], height)
+	binary.LittleEndian.PutUint32(header[12:], dtype)
+	return header
+}
+
+func TestReadRawGradientsRoundTrip(t *testing.T) {
+	stages, err := DetectStages(context.Background(), square(12), Options{Blur: true, MinRatio: 0.1, MaxRatio: 0.3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteRawGradients(&buf, stages); err != nil {
+		t.Fatal(err)
+	}
+	magnitudes, directions, err := ReadRawGradients(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalPixels(magnitudes, stages.Magnitudes) {
+		t.Error("magnitudes differ")
+	}
+	for y := range directions {
+		for x := range directions[y] {
+			if directions[y][x] != stages.Directions[y][x] {
+				t.Fatalf("(%d, %d): got direction %v, want %v", x, y, directions[y][x], stages.Directions[y][x])
+			}
+		}
+	}
+
+	// The same 2x1 gradients as float32 samples.
+	data := rawHeader(2, 1, RAW_DTYPE_FLOAT32)
+	for _, v := range []float32{45, -90, 12.4, 300} {
+		data = append(data, make([]byte, 4)...)
+		binary.LittleEndian.PutUint32(data[len(data)-4:], math.Float32bits(v))
+	}
+	magnitudes, directions, err = ReadRawGradients(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (directions[0][0] != 45) || (directions[0][1] != -90) || (magnitudes[0][0].Y != 12) || (magnitudes[0][1].Y != 255) {
+		t.Errorf("got directions %v and magnitudes %v", directions, magnitudes)
+	}
+}
+
+func TestReadRawGradientsRejectsBadHeaders(t *testing.T) {
+	for _, header := range [][]byte{
+		rawHeader(1<<20, 1<<20, RAW_DTYPE_FLOAT64),
+		rawHeader(1<<12, 1<<12, RAW_DTYPE_FLOAT32),
+		rawHeader(3, 2, RAW_DTYPE_FLOAT64),
+		rawHeader(2, 2, 7),
+		[]byte("PNG\x00" + string(make([]byte, RAW_HEADER_SIZE-4))),
+	} {
+		data := append(header, make([]byte, 64)...)
+		if _, _, err := ReadRawGradients(bytes.NewReader(data)); err == nil {
+			t.Errorf("header %x: expected an error", header)
+		}
+	}
+}
+
+func TestResumeFromGradientsReproducesDetectStages(t *testing.T) {
+	r := [2]float64{60, 120}
+	for _, opts := range []Options{
+		{Blur: true, MinRatio: 0.1, MaxRatio: 0.3},
+		{MinRatio: 0.2, MaxRatio: 0.5, BorderValid: true, MagnitudeFloor: 20},
+		{MinRatio: 0.1, MaxRatio: 0.3, AngleRange: &r},
+	} {
+		stages, err := DetectStages(context.Background(), square(20), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resumed, err := ResumeFromGradients(context.Background(), stages.Magnitudes, stages.Directions, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equalPixels(resumed.Edges, stages.Edges) {
+			t.Errorf("%+v: resumed edges differ", opts)
+		}
+	}
+
+	_, err := ResumeFromGradients(context.Background(), square(4), make([][]float64, 3), Options{})
+	if err == nil {
+		t.Error("expected an error for mismatching dimensions")
+	}
+}
@@ -0,0 +1,151 @@
+package canny
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// ResizeBilinear resamples pixels to the given size with bilinear
+// interpolation of the gray values.
+func ResizeBilinear(pixels [][]GrayPixel, width, height int) [][]GrayPixel {
+	srcHeight := len(pixels)
+	srcWidth := len(pixels[0])
+	scaleX := float64(srcWidth) / float64(width)
+	scaleY := float64(srcHeight) / float64(height)
+	var result [][]GrayPixel
+
+	for y := 0; y < height; y++ {
+		srcY := math.Max(0, (float64(y)+0.5)*scaleY-0.5)
+		y0 := int(srcY)
+		y1 := int(math.Min(float64(srcHeight-1), float64(y0+1)))
+		fy := srcY - float64(y0)
+		resultRow := make([]GrayPixel, 0, width)
+		for x := 0; x < width; x++ {
+			srcX := math.Max(0, (float64(x)+0.5)*scaleX-0.5)
+			x0 := int(srcX)
+			x1 := int(math.Min(float64(srcWidth-1), float64(x0+1)))
+			fx := srcX - float64(x0)
+
+			top := float64(pixels[y0][x0].Y)*(1-fx) + float64(pixels[y0][x1].Y)*fx
+			bottom := float64(pixels[y1][x0].Y)*(1-fx) + float64(pixels[y1][x1].Y)*fx
+			value := top*(1-fy) + bottom*fy
+			resultRow = append(resultRow, GrayPixel{uint8(math.Round(value)), pixels[y0][x0].A})
+		}
+		result = append(result, resultRow)
+	}
+
+	return result
+}
+
+// DownsampleEdges shrinks an edge map by an integer factor. An output pixel
+// is an edge with the highest magnitude of its block if any pixel of the
+// block is an edge.
+func DownsampleEdges(edges [][]GrayPixel, factor int) [][]GrayPixel {
+	height := (len(edges) + factor - 1) / factor
+	width := (len(edges[0]) + factor - 1) / factor
+	result := make([][]GrayPixel, height)
+
+	for y := 0; y < height; y++ {
+		result[y] = make([]GrayPixel, width)
+		for x := 0; x < width; x++ {
+			max := GrayPixel{uint8(0), uint8(255)}
+			for i := y * factor; (i < (y+1)*factor) && (i < len(edges)); i++ {
+				for j := x * factor; (j < (x+1)*factor) && (j < len(edges[i])); j++ {
+					if edges[i][j].Y > max.Y {
+						max = edges[i][j]
+					}
+				}
+			}
+			result[y][x] = max
+		}
+	}
+
+	return result
+}
+
+// UpsampleEdges scales an edge map to width x height with nearest neighbour
+// sampling, so edges stay binary.
+func UpsampleEdges(edges [][]GrayPixel, width, height int) [][]GrayPixel {
+	srcHeight := len(edges)
+	srcWidth := len(edges[0])
+	result := make([][]GrayPixel, height)
+
+	for y := 0; y < height; y++ {
+		result[y] = make([]GrayPixel, width)
+		sy := y * srcHeight / height
+		for x := 0; x < width; x++ {
+			result[y][x] = edges[sy][x*srcWidth/width]
+		}
+	}
+
+	return result
+}
+
+// RotateBilinear rotates pixels counterclockwise as displayed by the given
+// angle in degrees around the image center, keeping the dimensions. Samples
+// are interpolated bilinearly and clamped to the image, so the corners repeat
+// the border instead of introducing new edges.
+func RotateBilinear(pixels [][]GrayPixel, degrees float64) [][]GrayPixel {
+	height := len(pixels)
+	width := len(pixels[0])
+	sin, cos := math.Sincos(degrees * (math.Pi / 180))
+	cx := float64(width-1) / 2
+	cy := float64(height-1) / 2
+	var result [][]GrayPixel
+
+	for y := 0; y < height; y++ {
+		resultRow := make([]GrayPixel, 0, width)
+		for x := 0; x < width; x++ {
+			dx, dy := float64(x)-cx, float64(y)-cy
+			srcX := cx + dx*cos - dy*sin
+			srcY := cy + dx*sin + dy*cos
+			value := sampleLinear(pixels, srcX, srcY)
+			resultRow = append(resultRow, GrayPixel{uint8(math.Round(value)), uint8(255)})
+		}
+		result = append(result, resultRow)
+	}
+
+	return result
+}
+
+// RotateImage is RotateBilinear for the colors of img. The result keeps the
+// bounds of img, so it lines up with edges detected on rotated pixels of it.
+func RotateImage(img image.Image, degrees float64) *image.RGBA64 {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	sin, cos := math.Sincos(degrees * (math.Pi / 180))
+	cx := float64(width-1) / 2
+	cy := float64(height-1) / 2
+	at := func(x, y int) color.RGBA64 {
+		x = clampIndex(x, width)
+		y = clampIndex(y, height)
+		return color.RGBA64Model.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.RGBA64)
+	}
+	result := image.NewRGBA64(bounds)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			dx, dy := float64(x)-cx, float64(y)-cy
+			srcX := cx + dx*cos - dy*sin
+			srcY := cy + dx*sin + dy*cos
+			x0, y0 := math.Floor(srcX), math.Floor(srcY)
+			fx, fy := srcX-x0, srcY-y0
+			ix, iy := int(x0), int(y0)
+			c00, c10, c01, c11 := at(ix, iy), at(ix+1, iy), at(ix, iy+1), at(ix+1, iy+1)
+			mix := func(v00, v10, v01, v11 uint16) uint16 {
+				top := float64(v00)*(1-fx) + float64(v10)*fx
+				bottom := float64(v01)*(1-fx) + float64(v11)*fx
+				return uint16(math.Round(top*(1-fy) + bottom*fy))
+			}
+			result.SetRGBA64(bounds.Min.X+x, bounds.Min.Y+y, color.RGBA64{
+				mix(c00.R, c10.R, c01.R, c11.R),
+				mix(c00.G, c10.G, c01.G, c11.G),
+				mix(c00.B, c10.B, c01.B, c11.B),
+				mix(c00.A, c10.A, c01.A, c11.A),
+			})
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,93 @@
+package canny
+
+import (
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	"math"
+	"testing"
+)
+
+func TestResizeBilinear(t *testing.T) {
+	pixels := newPixels(2, 2, func(x, y int) uint8 { return uint8(100 * x) })
+	resized := ResizeBilinear(pixels, 4, 4)
+	if (len(resized) != 4) || (len(resized[0]) != 4) {
+		t.Fatalf("got %dx%d, want 4x4", len(resized[0]), len(resized))
+	}
+	want := []uint8{0, 25, 75, 100}
+	for y := range resized {
+		for x := range resized[y] {
+			if resized[y][x].Y != want[x] {
+				t.Errorf("(%d, %d): got %d, want %d", x, y, resized[y][x].Y, want[x])
+			}
+		}
+	}
+}
+
+func TestDownsampleEdges(t *testing.T) {
+	edges := newPixels(5, 3, func(x, y int) uint8 {
+		if (x == 4) && (y == 2) {
+			return 255
+		}
+		return 0
+	})
+	small := DownsampleEdges(edges, 2)
+	if (len(small) != 2) || (len(small[0]) != 3) {
+		t.Fatalf("got %dx%d, want 3x2", len(small[0]), len(small))
+	}
+	for y := range small {
+		for x := range small[y] {
+			want := uint8(0)
+			if (x == 2) && (y == 1) {
+				want = 255
+			}
+			if small[y][x].Y != want {
+				t.Errorf("(%d, %d): got %d, want %d", x, y, small[y][x].Y, want)
+			}
+		}
+	}
+}
+
+func TestUpsampleEdges(t *testing.T) {
+	edges := newPixels(3, 2, func(x, y int) uint8 {
+		if x == y {
+			return 255
+		}
+		return 0
+	})
+	up := UpsampleEdges(edges, 6, 4)
+	if (len(up) != 4) || (len(up[0]) != 6) {
+		t.Fatalf("got %dx%d, want 6x4", len(up[0]), len(up))
+	}
+	for y := range up {
+		for x := range up[y] {
+			if up[y][x] != edges[y/2][x/2] {
+				t.Errorf("(%d, %d): got %v, want %v", x, y, up[y][x], edges[y/2][x/2])
+			}
+		}
+	}
+}
+
+func TestRotateImageMatchesRotateBilinear(t *testing.T) {
+	pixels := noisePixels(9, 6, 2)
+	src := image.NewGray(image.Rect(3, 4, 12, 10))
+	for y := range pixels {
+		for x := range pixels[y] {
+			src.SetGray(3+x, 4+y, color.Gray{pixels[y][x].Y})
+		}
+	}
+
+	rotated := RotateImage(src, 30)
+	want := RotateBilinear(pixels, 30)
+	if rotated.Bounds() != src.Bounds() {
+		t.Fatalf("got bounds %v, want %v", rotated.Bounds(), src.Bounds())
+	}
+	for y := range want {
+		for x := range want[y] {
+			got := color.GrayModel.Convert(rotated.At(3+x, 4+y)).(color.Gray).Y
+			if math.Abs(float64(got)-float64(want[y][x].Y)) > 1 {
+				t.Errorf("(%d, %d): got %d, want %d", x, y, got, want[y][x].Y)
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package canny
+
+import (
+	"context"
+	"math"
+)
+
+// SCALE_MAP_SIGMAS are the standard deviations of the Gaussian blurs ScaleMap
+// detects edges at, from fine to coarse.
+var SCALE_MAP_SIGMAS = []float64{1, 2, 4, 8}
+
+// ScaleMap detects the edges of pixels after blurring them with each of the
+// given sigmas, from fine to coarse, and records for every edge pixel of the
+// finest scale how many consecutive scales an edge persists within one pixel
+// of it. The result maps that count linearly to gray values, so edges that
+// survive the coarsest scale are white. opts.Blur is ignored.
+func ScaleMap(ctx context.Context, pixels [][]GrayPixel, opts Options, sigmas []float64) ([][]GrayPixel, error) {
+	if err := checkRectangular(pixels); err != nil {
+		return nil, err
+	}
+	opts.Blur = false
+
+	height := len(pixels)
+	width := len(pixels[0])
+	persistence := make([][]int, height)
+	for y := range persistence {
+		persistence[y] = make([]int, width)
+	}
+
+	for level, sigma := range sigmas {
+		edges, err := CannyEdgeDetectContext(ctx, GaussianBlurSigma(pixels, sigma), opts)
+		if err != nil {
+			return nil, err
+		}
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				if persistence[y][x] != level {
+					continue
+				}
+				if (level == 0) && (edges[y][x].Y == 0) {
+					continue
+				}
+				if (level > 0) && !hasEdgeNearby(edges, x, y, 1) {
+					continue
+				}
+				persistence[y][x]++
+			}
+		}
+	}
+
+	var result [][]GrayPixel
+	for y := 0; y < height; y++ {
+		resultRow := make([]GrayPixel, 0, width)
+		for x := 0; x < width; x++ {
+			value := persistence[y][x] * 255 / len(sigmas)
+			resultRow = append(resultRow, GrayPixel{uint8(value), 255})
+		}
+		result = append(result, resultRow)
+	}
+
+	return result, nil
+}
+
+// GaussianBlurSigma blurs pixels with a separable Gaussian of the given
+// standard deviation, truncated at three sigmas and clamped at the borders.
+func GaussianBlurSigma(pixels [][]GrayPixel, sigma float64) [][]GrayPixel {
+	kernel := gaussianKernel(sigma)
+	radius := len(kernel) / 2
+
+	height := len(pixels)
+	width := len(pixels[0])
+	horizontal := make([][]float64, height)
+	for y := 0; y < height; y++ {
+		horizontal[y] = make([]float64, width)
+		for x := 0; x < width; x++ {
+			var v float64
+			for i, k := range kernel {
+				j := clampIndex(x+i-radius, width)
+				v += k * float64(pixels[y][j].Y)
+			}
+			horizontal[y][x] = v
+		}
+	}
+
+	var result [][]GrayPixel
+	for y := 0; y < height; y++ {
+		resultRow := make([]GrayPixel, 0, width)
+		for x := 0; x < width; x++ {
+			var v float64
+			for i, k := range kernel {
+				v += k * horizontal[clampIndex(y+i-radius, height)][x]
+			}
+			resultRow = append(resultRow, GrayPixel{clampUint8(int(math.Round(v))), pixels[y][x].A})
+		}
+		result = append(result, resultRow)
+	}
+
+	return result
+}
+
+func clampIndex(i, n int) int {
+	if i < 0 {
+		return 0
+	}
+	if i >= n {
+		return n - 1
+	}
+	return i
+}
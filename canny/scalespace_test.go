@@ -0,0 +1,46 @@
+package canny
+
+import (
+	"context"
+	"testing"
+)
+
+func TestScaleMapStraightEdgeOutlastsTexture(t *testing.T) {
+	// A vertical step at x = 40 and a fine checkerboard of the same contrast
+	// in the left half.
+	pixels := newPixels(80, 60, func(x, y int) uint8 {
+		switch {
+		case x >= 40:
+			return 100
+		case (x >= 8) && (x < 24) && (y >= 20) && (y < 40):
+			return uint8(40 + 60*((x/4+y/4)%2))
+		}
+		return 40
+	})
+	scales, err := ScaleMap(context.Background(), pixels, Options{MinRatio: 0.1, MaxRatio: 0.2}, SCALE_MAP_SIGMAS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	maxIn := func(minX, maxX, minY, maxY int) uint8 {
+		var max uint8
+		for y := minY; y < maxY; y++ {
+			for x := minX; x < maxX; x++ {
+				if scales[y][x].Y > max {
+					max = scales[y][x].Y
+				}
+			}
+		}
+		return max
+	}
+	edge := maxIn(37, 43, 25, 35)
+	// The outline of the textured block is a structural edge itself, so only
+	// its inside is measured.
+	texture := maxIn(12, 20, 26, 34)
+	if edge != 255 {
+		t.Errorf("the step persists across %d/255 of the scales, want all of them", edge)
+	}
+	if (texture == 0) || (texture >= edge) {
+		t.Errorf("the texture persists across %d/255 of the scales, want fewer than the step", texture)
+	}
+}
@@ -0,0 +1,87 @@
+package canny
+
+import (
+	"errors"
+)
+
+// DetectBatch runs CannyEdgeDetect on every frame with the same parameters.
+func DetectBatch(frames [][][]GrayPixel, blur bool, minRatio, maxRatio float64) ([][][]GrayPixel, error) {
+	var result [][][]GrayPixel
+
+	for _, frame := range frames {
+		edges, err := CannyEdgeDetect(frame, blur, minRatio, maxRatio)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, edges)
+	}
+
+	return result, nil
+}
+
+// DetectSequence detects edges in consecutive frames of a video and reduces
+// flicker with temporal hysteresis: an edge pixel of a frame is only kept if
+// the previous frame had an edge within radius pixels of it. The first frame
+// is returned as detected.
+func DetectSequence(frames [][][]GrayPixel, blur bool, minRatio, maxRatio float64, radius int) ([][][]GrayPixel, error) {
+	detected, err := DetectBatch(frames, blur, minRatio, maxRatio)
+	if err != nil {
+		return nil, err
+	}
+	if len(detected) == 0 {
+		return detected, nil
+	}
+
+	result := [][][]GrayPixel{detected[0]}
+	for i := 1; i < len(detected); i++ {
+		smoothed, err := temporalHysteresis(detected[i-1], detected[i], radius)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, smoothed)
+	}
+
+	return result, nil
+}
+
+func temporalHysteresis(prev, cur [][]GrayPixel, radius int) ([][]GrayPixel, error) {
+	if (len(prev) != len(cur)) || (len(prev[0]) != len(cur[0])) {
+		return nil, errors.New("dimensions of consecutive frames must match")
+	}
+	var result [][]GrayPixel
+
+	for y := 0; y < len(cur); y++ {
+		resultRow := make([]GrayPixel, len(cur[y]))
+		for x := 0; x < len(cur[y]); x++ {
+			r := cur[y][x]
+			if (r.Y != 0) && !hasEdgeNearby(prev, x, y, radius) {
+				r.Y = uint8(0)
+			}
+			resultRow[x] = r
+		}
+		result = append(result, resultRow)
+	}
+
+	return result, nil
+}
+
+func hasEdgeNearby(pixels [][]GrayPixel, x, y, radius int) bool {
+	height := len(pixels)
+	width := len(pixels[0])
+
+	for i := y - radius; i <= y+radius; i++ {
+		if (i < 0) || (i >= height) {
+			continue
+		}
+		for j := x - radius; j <= x+radius; j++ {
+			if (j < 0) || (j >= width) {
+				continue
+			}
+			if pixels[i][j].Y != 0 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
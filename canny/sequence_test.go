@@ -0,0 +1,80 @@
+package canny
+
+import "testing"
+
+// dot returns a width x height frame whose only edge pixel is (px, py).
+func dot(width, height, px, py int) [][]GrayPixel {
+	return newPixels(width, height, func(x, y int) uint8 {
+		if (x == px) && (y == py) {
+			return 255
+		}
+		return 0
+	})
+}
+
+func TestTemporalHysteresis(t *testing.T) {
+	prev := dot(8, 8, 2, 2)
+	for _, c := range []struct {
+		x, y int
+		kept bool
+	}{
+		{2, 2, true},
+		{3, 3, true},
+		{4, 2, false},
+		{6, 6, false},
+	} {
+		result, err := temporalHysteresis(prev, dot(8, 8, c.x, c.y), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kept := result[c.y][c.x].Y != 0; kept != c.kept {
+			t.Errorf("edge at (%d, %d): got kept %v, want %v", c.x, c.y, kept, c.kept)
+		}
+	}
+
+	if _, err := temporalHysteresis(prev, dot(7, 8, 0, 0), 1); err == nil {
+		t.Error("expected an error for mismatching frame sizes")
+	}
+}
+
+func TestDetectSequenceKeepsFirstFrame(t *testing.T) {
+	square := newPixels(16, 16, func(x, y int) uint8 {
+		if (x >= 4) && (x < 12) && (y >= 4) && (y < 12) {
+			return 200
+		}
+		return 20
+	})
+	blank := newPixels(16, 16, func(x, y int) uint8 { return 20 })
+	moved := newPixels(16, 16, func(x, y int) uint8 { return square[y][(x+8)%16].Y })
+
+	frames := [][][]GrayPixel{square, blank, moved}
+	detected, err := DetectBatch(frames, false, 0.2, 0.6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sequence, err := DetectSequence(frames, false, 0.2, 0.6, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sequence) != len(frames) {
+		t.Fatalf("got %d frames, want %d", len(sequence), len(frames))
+	}
+	if !equalPixels(sequence[0], detected[0]) {
+		t.Error("first frame differs from its plain detection")
+	}
+	// Edges of the last frame have no edge in the blank frame before it.
+	edges := 0
+	for y := range sequence[2] {
+		for x := range sequence[2][y] {
+			if detected[2][y][x].Y != 0 {
+				edges++
+			}
+			if sequence[2][y][x].Y != 0 {
+				t.Fatalf("edge at (%d, %d) survived without a previous edge", x, y)
+			}
+		}
+	}
+	if edges == 0 {
+		t.Error("expected edges in the plain detection of the last frame")
+	}
+}
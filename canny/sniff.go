@@ -0,0 +1,116 @@
+package canny
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// SNIFF_LENGTH is the number of leading bytes SniffFormat inspects.
+const SNIFF_LENGTH = 16
+
+// ISO_BRANDS maps the major brands of ISO base media files, found after the
+// ftyp box type at offset 4, to the image formats they identify.
+var ISO_BRANDS = map[string]string{
+	"heic": "HEIC",
+	"heix": "HEIC",
+	"hevc": "HEIC",
+	"heim": "HEIC",
+	"heis": "HEIC",
+	"mif1": "HEIF",
+	"msf1": "HEIF",
+	"avif": "AVIF",
+	"avis": "AVIF",
+}
+
+// SniffFormat names the image format of a file from its first bytes, or
+// returns an empty string if the format isn't recognized. It knows common
+// formats beyond the registered decoders so unsupported inputs can be
+// reported by name.
+func SniffFormat(header []byte) string {
+	switch {
+	case bytes.HasPrefix(header, []byte("\xff\xd8\xff")):
+		return "JPEG"
+	case bytes.HasPrefix(header, []byte("\x89PNG\r\n\x1a\n")):
+		return "PNG"
+	case bytes.HasPrefix(header, []byte("GIF87a")), bytes.HasPrefix(header, []byte("GIF89a")):
+		return "GIF"
+	case (len(header) >= 12) && bytes.HasPrefix(header, []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WEBP")):
+		return "WebP"
+	case (len(header) >= 12) && bytes.Equal(header[4:8], []byte("ftyp")):
+		return ISO_BRANDS[string(header[8:12])]
+	case bytes.HasPrefix(header, []byte("II*\x00")), bytes.HasPrefix(header, []byte("MM\x00*")):
+		return "TIFF"
+	case bytes.HasPrefix(header, []byte("BM")):
+		return "BMP"
+	case bytes.HasPrefix(header, []byte("\xff\x0a")), bytes.HasPrefix(header, []byte("\x00\x00\x00\x0cJXL \r\n\x87\n")):
+		return "JPEG XL"
+	case bytes.HasPrefix(header, []byte("8BPS")):
+		return "PSD"
+	case bytes.HasPrefix(header, []byte("\x00\x00\x01\x00")):
+		return "ICO"
+	}
+
+	return ""
+}
+
+// ErrProgressiveJPEG is returned for progressive JPEGs where they are
+// rejected.
+var ErrProgressiveJPEG = errors.New("progressive JPEG")
+
+// IsProgressiveJPEG reports whether r holds a progressive JPEG. It reads the
+// marker segments up to the first frame header without decoding any image
+// data, so untrusted inputs can be checked cheaply before decoding them.
+// Inputs that aren't JPEGs are reported as not progressive.
+func IsProgressiveJPEG(r io.Reader) (bool, error) {
+	br := bufio.NewReader(r)
+	soi := make([]byte, 2)
+	if _, err := io.ReadFull(br, soi); err != nil {
+		return false, nil
+	}
+	if !bytes.Equal(soi, JPEG_SOI) {
+		return false, nil
+	}
+
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return false, err
+		}
+		if b != 0xff {
+			return false, fmt.Errorf("invalid JPEG marker 0x%02x", b)
+		}
+		marker := byte(0xff)
+		for marker == 0xff {
+			if marker, err = br.ReadByte(); err != nil {
+				return false, err
+			}
+		}
+
+		switch {
+		case (marker == 0x01) || ((marker >= 0xd0) && (marker <= 0xd8)):
+			// Standalone markers carry no segment.
+			continue
+		case (marker == 0xd9) || (marker == 0xda):
+			// The image ends or its data starts without a frame header.
+			return false, nil
+		case (marker >= 0xc0) && (marker <= 0xcf) && (marker != 0xc4) && (marker != 0xc8) && (marker != 0xcc):
+			// Frame headers SOF2, SOF6, SOF10 and SOF14 are progressive.
+			return (marker & 0x03) == 0x02, nil
+		}
+
+		length := make([]byte, 2)
+		if _, err := io.ReadFull(br, length); err != nil {
+			return false, err
+		}
+		n := int(length[0])<<8 | int(length[1])
+		if n < 2 {
+			return false, fmt.Errorf("invalid JPEG segment length %d", n)
+		}
+		if _, err := br.Discard(n - 2); err != nil {
+			return false, err
+		}
+	}
+}
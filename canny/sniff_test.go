@@ -0,0 +1,69 @@
+package canny
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestSniffFormat(t *testing.T) {
+	for _, c := range []struct {
+		header string
+		want   string
+	}{
+		{"\xff\xd8\xff\xe0\x00\x10JFIF", "JPEG"},
+		{"\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR", "PNG"},
+		{"GIF89a\x10\x00", "GIF"},
+		{"RIFF\x24\x00\x00\x00WEBPVP8 ", "WebP"},
+		{"\x00\x00\x00\x18ftypheic\x00\x00\x00\x00", "HEIC"},
+		{"\x00\x00\x00\x1cftypavif\x00\x00\x00\x00", "AVIF"},
+		{"\x00\x00\x00\x18ftypisom\x00\x00\x00\x00", ""},
+		{"II*\x00\x08\x00\x00\x00", "TIFF"},
+		{"BM\x36\x00", "BMP"},
+		{"hello, world", ""},
+		{"", ""},
+	} {
+		if got := SniffFormat([]byte(c.header)); got != c.want {
+			t.Errorf("%q: got %q, want %q", c.header, got, c.want)
+		}
+	}
+}
+
+func TestIsProgressiveJPEG(t *testing.T) {
+	var baseline bytes.Buffer
+	if err := jpeg.Encode(&baseline, image.NewGray(image.Rect(0, 0, 16, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+	// The same header with its SOF0 frame header relabelled as SOF2, after a
+	// fill byte before the marker.
+	progressive := bytes.Replace(baseline.Bytes(), []byte{0xff, 0xc0}, []byte{0xff, 0xff, 0xc2}, 1)
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"baseline", baseline.Bytes(), false},
+		{"progressive", progressive, true},
+		{"png", pngData.Bytes(), false},
+		{"empty", nil, false},
+	} {
+		got, err := IsProgressiveJPEG(bytes.NewReader(c.data))
+		if (err != nil) || (got != c.want) {
+			t.Errorf("%s: got %t, %v, want %t", c.name, got, err, c.want)
+		}
+	}
+
+	// Segments that are cut off or lack their marker are errors.
+	for _, data := range []string{"\xff\xd8\xff\xe0\x00\x10JF", "\xff\xd8\x00\xc2", "\xff\xd8\xff\xe0\x00\x01"} {
+		if _, err := IsProgressiveJPEG(bytes.NewReader([]byte(data))); err == nil {
+			t.Errorf("%q: expected an error", data)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package canny
+
+// SubpixelPoint is an edge position with sub-pixel precision.
+type SubpixelPoint struct {
+	X, Y float64
+}
+
+// SubpixelEdges refines the position of every edge pixel of a detection run
+// by fitting a parabola through the gradient magnitudes of the pixel and its
+// two neighbours along the gradient direction, the same samples non-maximum
+// suppression compares. Points are returned in row-major order.
+func SubpixelEdges(stages *Stages, rule BinRule) []SubpixelPoint {
+	var points []SubpixelPoint
+	magnitudes := stages.Magnitudes
+
+	for y := 0; y < len(stages.Edges); y++ {
+		for x := 0; x < len(stages.Edges[y]); x++ {
+			if stages.Edges[y][x].Y == 0 {
+				continue
+			}
+			p, q := getPointsInGradientDirection(stages.Directions, x, y, rule)
+			before := float64(magnitudes[q.Y][q.X].Y)
+			center := float64(magnitudes[y][x].Y)
+			after := float64(magnitudes[p.Y][p.X].Y)
+
+			// Neighbours clamped at the border leave nothing to fit.
+			offset := float64(0)
+			denominator := before - 2*center + after
+			if (p.X-x == x-q.X) && (p.Y-y == y-q.Y) && (denominator != 0) {
+				offset = (before - after) / (2 * denominator)
+			}
+			dx := float64(p.X - x)
+			dy := float64(p.Y - y)
+			points = append(points, SubpixelPoint{float64(x) + offset*dx, float64(y) + offset*dy})
+		}
+	}
+
+	return points
+}
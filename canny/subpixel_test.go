@@ -0,0 +1,35 @@
+package canny
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSubpixelEdges(t *testing.T) {
+	magnitudes := newPixels(8, 3, func(x, y int) uint8 {
+		return []uint8{90, 40, 0, 0, 50, 100, 80, 0}[x]
+	})
+	edges := newPixels(8, 3, func(x, y int) uint8 {
+		if (y == 1) && ((x == 0) || (x == 5)) {
+			return 255
+		}
+		return 0
+	})
+	directions := make([][]float64, 3)
+	for y := range directions {
+		directions[y] = make([]float64, 8)
+	}
+
+	points := SubpixelEdges(&Stages{Magnitudes: magnitudes, Directions: directions, Edges: edges}, ROUND_HALF_UP)
+	if len(points) != 2 {
+		t.Fatalf("got %d points, want 2", len(points))
+	}
+	// The border pixel has no neighbour to its left and stays in place.
+	if (points[0].X != 0) || (points[0].Y != 1) {
+		t.Errorf("border point: got %v, want (0, 1)", points[0])
+	}
+	// The parabola through 50, 100 and 80 peaks 3/14 of a pixel towards 80.
+	if (math.Abs(points[1].X-(5+3.0/14)) > 1e-9) || (points[1].Y != 1) {
+		t.Errorf("got %v, want (%v, 1)", points[1], 5+3.0/14)
+	}
+}
@@ -0,0 +1,278 @@
+package canny
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+)
+
+// TIFF tags read by OpenTIFF.
+const (
+	TIFF_IMAGE_WIDTH       = 256
+	TIFF_IMAGE_LENGTH      = 257
+	TIFF_BITS_PER_SAMPLE   = 258
+	TIFF_COMPRESSION       = 259
+	TIFF_PHOTOMETRIC       = 262
+	TIFF_STRIP_OFFSETS     = 273
+	TIFF_SAMPLES_PER_PIXEL = 277
+	TIFF_ROWS_PER_STRIP    = 278
+	TIFF_STRIP_BYTE_COUNTS = 279
+	TIFF_PLANAR_CONFIG     = 284
+	TIFF_TILE_WIDTH        = 322
+	TIFF_TILE_LENGTH       = 323
+	TIFF_TILE_OFFSETS      = 324
+	TIFF_TILE_BYTE_COUNTS  = 325
+	TIFF_EXTRA_SAMPLES     = 338
+)
+
+// Values of the TIFF tags that OpenTIFF supports.
+const (
+	TIFF_UNCOMPRESSED     = 1
+	TIFF_CHUNKY           = 1
+	TIFF_WHITE_IS_ZERO    = 0
+	TIFF_BLACK_IS_ZERO    = 1
+	TIFF_RGB              = 2
+	TIFF_ASSOCIATED_ALPHA = 1
+)
+
+// Limits OpenTIFF checks crafted headers against before allocating.
+const (
+	TIFF_MAX_BLOCK_BYTES = 1 << 28
+	TIFF_MAX_TAG_VALUES  = 1 << 24
+	TIFF_MAX_IFD_ENTRIES = 1 << 12
+)
+
+// TIFF_LITTLE_ENDIAN_MARK and TIFF_BIG_ENDIAN_MARK start TIFF files of either
+// byte order.
+const (
+	TIFF_LITTLE_ENDIAN_MARK = "II*\x00"
+	TIFF_BIG_ENDIAN_MARK    = "MM\x00*"
+)
+
+// ErrUnsupportedTIFF is returned by OpenTIFF for TIFFs it can't read
+// strip by strip or tile by tile.
+var ErrUnsupportedTIFF = errors.New("unsupported TIFF, only uncompressed 8-bit gray, RGB and RGBA images are supported")
+
+// TIFF reads the rows of the first image of a TIFF file on demand, loading
+// only the strips or tiles that hold them instead of decoding the whole
+// image. It supports uncompressed 8-bit grayscale, RGB and RGBA images with
+// interleaved samples.
+type TIFF struct {
+	Width, Height int
+	// Tiled is set for tiled images and unset for images stored in strips.
+	Tiled bool
+
+	r           io.ReaderAt
+	samples     int
+	photometric uint64
+	associated  bool
+	// Strips are blocks of the full image width.
+	blockWidth, blockHeight int
+	offsets, byteCounts     []uint64
+}
+
+// OpenTIFF reads the header and the first image file directory of the TIFF
+// in r. Pixel data is only read by ReadRows.
+func OpenTIFF(r io.ReaderAt) (*TIFF, error) {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, err
+	}
+	var order binary.ByteOrder
+	switch string(header[:4]) {
+	case TIFF_LITTLE_ENDIAN_MARK:
+		order = binary.LittleEndian
+	case TIFF_BIG_ENDIAN_MARK:
+		order = binary.BigEndian
+	default:
+		return nil, errors.New("not a TIFF")
+	}
+
+	tags, err := readTIFFDirectory(r, order, int64(order.Uint32(header[4:])))
+	if err != nil {
+		return nil, err
+	}
+	single := func(tag uint16, fallback uint64) uint64 {
+		if values, ok := tags[tag]; ok && (len(values) > 0) {
+			return values[0]
+		}
+		return fallback
+	}
+
+	t := &TIFF{r: r}
+	t.Width = int(single(TIFF_IMAGE_WIDTH, 0))
+	t.Height = int(single(TIFF_IMAGE_LENGTH, 0))
+	t.samples = int(single(TIFF_SAMPLES_PER_PIXEL, 1))
+	t.photometric = single(TIFF_PHOTOMETRIC, TIFF_BLACK_IS_ZERO)
+	t.associated = single(TIFF_EXTRA_SAMPLES, 0) == TIFF_ASSOCIATED_ALPHA
+	if (t.Width <= 0) || (t.Height <= 0) {
+		return nil, errors.New("TIFF image must not be empty")
+	}
+	if (single(TIFF_COMPRESSION, TIFF_UNCOMPRESSED) != TIFF_UNCOMPRESSED) || (single(TIFF_PLANAR_CONFIG, TIFF_CHUNKY) != TIFF_CHUNKY) {
+		return nil, ErrUnsupportedTIFF
+	}
+	for _, bits := range tags[TIFF_BITS_PER_SAMPLE] {
+		if bits != 8 {
+			return nil, ErrUnsupportedTIFF
+		}
+	}
+	switch {
+	case ((t.photometric == TIFF_WHITE_IS_ZERO) || (t.photometric == TIFF_BLACK_IS_ZERO)) && (t.samples == 1):
+	case (t.photometric == TIFF_RGB) && ((t.samples == 3) || (t.samples == 4)):
+	default:
+		return nil, ErrUnsupportedTIFF
+	}
+
+	if _, ok := tags[TIFF_TILE_WIDTH]; ok {
+		t.Tiled = true
+		t.blockWidth = int(single(TIFF_TILE_WIDTH, 0))
+		t.blockHeight = int(single(TIFF_TILE_LENGTH, 0))
+		t.offsets = tags[TIFF_TILE_OFFSETS]
+		t.byteCounts = tags[TIFF_TILE_BYTE_COUNTS]
+	} else {
+		t.blockWidth = t.Width
+		t.blockHeight = minInt(t.Height, int(single(TIFF_ROWS_PER_STRIP, uint64(t.Height))))
+		t.offsets = tags[TIFF_STRIP_OFFSETS]
+		t.byteCounts = tags[TIFF_STRIP_BYTE_COUNTS]
+	}
+	if (t.blockWidth <= 0) || (t.blockHeight <= 0) || (uint64(t.blockWidth)*uint64(t.blockHeight)*uint64(t.samples) > TIFF_MAX_BLOCK_BYTES) {
+		return nil, fmt.Errorf("invalid TIFF block size %dx%d", t.blockWidth, t.blockHeight)
+	}
+	blocks := t.blocksAcross() * t.blocksDown()
+	if (len(t.offsets) != blocks) || (len(t.byteCounts) != blocks) {
+		return nil, fmt.Errorf("TIFF has %d offsets and %d byte counts for %d blocks", len(t.offsets), len(t.byteCounts), blocks)
+	}
+
+	return t, nil
+}
+
+// readTIFFDirectory reads the entries of the image file directory at offset
+// as the values of every tag. Only integer values are read, other types are
+// skipped.
+func readTIFFDirectory(r io.ReaderAt, order binary.ByteOrder, offset int64) (map[uint16][]uint64, error) {
+	count := make([]byte, 2)
+	if _, err := r.ReadAt(count, offset); err != nil {
+		return nil, err
+	}
+	entries := int(order.Uint16(count))
+	if entries > TIFF_MAX_IFD_ENTRIES {
+		return nil, fmt.Errorf("TIFF directory with %d entries", entries)
+	}
+	data := make([]byte, 12*entries)
+	if _, err := r.ReadAt(data, offset+2); err != nil {
+		return nil, err
+	}
+
+	tags := make(map[uint16][]uint64)
+	for i := 0; i < entries; i++ {
+		entry := data[12*i : 12*(i+1)]
+		tag := order.Uint16(entry)
+		n := uint64(order.Uint32(entry[4:]))
+		var size uint64
+		switch order.Uint16(entry[2:]) {
+		case 1:
+			size = 1
+		case 3:
+			size = 2
+		case 4:
+			size = 4
+		default:
+			continue
+		}
+		if n > TIFF_MAX_TAG_VALUES {
+			return nil, fmt.Errorf("TIFF tag %d with %d values", tag, n)
+		}
+
+		raw := entry[8:12]
+		if n*size > 4 {
+			raw = make([]byte, n*size)
+			if _, err := r.ReadAt(raw, int64(order.Uint32(entry[8:]))); err != nil {
+				return nil, err
+			}
+		}
+		values := make([]uint64, n)
+		for j := range values {
+			switch size {
+			case 1:
+				values[j] = uint64(raw[j])
+			case 2:
+				values[j] = uint64(order.Uint16(raw[2*j:]))
+			case 4:
+				values[j] = uint64(order.Uint32(raw[4*j:]))
+			}
+		}
+		tags[tag] = values
+	}
+
+	return tags, nil
+}
+
+func (t *TIFF) blocksAcross() int {
+	return (t.Width + t.blockWidth - 1) / t.blockWidth
+}
+
+func (t *TIFF) blocksDown() int {
+	return (t.Height + t.blockHeight - 1) / t.blockHeight
+}
+
+// ReadRows returns the rows [minY, maxY) of the image as gray values, reading
+// only the strips or tiles they intersect. Gray values are converted like
+// ImageToPixelArray converts the decoded image. It can be passed to
+// DetectBands as a BandReader.
+func (t *TIFF) ReadRows(minY, maxY int) ([][]GrayPixel, error) {
+	if (minY < 0) || (maxY > t.Height) || (minY >= maxY) {
+		return nil, fmt.Errorf("rows [%d, %d) out of image height %d", minY, maxY, t.Height)
+	}
+	rows := make([][]GrayPixel, maxY-minY)
+	for y := range rows {
+		rows[y] = make([]GrayPixel, t.Width)
+	}
+
+	block := make([]byte, t.blockWidth*t.blockHeight*t.samples)
+	for by := minY / t.blockHeight; by <= (maxY-1)/t.blockHeight; by++ {
+		blockMinY := by * t.blockHeight
+		blockRows := minInt(t.blockHeight, t.Height-blockMinY)
+		if t.Tiled {
+			// Tiles are padded to full size at the image border.
+			blockRows = t.blockHeight
+		}
+		for bx := 0; bx < t.blocksAcross(); bx++ {
+			i := by*t.blocksAcross() + bx
+			size := uint64(t.blockWidth * blockRows * t.samples)
+			if t.byteCounts[i] < size {
+				return nil, fmt.Errorf("TIFF block %d has %d bytes, want %d", i, t.byteCounts[i], size)
+			}
+			if _, err := t.r.ReadAt(block[:size], int64(t.offsets[i])); err != nil {
+				return nil, err
+			}
+
+			blockMinX := bx * t.blockWidth
+			for y := maxInt(minY, blockMinY); y < minInt(maxY, blockMinY+blockRows); y++ {
+				for x := blockMinX; x < minInt(t.Width, blockMinX+t.blockWidth); x++ {
+					offset := ((y-blockMinY)*t.blockWidth + (x - blockMinX)) * t.samples
+					rows[y-minY][x] = t.grayPixel(block[offset : offset+t.samples])
+				}
+			}
+		}
+	}
+
+	return rows, nil
+}
+
+// grayPixel converts the samples of a pixel to a gray value.
+func (t *TIFF) grayPixel(samples []byte) GrayPixel {
+	switch {
+	case t.photometric == TIFF_WHITE_IS_ZERO:
+		return GrayPixel{255 - samples[0], 255}
+	case t.samples == 1:
+		return GrayPixel{samples[0], 255}
+	case t.samples == 3:
+		return rgbaToGrayPixel(color.RGBA{samples[0], samples[1], samples[2], 255})
+	case t.associated:
+		return rgbaToGrayPixel(color.RGBA{samples[0], samples[1], samples[2], samples[3]})
+	default:
+		return rgbaToGrayPixel(color.NRGBA{samples[0], samples[1], samples[2], samples[3]})
+	}
+}
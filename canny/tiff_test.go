@@ -0,0 +1,199 @@
+package canny
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// encodeTIFF encodes img as an uncompressed TIFF in blocks of the given
+// size, tiles if tiled is set and strips of blockHeight rows otherwise. RGB
+// samples are written if rgb is set and the gray value otherwise.
+func encodeTIFF(img image.Image, tiled, rgb bool, blockWidth, blockHeight int, order binary.ByteOrder) []byte {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	samples := 1
+	if rgb {
+		samples = 3
+	}
+	if !tiled {
+		blockWidth = width
+	}
+
+	var data bytes.Buffer
+	var offsets, counts []uint32
+	for by := 0; by < height; by += blockHeight {
+		for bx := 0; bx < width; bx += blockWidth {
+			offsets = append(offsets, uint32(8+data.Len()))
+			rows := blockHeight
+			if !tiled && (by+rows > height) {
+				rows = height - by
+			}
+			for y := by; y < by+rows; y++ {
+				for x := bx; x < bx+blockWidth; x++ {
+					var c color.RGBA
+					if (x < width) && (y < height) {
+						c = color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+					}
+					if rgb {
+						data.Write([]byte{c.R, c.G, c.B})
+					} else {
+						data.WriteByte(c.R)
+					}
+				}
+			}
+			counts = append(counts, uint32(rows*blockWidth*samples))
+		}
+	}
+
+	type entry struct {
+		tag, typ uint16
+		values   []uint32
+	}
+	photometric := uint32(TIFF_BLACK_IS_ZERO)
+	bits := []uint32{8}
+	if rgb {
+		photometric = TIFF_RGB
+		bits = []uint32{8, 8, 8}
+	}
+	entries := []entry{
+		{TIFF_IMAGE_WIDTH, 4, []uint32{uint32(width)}},
+		{TIFF_IMAGE_LENGTH, 4, []uint32{uint32(height)}},
+		{TIFF_BITS_PER_SAMPLE, 3, bits},
+		{TIFF_COMPRESSION, 3, []uint32{TIFF_UNCOMPRESSED}},
+		{TIFF_PHOTOMETRIC, 3, []uint32{photometric}},
+		{TIFF_SAMPLES_PER_PIXEL, 3, []uint32{uint32(samples)}},
+	}
+	if tiled {
+		entries = append(entries,
+			entry{TIFF_TILE_WIDTH, 3, []uint32{uint32(blockWidth)}},
+			entry{TIFF_TILE_LENGTH, 3, []uint32{uint32(blockHeight)}},
+			entry{TIFF_TILE_OFFSETS, 4, offsets},
+			entry{TIFF_TILE_BYTE_COUNTS, 4, counts})
+	} else {
+		entries = append(entries,
+			entry{TIFF_STRIP_OFFSETS, 4, offsets},
+			entry{TIFF_ROWS_PER_STRIP, 3, []uint32{uint32(blockHeight)}},
+			entry{TIFF_STRIP_BYTE_COUNTS, 4, counts})
+	}
+
+	var out bytes.Buffer
+	if order == binary.LittleEndian {
+		out.WriteString(TIFF_LITTLE_ENDIAN_MARK)
+	} else {
+		out.WriteString(TIFF_BIG_ENDIAN_MARK)
+	}
+	ifd := uint32(8 + data.Len())
+	binary.Write(&out, order, ifd)
+	out.Write(data.Bytes())
+
+	// Values that don't fit into an entry follow the directory.
+	extra := ifd + 2 + uint32(12*len(entries)) + 4
+	var values bytes.Buffer
+	binary.Write(&out, order, uint16(len(entries)))
+	for _, e := range entries {
+		binary.Write(&out, order, e.tag)
+		binary.Write(&out, order, e.typ)
+		binary.Write(&out, order, uint32(len(e.values)))
+		var raw bytes.Buffer
+		for _, v := range e.values {
+			if e.typ == 3 {
+				binary.Write(&raw, order, uint16(v))
+			} else {
+				binary.Write(&raw, order, v)
+			}
+		}
+		if raw.Len() <= 4 {
+			out.Write(append(raw.Bytes(), make([]byte, 4-raw.Len())...))
+		} else {
+			binary.Write(&out, order, extra+uint32(values.Len()))
+			values.Write(raw.Bytes())
+		}
+	}
+	binary.Write(&out, order, uint32(0))
+	out.Write(values.Bytes())
+
+	return out.Bytes()
+}
+
+// testColorImage returns testImage with a different hue on each side.
+func testColorImage() *image.RGBA {
+	pixels := testImage()
+	img := image.NewRGBA(image.Rect(0, 0, len(pixels[0]), len(pixels)))
+	for y := range pixels {
+		for x, p := range pixels[y] {
+			img.Set(x, y, color.RGBA{p.Y, uint8(x * 5), 255 - p.Y/2, 255})
+		}
+	}
+	return img
+}
+
+func TestTIFFMatchesFullDecode(t *testing.T) {
+	img := testColorImage()
+	gray := image.NewGray(img.Bounds())
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			gray.Set(x, y, color.Gray{img.RGBAAt(x, y).R})
+		}
+	}
+	opts := Options{Blur: true, MinRatio: 0.2, MaxRatio: 0.6}
+
+	cases := []struct {
+		name                    string
+		img                     image.Image
+		tiled, rgb              bool
+		blockWidth, blockHeight int
+		order                   binary.ByteOrder
+	}{
+		{"gray strips", gray, false, false, 0, 4, binary.LittleEndian},
+		{"gray tiles", gray, true, false, 16, 16, binary.BigEndian},
+		{"rgb strips", img, false, true, 0, 7, binary.BigEndian},
+		{"rgb tiles", img, true, true, 16, 8, binary.LittleEndian},
+	}
+
+	for _, c := range cases {
+		want := ImageToPixelArray(c.img)
+		tiff, err := OpenTIFF(bytes.NewReader(encodeTIFF(c.img, c.tiled, c.rgb, c.blockWidth, c.blockHeight, c.order)))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if tiff.Tiled != c.tiled {
+			t.Errorf("%s: tiled is %t", c.name, tiff.Tiled)
+		}
+		got, err := tiff.ReadRows(0, tiff.Height)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if !equalPixels(got, want) {
+			t.Errorf("%s: pixels differ from the decoded image", c.name)
+		}
+
+		wantEdges, err := CannyEdgeDetectContext(context.Background(), want, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotEdges, err := DetectBands(context.Background(), tiff.Width, tiff.Height, 6, tiff.ReadRows, opts)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if !equalPixels(gotEdges, wantEdges) {
+			t.Errorf("%s: edges differ from a full decode", c.name)
+		}
+	}
+}
+
+func TestOpenTIFFRejectsCompressed(t *testing.T) {
+	data := encodeTIFF(testColorImage(), false, false, 0, 4, binary.LittleEndian)
+	if _, err := OpenTIFF(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Patch the compression entry, the fourth one, to LZW.
+	ifd := binary.LittleEndian.Uint32(data[4:])
+	binary.LittleEndian.PutUint16(data[ifd+2+3*12+8:], 5)
+	if _, err := OpenTIFF(bytes.NewReader(data)); err != ErrUnsupportedTIFF {
+		t.Errorf("got %v, want ErrUnsupportedTIFF", err)
+	}
+}
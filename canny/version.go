@@ -0,0 +1,22 @@
+package canny
+
+// version, commit and date describe the build. They are set at link time, e.g.
+//
+//	go build -ldflags "-X github.com/chfanghr/canny-go/canny.version=1.2.0 -X github.com/chfanghr/canny-go/canny.commit=$(git rev-parse HEAD) -X github.com/chfanghr/canny-go/canny.date=$(date -u +%F)"
+var (
+	version = "dev"
+	commit  = "unknown"
+	date    = "unknown"
+)
+
+// Version returns the version the edge detector was built as, "dev" if none
+// was set.
+func Version() string {
+	return version
+}
+
+// BuildInfo returns the commit and the date the edge detector was built from,
+// "unknown" if they weren't set.
+func BuildInfo() (string, string) {
+	return commit, date
+}
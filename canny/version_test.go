@@ -0,0 +1,22 @@
+package canny
+
+import (
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	if (Version() != "dev") || (commit != "unknown") || (date != "unknown") {
+		t.Errorf("got %s, %s and %s without link time values", Version(), commit, date)
+	}
+
+	// -X sets the variables before the program starts, like the assignments
+	// below.
+	defer func(v, c, d string) { version, commit, date = v, c, d }(version, commit, date)
+	version, commit, date = "1.2.0", "abc123", "2020-01-02"
+	if Version() != "1.2.0" {
+		t.Errorf("got version %s, want 1.2.0", Version())
+	}
+	if c, d := BuildInfo(); (c != "abc123") || (d != "2020-01-02") {
+		t.Errorf("got build info %s and %s, want abc123 and 2020-01-02", c, d)
+	}
+}
@@ -0,0 +1,68 @@
+package canny
+
+import (
+	"context"
+)
+
+// RotationVote detects the edges of pixels and of its rotations by 90, 180
+// and 270 degrees, rotates the results back and keeps every pixel that is an
+// edge in at least minVotes of the four runs. This evens out the directional
+// asymmetry of the discrete gradient operators.
+func RotationVote(ctx context.Context, pixels [][]GrayPixel, opts Options, minVotes int) ([][]GrayPixel, error) {
+	if err := checkRectangular(pixels); err != nil {
+		return nil, err
+	}
+	height := len(pixels)
+	width := len(pixels[0])
+	votes := make([][]int, height)
+	for y := range votes {
+		votes[y] = make([]int, width)
+	}
+
+	rotated := pixels
+	for turns := 0; turns < 4; turns++ {
+		edges, err := CannyEdgeDetectContext(ctx, rotated, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < (4-turns)%4; i++ {
+			edges = rotate90(edges)
+		}
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				if edges[y][x].Y != 0 {
+					votes[y][x]++
+				}
+			}
+		}
+		rotated = rotate90(rotated)
+	}
+
+	result := make([][]GrayPixel, height)
+	for y := 0; y < height; y++ {
+		result[y] = make([]GrayPixel, width)
+		for x := 0; x < width; x++ {
+			result[y][x] = GrayPixel{uint8(0), uint8(255)}
+			if votes[y][x] >= minVotes {
+				result[y][x].Y = uint8(255)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// rotate90 rotates pixels clockwise by 90 degrees.
+func rotate90(pixels [][]GrayPixel) [][]GrayPixel {
+	height := len(pixels)
+	width := len(pixels[0])
+	result := make([][]GrayPixel, width)
+	for y := 0; y < width; y++ {
+		result[y] = make([]GrayPixel, height)
+		for x := 0; x < height; x++ {
+			result[y][x] = pixels[height-1-x][y]
+		}
+	}
+
+	return result
+}
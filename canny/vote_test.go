@@ -0,0 +1,61 @@
+package canny
+
+import (
+	"context"
+	"testing"
+)
+
+func countEdges(pixels [][]GrayPixel) int {
+	count := 0
+	for y := range pixels {
+		for x := range pixels[y] {
+			if pixels[y][x].Y != 0 {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func TestRotate90(t *testing.T) {
+	pixels := noisePixels(5, 3, 1)
+	rotated := rotate90(pixels)
+	if (len(rotated) != 5) || (len(rotated[0]) != 3) {
+		t.Fatalf("got %dx%d, want 3x5", len(rotated[0]), len(rotated))
+	}
+	// The bottom left corner moves to the top left.
+	if rotated[0][0] != pixels[2][0] {
+		t.Errorf("got %v at the top left, want %v", rotated[0][0], pixels[2][0])
+	}
+	if !equalPixels(rotate90(rotate90(rotate90(rotated))), pixels) {
+		t.Error("four rotations should give the input back")
+	}
+}
+
+func TestRotationVoteCompletesDiagonal(t *testing.T) {
+	line := newPixels(48, 48, func(x, y int) uint8 {
+		if x == y {
+			return 200
+		}
+		return 20
+	})
+	opts := Options{Blur: true, MinRatio: 0.1, MaxRatio: 0.3, NMSTieBreak: true}
+	plain, err := CannyEdgeDetectContext(context.Background(), line, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	voted, err := RotationVote(context.Background(), line, opts, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := range voted {
+		for x := range voted[y] {
+			if (voted[y][x].Y != 0) && ((x-y < -3) || (x-y > 4)) {
+				t.Errorf("unexpected edge at (%d, %d) away from the line", x, y)
+			}
+		}
+	}
+	if countEdges(voted) <= countEdges(plain) {
+		t.Errorf("got %d edge pixels with voting, %d without", countEdges(voted), countEdges(plain))
+	}
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNegativeDensityFlag(t *testing.T) {
+	out := runMain(t, nil, "-input", "in.png", "-density", "-2")
+	if !strings.Contains(string(out), "Invalid density block size") {
+		t.Errorf("got %q, want the invalid density message", out)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEdgeEnergyFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-edge-energy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "in.png")
+	writePNG(t, inputPath, circleImage(32, 32))
+	out := string(runMain(t, nil, "-input", inputPath, "-output", filepath.Join(dir, "out.png"), "-edge-energy"))
+	i := strings.Index(out, "Edge energy: ")
+	if i < 0 {
+		t.Fatalf("got output %q", out)
+	}
+	line := strings.SplitN(out[i+len("Edge energy: "):], "\n", 2)[0]
+	value, err := strconv.ParseFloat(line, 64)
+	if (err != nil) || (value <= 0) || math.IsNaN(value) {
+		t.Errorf("got energy %q", line)
+	}
+}
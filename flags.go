@@ -0,0 +1,273 @@
+package main
+
+import (
+	"flag"
+	"time"
+)
+
+// inputFlags holds the flags naming the inputs and outputs and how they are
+// read and written.
+type inputFlags struct {
+	input            *string
+	output           *string
+	inputBase64      *string
+	inputNMS         *string
+	force            *bool
+	recursive        *bool
+	outputPattern    *string
+	bandHeight       *int
+	maxPixels        *int64
+	noProgressive    *bool
+	maxProgressive   *int64
+	stripMetadata    *bool
+	preserveMetadata *bool
+	jpegGray         *bool
+	timeout          *time.Duration
+	version          *bool
+}
+
+// registerInputFlags registers the input and output flags on flags.
+func registerInputFlags(flags *flag.FlagSet) *inputFlags {
+	return &inputFlags{
+		input:            flags.String("input", "", "path to input file, directory of input files or tar archive of input files, - for stdin (required)"),
+		output:           flags.String("output", "out.jpg", "path to output file, output directory if the input is a directory or output tar archive if the input is one, - for stdout (optional, default: out.jpg, out for directories or out.tar for archives)"),
+		inputBase64:      flags.String("input-base64", "", "base64 encoded input image, used instead of -input (optional)"),
+		inputNMS:         flags.String("input-nms", "", "resume from non-maximum suppression on the gradients written by -dump-gradients, skipping the decoding, blur and gradients, used instead of -input (optional)"),
+		force:            flags.Bool("force", false, "reprocess inputs whose output is already up to date in directory mode (optional)"),
+		recursive:        flags.Bool("recursive", false, "process subdirectories in directory mode, mirroring them under the output directory (optional)"),
+		outputPattern:    flags.String("output-pattern", "", "name of every output in directory and archive mode, with {name}, {ext} and {dir} replaced by the name without extension, the extension and the directory of the input, e.g. {dir}/{name}_edges.png (optional, default: the input name)"),
+		bandHeight:       flags.Int("band-height", 256, "rows per band of TIFF inputs, which are read strip by strip or tile by tile instead of being decoded as a whole and only written as an edge map (optional, default: 256)"),
+		maxPixels:        flags.Int64("max-pixels", 0, "reject input images declaring more than n pixels before decoding them (optional)"),
+		noProgressive:    flags.Bool("no-progressive", false, "reject progressive JPEG inputs before decoding them, they are slower to decode than baseline JPEGs (optional)"),
+		maxProgressive:   flags.Int64("progressive-max-pixels", 0, "reject progressive JPEG inputs declaring more than n pixels before decoding them, a tighter -max-pixels for progressive JPEGs (optional)"),
+		stripMetadata:    flags.Bool("strip-metadata", true, "do not copy EXIF and text metadata of the input to the output (optional, default: true)"),
+		preserveMetadata: flags.Bool("preserve-metadata", false, "copy EXIF and text metadata of the input to the output, overrides -strip-metadata (optional)"),
+		jpegGray:         flags.Bool("jpeg-gray", false, "encode color JPEG outputs such as overlays as grayscale without chroma (optional)"),
+		timeout:          flags.Duration("timeout", 0, "abort edge detection after the given duration, e.g. 30s (optional)"),
+		version:          flags.Bool("version", false, "print the version and exit (optional)"),
+	}
+}
+
+// detectionFlags holds the flags that make up the canny.Options of the
+// detection.
+type detectionFlags struct {
+	blur               *bool
+	min                *float64
+	max                *float64
+	operator           *string
+	maxBorder          *int
+	magFloor           *uint
+	percentile         *float64
+	refMax             *float64
+	angleRange         *string
+	radial             *string
+	nmsTieBreak        *bool
+	nmsTolerance       *uint
+	nms                *string
+	binTies            *string
+	norm               *string
+	round              *string
+	parallelHysteresis *bool
+	workers            *int
+	deterministic      *bool
+	strict             *bool
+	seedPoints         *string
+	linearBlur         *bool
+	thinning           *string
+	borderValid        *bool
+	preErode           *int
+}
+
+// registerDetectionFlags registers the detection flags on flags.
+func registerDetectionFlags(flags *flag.FlagSet) *detectionFlags {
+	return &detectionFlags{
+		blur:               flags.Bool("blur", true, "perform gaussian blur before edge detection (optional, default: true)"),
+		min:                flags.Float64("min", float64(0.2), "ratio of lower threshold (optional, default: 0.2"),
+		max:                flags.Float64("max", float64(0.6), "ratio of upper threshold (optional, default: 0.6"),
+		operator:           flags.String("operator", "sobel", "gradient operator, one of sobel, scharr, prewitt, central or gd5, the 5x5 derivative of a Gaussian (optional, default: sobel)"),
+		maxBorder:          flags.Int("max-border", 0, "ignore a frame of n pixels at the border when scaling the thresholds (optional)"),
+		magFloor:           flags.Uint("mag-floor", 0, "zero gradient magnitudes below the given value before non-maximum suppression (optional)"),
+		percentile:         flags.Float64("percentile", float64(0), "set the upper threshold to the given percentile of gradient magnitudes instead of -max, the lower one keeps the -min/-max proportion (optional)"),
+		refMax:             flags.Float64("ref-max", float64(0), "scale the thresholds by this fixed magnitude instead of the maximum magnitude of each image (optional)"),
+		angleRange:         flags.String("angle-range", "", "keep only edges whose gradient orientation lies in lo,hi degrees of [0, 180), 90 for horizontal edges, wrapping around 0 if lo > hi (optional)"),
+		radial:             flags.String("radial", "", "emphasize circular edges by projecting the gradient onto the radii from the center cx,cy (optional)"),
+		nmsTieBreak:        flags.Bool("nms-tie-break", false, "keep only the first of equal neighbours during non-maximum suppression (optional)"),
+		nmsTolerance:       flags.Uint("nms-tolerance", 0, "magnitude difference up to which neighbours count as equal for -nms-tie-break (optional)"),
+		nms:                flags.String("nms", "discrete", "interpolation of the neighbour magnitudes in non-maximum suppression, discrete, linear or cubic (optional, default: discrete)"),
+		binTies:            flags.String("bin-ties", "up", "direction bin of gradient angles exactly on a bin boundary, up or down (optional, default: up)"),
+		norm:               flags.String("norm", "l2", "combination of the X and Y gradients into the magnitude, l2 or l1 (optional, default: l2)"),
+		round:              flags.String("round", "nearest", "quantization of blurred values and gradient magnitudes, nearest or trunc (optional, default: nearest)"),
+		parallelHysteresis: flags.Bool("parallel-hysteresis", false, "track edges by concurrently processing connected components (optional)"),
+		workers:            flags.Int("workers", 0, "number of goroutines the blur and -parallel-hysteresis split the image between, 0 for one per CPU (optional, default: 0)"),
+		deterministic:      flags.Bool("deterministic", false, "use an integer blur and gradient that give bit-identical results on every platform (optional)"),
+		strict:             flags.Bool("strict", false, "report internal invariant violations with their dimensions or coordinates as errors instead of panicking (optional)"),
+		seedPoints:         flags.String("seed-points", "", "semicolon separated x,y points treated as strong edges by the hysteresis regardless of their magnitude, e.g. 10,20;30,40 (optional)"),
+		linearBlur:         flags.Bool("linear-blur", false, "blur in linear light instead of on the gamma encoded gray values, the gradients and thresholds still work on gamma encoded values (optional)"),
+		thinning:           flags.String("thinning", "nms", "thinning of the edges, nms for non-maximum suppression, skeleton for a one pixel wide Zhang-Suen skeleton of the thresholded edges or none (optional, default: nms)"),
+		borderValid:        flags.Bool("border-valid", false, "only let interior pixels whose gradient kernel lies fully inside the image become edges (optional)"),
+		preErode:           flags.Int("pre-erode", 0, "erode the gradient magnitudes above the low threshold n times before non-maximum suppression, pruning thin responses at the cost of sensitivity, not for TIFF inputs (optional)"),
+	}
+}
+
+// preprocessFlags holds the flags that transform the input before detection
+// or run the detection on several versions of it.
+type preprocessFlags struct {
+	diff           *string
+	normalize      *bool
+	normalizeMean  *float64
+	normalizeStd   *float64
+	flatten        *int
+	lut            *string
+	windowLevel    *string
+	channelCombine *string
+	withBinary     *bool
+	rotate         *float64
+	deskew         *bool
+	supersample    *int
+	fastScale      *float64
+	rotationVote   *int
+}
+
+// registerPreprocessFlags registers the preprocessing flags on flags.
+func registerPreprocessFlags(flags *flag.FlagSet) *preprocessFlags {
+	return &preprocessFlags{
+		diff:           flags.String("diff", "", "path to image subtracted from input before detection (optional)"),
+		normalize:      flags.Bool("normalize-input", false, "standardize the input brightness to -normalize-mean and -normalize-std before detection (optional)"),
+		normalizeMean:  flags.Float64("normalize-mean", float64(128), "mean gray value of normalized input (optional, default: 128)"),
+		normalizeStd:   flags.Float64("normalize-std", float64(48), "standard deviation of normalized input (optional, default: 48)"),
+		flatten:        flags.Int("flatten", 0, "subtract a box blur of the given radius to flatten uneven illumination before detection (optional)"),
+		lut:            flags.String("lut", "", "remap the grayscale input through a 256-byte lookup table file before detection (optional)"),
+		windowLevel:    flags.String("window-level", "", "map the 16-bit luma of 16-bit inputs to gray values with window,level in 16-bit units instead of scaling the full range, e.g. 4096,2048 (optional)"),
+		channelCombine: flags.String("channel-combine", "", "detect the edges of the red, green and blue channels separately and merge them with max, and, or or sum (optional)"),
+		withBinary:     flags.Bool("with-binary", false, "combine the edges with the dark regions of an Otsu binarization of the input, e.g. text (optional)"),
+		rotate:         flags.Float64("rotate", float64(0), "rotate the input counterclockwise by the given angle in degrees before detection (optional, default: 0)"),
+		deskew:         flags.Bool("deskew", false, "rotate the input so that its dominant edges align with the image axes before detection (optional)"),
+		supersample:    flags.Int("supersample", 1, "detect on the input upscaled by the given factor and downsample the edges, reducing staircase artifacts at factor² the cost (optional, default: 1)"),
+		fastScale:      flags.Float64("fast-scale", float64(1), "detect on the input downscaled by the given factor in (0, 1] and upsample the edges back to full resolution (optional, default: 1)"),
+		rotationVote:   flags.Int("rotation-vote", 0, "detect on the input and its 90, 180 and 270 degree rotations and keep edges found in at least n of the 4 runs (optional)"),
+	}
+}
+
+// postprocessFlags holds the flags that filter the detected edges.
+type postprocessFlags struct {
+	keepLargest       *bool
+	minEdgeLength     *int
+	removeIsolated    *bool
+	subtractEdges     *string
+	subtractTolerance *int
+}
+
+// registerPostprocessFlags registers the postprocessing flags on flags.
+func registerPostprocessFlags(flags *flag.FlagSet) *postprocessFlags {
+	return &postprocessFlags{
+		keepLargest:       flags.Bool("keep-largest", false, "keep only the largest connected edge component (optional)"),
+		minEdgeLength:     flags.Int("min-edge-length", 0, "discard traced edges shorter than n pixels (optional, default: 0)"),
+		removeIsolated:    flags.Bool("remove-isolated", false, "remove edge pixels without any neighbouring edge pixel (optional)"),
+		subtractEdges:     flags.String("subtract-edges", "", "path to a reference edge map, edges within -subtract-tolerance of a reference edge are removed, leaving the anomalies (optional)"),
+		subtractTolerance: flags.Int("subtract-tolerance", 1, "distance in pixels up to which an edge matches a reference edge for -subtract-edges (optional, default: 1)"),
+	}
+}
+
+// renderFlags holds the flags that control how the edges are drawn, or that
+// write another image instead of the edge map.
+type renderFlags struct {
+	quiver          *int
+	quiverScale     *float64
+	compare         *string
+	density         *int
+	operatorGrid    *bool
+	scaleMap        *bool
+	levels          *int
+	confidence      *bool
+	dualOutput      *bool
+	paletteEdges    *bool
+	componentBounds *bool
+	transparent     *string
+	edgeColor       *string
+	edgeDarken      *float64
+	blend           *float64
+	dim             *float64
+	grid            *int
+	scalebar        *string
+	ascii           *bool
+	asciiWidth      *int
+	csvMagnitude    *bool
+	geoTransform    *string
+}
+
+// registerRenderFlags registers the rendering flags on flags.
+func registerRenderFlags(flags *flag.FlagSet) *renderFlags {
+	return &renderFlags{
+		quiver:          flags.Int("quiver", 0, "output the gradient field sampled every n pixels instead of edges (optional)"),
+		quiverScale:     flags.Float64("quiver-scale", float64(1), "length of the longest gradient vector in grid steps (optional, default: 1)"),
+		compare:         flags.String("compare", "", "overlay the edges of several comma separated min:max threshold pairs in different colors, e.g. 0.1:0.3,0.2:0.6 (optional)"),
+		density:         flags.Int("density", 0, "output the fraction of edge pixels per block of the given size instead of the edges (optional)"),
+		operatorGrid:    flags.Bool("operator-grid", false, "output the edges of the sobel, scharr and prewitt operators side by side (optional)"),
+		scaleMap:        flags.Bool("scale-map", false, "write how many blur scales of 1, 2, 4 and 8 sigmas every edge persists across as gray values (optional)"),
+		levels:          flags.Int("levels", 0, "write the edges of n increasing threshold levels as brightness layers, the strongest edges brightest (optional)"),
+		confidence:      flags.Bool("confidence", false, "write the confidence of every edge pixel as gray values, strong pixels brightest and promoted weak pixels dimmed by their distance to the nearest strong pixel (optional)"),
+		dualOutput:      flags.Bool("dual-output", false, "write the gradient magnitude to the red and the edges to the green channel of one image (optional)"),
+		paletteEdges:    flags.Bool("palette-edges", false, "render every connected edge component in its own color (optional)"),
+		componentBounds: flags.Bool("component-bounds", false, "outline the bounding box of every connected edge component (optional)"),
+		transparent:     flags.String("transparent", "", "write the edges in the given RRGGBB color on a transparent background as PNG (optional)"),
+		edgeColor:       flags.String("edge-color", "", "draw the edges on black in the given RRGGBB color, or in the color of the input image with \"source\" (optional)"),
+		edgeDarken:      flags.Float64("edge-darken", float64(0), "darken source colored edges by the given fraction in [0, 1] (optional, default: 0)"),
+		blend:           flags.Float64("blend", float64(0), "blend the edges in the -edge-color color, red by default, over the original image at the given opacity in (0, 1] (optional)"),
+		dim:             flags.Float64("dim", float64(0), "darken the original image behind blended edges by the given fraction in [0, 1] (optional, default: 0)"),
+		grid:            flags.Int("grid", 0, "draw a measurement grid with the given spacing in pixels over the output (optional)"),
+		scalebar:        flags.String("scalebar", "", "draw a scale bar of the given length in pixels and label over the output, e.g. 50,10um (optional)"),
+		ascii:           flags.Bool("ascii", false, "print the edges as ASCII art instead of writing an output (optional)"),
+		asciiWidth:      flags.Int("ascii-width", 80, "width of the ASCII art in characters (optional, default: 80)"),
+		csvMagnitude:    flags.Bool("csv-magnitude", false, "add the magnitude as a third column to .csv outputs (optional)"),
+		geoTransform:    flags.String("geotransform", "", "six comma separated coefficients of the affine pixel to world transform for .geojson output (optional)"),
+	}
+}
+
+// reportFlags holds the flags that print measurements or write side outputs
+// next to the edge map.
+type reportFlags struct {
+	dominantOrientation *bool
+	dumpThresholds      *string
+	subpixel            *string
+	dumpRecall          *string
+	dumpGradients       *string
+	dumpNMS             *string
+	primitives          *string
+	edgeEnergy          *bool
+}
+
+// registerReportFlags registers the report flags on flags.
+func registerReportFlags(flags *flag.FlagSet) *reportFlags {
+	return &reportFlags{
+		dominantOrientation: flags.Bool("dominant-orientation", false, "print the dominant edge orientation in degrees (optional)"),
+		dumpThresholds:      flags.String("dump-thresholds", "", "write the strong and weak threshold maps to <prefix>_strong.png and <prefix>_weak.png (optional)"),
+		subpixel:            flags.String("subpixel", "", "write sub-pixel edge positions as CSV to the given path (optional)"),
+		dumpRecall:          flags.String("dump-recall", "", "write the strong-only and the hysteresis edges to <prefix>_strong_only.png and <prefix>_hysteresis.png and print how many pixels hysteresis recovered (optional)"),
+		dumpGradients:       flags.String("dump-gradients", "", "write the gradient directions and magnitudes as raw little-endian float64 arrays with a 16 byte header to the given path (optional)"),
+		dumpNMS:             flags.String("dump-nms", "", "write the magnitudes after non-maximum suppression, before thresholding, as a raw little-endian float64 array with a 16 byte header to the given path (optional)"),
+		primitives:          flags.String("primitives", "", "write the contours and the bounding boxes of the connected components of the edges as one JSON document to the given path (optional)"),
+		edgeEnergy:          flags.Bool("edge-energy", false, "print the sum of the gradient magnitudes at the edge pixels divided by the pixel count, low for blank or blurry images (optional)"),
+	}
+}
+
+// profilingFlags holds the flags that profile or benchmark the detection.
+type profilingFlags struct {
+	profile       *bool
+	profileOutput *string
+	cpuProfile    *string
+	memProfile    *string
+	benchmark     *int
+	memReport     *bool
+}
+
+// registerProfilingFlags registers the profiling flags on flags.
+func registerProfilingFlags(flags *flag.FlagSet) *profilingFlags {
+	return &profilingFlags{
+		profile:       flags.Bool("profile", false, "do cpu/mem profile on the main logic"),
+		profileOutput: flags.String("profile-output", "", "directory to write timestamped profiles to (optional, default: current directory without timestamp)"),
+		cpuProfile:    flags.String("cpu-profile", "cpu_profile", "file name of the cpu profile (optional, default: cpu_profile)"),
+		memProfile:    flags.String("mem-profile", "mem_profile", "file name of the memory profile (optional, default: mem_profile)"),
+		benchmark:     flags.Int("benchmark", 0, "run the detection n times and report its latency and throughput instead of writing an output (optional)"),
+		memReport:     flags.Bool("mem-report", false, "print the memory of every intermediate array of the detection (optional)"),
+	}
+}
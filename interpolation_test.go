@@ -0,0 +1,26 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNMSFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-nms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "in.png")
+	writePNG(t, inputPath, circleImage(32, 32))
+	for _, mode := range []string{"discrete", "linear", "cubic", "quadratic"} {
+		out := runMain(t, nil, "-input", inputPath, "-output", filepath.Join(dir, "out.png"), "-nms", mode)
+		if strings.Contains(string(out), "Unknown non-maximum suppression") != (mode == "quadratic") {
+			t.Errorf("-nms %s: got output %q", mode, out)
+		}
+	}
+}
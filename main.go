@@ -1,146 +1,1454 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
 	"flag"
 	"fmt"
 	"image"
 	"image/color"
+	"image/draw"
 	"image/jpeg"
 	"image/png"
 	"io"
+	"io/ioutil"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
+	"runtime"
 	"runtime/pprof"
-)
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
-type GrayPixel struct {
-	y uint8
-	a uint8
-}
+	"github.com/chfanghr/canny-go/canny"
+)
 
 func main() {
+	if (len(os.Args) > 1) && (os.Args[1] == "explain") {
+		explain(os.Args[2:])
+		return
+	}
 
-	blurFlagPtr := flag.Bool("blur", true, "perform gaussian blur before edge detection (optional, default: true)")
-	inputFileArgPtr := flag.String("input", "", "path to input file (required)")
-	outputFileArgPtr := flag.String("output", "out.jpg", "path to output file (optional, default: out.jpg")
-	minThresholdArgPtr := flag.Float64("min", float64(0.2), "ratio of lower threshold (optional, default: 0.2")
-	maxThresholdArgPtr := flag.Float64("max", float64(0.6), "ratio of upper threshold (optional, default: 0.6")
-	profileFlag := flag.Bool("profile", false, "do cpu/mem profile on the main logic")
+	input := registerInputFlags(flag.CommandLine)
+	detection := registerDetectionFlags(flag.CommandLine)
+	preprocess := registerPreprocessFlags(flag.CommandLine)
+	postprocess := registerPostprocessFlags(flag.CommandLine)
+	render := registerRenderFlags(flag.CommandLine)
+	reports := registerReportFlags(flag.CommandLine)
+	profiling := registerProfilingFlags(flag.CommandLine)
 
 	flag.Parse()
 
-	if *inputFileArgPtr == "" {
-		fmt.Println("No path to input file specified, nothing to do.")
+	if *input.output == "-" {
+		infoOut = os.Stderr
+	}
+
+	if *input.version {
+		commit, date := canny.BuildInfo()
+		fmt.Printf("canny-go %s (commit %s, built %s)\n", canny.Version(), commit, date)
+		return
+	}
+
+	if (*input.input == "") && (*input.inputBase64 == "") && (*input.inputNMS == "") {
+		fmt.Fprintln(infoOut, "No path to input file specified, nothing to do.")
+		return
+	}
+
+	var inputData []byte
+	if *input.input == "-" {
+		var err error
+		inputData, err = ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else if *input.inputBase64 != "" {
+		var err error
+		inputData, err = base64.StdEncoding.DecodeString(*input.inputBase64)
+		if err != nil {
+			fmt.Fprintln(infoOut, "Invalid base64 input given, exiting.")
+			return
+		}
+	}
+
+	if !isValidRatioValue(*detection.min) || !isValidRatioValue(*detection.max) {
+		fmt.Fprintln(infoOut, "Invalid value for threshold ratio given, exiting.")
+		return
+	}
+
+	interpolation, ok := canny.NMS_INTERPOLATIONS[*detection.nms]
+	if !ok {
+		fmt.Fprintln(infoOut, "Unknown non-maximum suppression interpolation given, exiting.")
+		return
+	}
+
+	operator, ok := canny.OPERATORS[*detection.operator]
+	if !ok {
+		fmt.Fprintln(infoOut, "Unknown gradient operator given, exiting.")
+		return
+	}
+
+	if *detection.deterministic && !operator.Integer() {
+		fmt.Fprintln(infoOut, "Invalid gradient operator for deterministic detection given, exiting.")
+		return
+	}
+
+	thinning, ok := canny.THINNINGS[*detection.thinning]
+	if !ok {
+		fmt.Fprintln(infoOut, "Unknown thinning given, exiting.")
+		return
+	}
+
+	var windowLevel *[2]float64
+	if *preprocess.windowLevel != "" {
+		values, err := parseFloatList(*preprocess.windowLevel)
+		if (err != nil) || (len(values) != 2) || (values[0] <= 0) {
+			fmt.Fprintln(infoOut, "Invalid window and level given, exiting.")
+			return
+		}
+		windowLevel = &[2]float64{values[0], values[1]}
+	}
+
+	var channelCombine *canny.ChannelCombine
+	if *preprocess.channelCombine != "" {
+		combine, ok := canny.CHANNEL_COMBINES[*preprocess.channelCombine]
+		if !ok {
+			fmt.Fprintln(infoOut, "Unknown channel combination given, exiting.")
+			return
+		}
+		channelCombine = &combine
+	}
+
+	if *render.asciiWidth < 1 {
+		fmt.Fprintln(infoOut, "Invalid ASCII art width given, exiting.")
+		return
+	}
+
+	if (*preprocess.fastScale <= 0) || (*preprocess.fastScale > 1) {
+		fmt.Fprintln(infoOut, "Invalid value for fast scale given, exiting.")
+		return
+	}
+
+	if (*preprocess.fastScale < 1) && (*preprocess.supersample > 1) {
+		fmt.Fprintln(infoOut, "Fast scale and supersampling can't be combined, exiting.")
+		return
+	}
+
+	if (*detection.percentile < 0) || (*detection.percentile >= 100) {
+		fmt.Fprintln(infoOut, "Invalid value for threshold percentile given, exiting.")
+		return
+	}
+
+	if (*detection.norm != "l2") && (*detection.norm != "l1") {
+		fmt.Fprintln(infoOut, "Invalid gradient norm given, exiting.")
+		return
+	}
+
+	if (*detection.round != "nearest") && (*detection.round != "trunc") {
+		fmt.Fprintln(infoOut, "Invalid rounding mode given, exiting.")
+		return
+	}
+
+	if (*detection.binTies != "up") && (*detection.binTies != "down") {
+		fmt.Fprintln(infoOut, "Invalid direction bin tie rule given, exiting.")
+		return
+	}
+
+	if *detection.nmsTolerance > 255 {
+		fmt.Fprintln(infoOut, "Invalid value for non-maximum suppression tolerance given, exiting.")
+		return
+	}
+
+	if *render.density < 0 {
+		fmt.Fprintln(infoOut, "Invalid density block size given, exiting.")
+		return
+	}
+
+	if *detection.magFloor > 255 {
+		fmt.Fprintln(infoOut, "Invalid value for magnitude floor given, exiting.")
+		return
+	}
+
+	if *input.bandHeight <= 0 {
+		fmt.Fprintln(infoOut, "Invalid band height given, exiting.")
+		return
+	}
+
+	if *preprocess.supersample < 1 {
+		fmt.Fprintln(infoOut, "Invalid supersampling factor given, exiting.")
+		return
+	}
+
+	if (*detection.refMax < 0) || (*detection.refMax > 255) {
+		fmt.Fprintln(infoOut, "Invalid value for reference maximum given, exiting.")
+		return
+	}
+
+	if *input.maxPixels < 0 {
+		fmt.Fprintln(infoOut, "Invalid value for maximum pixel count given, exiting.")
+		return
+	}
+
+	if *input.maxProgressive < 0 {
+		fmt.Fprintln(infoOut, "Invalid value for maximum progressive JPEG pixel count given, exiting.")
+		return
+	}
+
+	if *profiling.benchmark < 0 {
+		fmt.Fprintln(infoOut, "Invalid number of benchmark runs given, exiting.")
+		return
+	}
+
+	if *detection.workers < 0 {
+		fmt.Fprintln(infoOut, "Invalid number of workers given, exiting.")
+		return
+	}
+
+	if *postprocess.minEdgeLength < 0 {
+		fmt.Fprintln(infoOut, "Invalid value for minimum edge length given, exiting.")
+		return
+	}
+
+	var transparent *color.NRGBA
+	if *render.transparent != "" {
+		c, err := parseHexColor(*render.transparent)
+		if err != nil {
+			fmt.Fprintln(infoOut, "Invalid transparent edge color given, exiting.")
+			return
+		}
+		transparent = &c
+	}
+
+	if (*render.edgeColor != "source") && (*render.edgeColor != "") {
+		if _, err := parseHexColor(*render.edgeColor); err != nil {
+			fmt.Fprintln(infoOut, "Invalid edge color given, exiting.")
+			return
+		}
+	}
+
+	if (*render.edgeDarken < 0) || (*render.edgeDarken > 1) {
+		fmt.Fprintln(infoOut, "Invalid value for edge darkening given, exiting.")
+		return
+	}
+
+	if (*render.blend < 0) || (*render.blend > 1) || ((*render.blend > 0) && (*render.edgeColor == "source")) {
+		fmt.Fprintln(infoOut, "Invalid blend opacity given, exiting.")
+		return
+	}
+
+	if (*render.dim < 0) || (*render.dim > 1) {
+		fmt.Fprintln(infoOut, "Invalid value for background dimming given, exiting.")
+		return
+	}
+
+	if (*preprocess.rotationVote < 0) || (*preprocess.rotationVote > 4) {
+		fmt.Fprintln(infoOut, "Invalid number of rotation votes given, exiting.")
+		return
+	}
+
+	if *render.levels < 0 {
+		fmt.Fprintln(infoOut, "Invalid number of threshold levels given, exiting.")
+		return
+	}
+
+	if *postprocess.subtractTolerance < 0 {
+		fmt.Fprintln(infoOut, "Invalid edge subtraction tolerance given, exiting.")
+		return
+	}
+
+	if *detection.preErode < 0 {
+		fmt.Fprintln(infoOut, "Invalid number of erosions given, exiting.")
 		return
 	}
 
-	if !isValidRatioValue(*minThresholdArgPtr) || !isValidRatioValue(*maxThresholdArgPtr) {
-		fmt.Println("Invalid value for threshold ratio given, exiting.")
+	if *render.grid < 0 {
+		fmt.Fprintln(infoOut, "Invalid grid spacing given, exiting.")
 		return
 	}
 
+	var scalebarLength int
+	var scalebarLabel string
+	if *render.scalebar != "" {
+		parts := strings.SplitN(*render.scalebar, ",", 2)
+		length, err := strconv.Atoi(parts[0])
+		if (err != nil) || (length <= 0) {
+			fmt.Fprintln(infoOut, "Invalid scale bar given, exiting.")
+			return
+		}
+		scalebarLength = length
+		if len(parts) == 2 {
+			scalebarLabel = parts[1]
+		}
+	}
+
+	var lut *[256]uint8
+	if *preprocess.lut != "" {
+		var err error
+		lut, err = readLUT(*preprocess.lut)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	image.RegisterFormat("jpeg", "jpeg", jpeg.Decode, jpeg.DecodeConfig)
 	image.RegisterFormat("png", "png", png.Decode, png.DecodeConfig)
 
-	pixels := openImage(*inputFileArgPtr)
-	if *profileFlag {
-		cpuf, err := os.Create("cpu_profile")
+	encodeOptions.Grayscale = *input.jpegGray
+	maxPixels = *input.maxPixels
+	noProgressive = *input.noProgressive
+	progressiveMaxPixels = *input.maxProgressive
+
+	opts := canny.Options{Blur: *detection.blur, MinRatio: *detection.min, MaxRatio: *detection.max, Operator: operator}
+	opts.MaxBorder = *detection.maxBorder
+	opts.MagnitudeFloor = uint8(*detection.magFloor)
+	opts.Percentile = *detection.percentile
+	opts.RefMax = *detection.refMax
+	if *detection.angleRange != "" {
+		r, err := parseFloatList(*detection.angleRange)
+		if (err != nil) || (len(r) != 2) || (r[0] < 0) || (r[0] >= 180) || (r[1] < 0) || (r[1] >= 180) {
+			fmt.Fprintln(infoOut, "Invalid angle range given, exiting.")
+			return
+		}
+		opts.AngleRange = &[2]float64{r[0], r[1]}
+	}
+	if *detection.radial != "" {
+		center, err := parseFloatList(*detection.radial)
+		if (err != nil) || (len(center) != 2) {
+			fmt.Fprintln(infoOut, "Invalid radial center given, exiting.")
+			return
+		}
+		opts.RadialCenter = &image.Point{int(center[0]), int(center[1])}
+	}
+	opts.NMSTieBreak = *detection.nmsTieBreak
+	opts.NMSTolerance = uint8(*detection.nmsTolerance)
+	opts.ParallelHysteresis = *detection.parallelHysteresis
+	opts.Workers = *detection.workers
+	opts.Deterministic = *detection.deterministic
+	opts.Strict = *detection.strict
+	opts.LinearBlur = *detection.linearBlur
+	opts.Thinning = thinning
+	if *detection.seedPoints != "" {
+		seeds, err := parsePoints(*detection.seedPoints)
 		if err != nil {
-			log.Fatal(err)
+			fmt.Fprintln(infoOut, "Invalid seed points given, exiting.")
+			return
 		}
-		_ = pprof.StartCPUProfile(cpuf)
+		opts.Seeds = seeds
+	}
+	opts.NMSInterpolation = interpolation
+	opts.BorderValid = *detection.borderValid
+	opts.PreErode = *detection.preErode
+	if *detection.binTies == "down" {
+		opts.BinRule = canny.ROUND_HALF_DOWN
+	}
+	if *detection.round == "trunc" {
+		opts.Rounding = canny.ROUND_TRUNCATE
+	}
+	if *detection.norm == "l1" {
+		opts.Norm = canny.NORM_L1
 	}
 
-	pixels = CannyEdgeDetect(pixels, *blurFlagPtr, *minThresholdArgPtr, *maxThresholdArgPtr)
+	cli := cliOptions{
+		diffFile:            *preprocess.diff,
+		flatten:             *preprocess.flatten,
+		quiverStep:          *render.quiver,
+		quiverScale:         *render.quiverScale,
+		compare:             *render.compare,
+		keepLargest:         *postprocess.keepLargest,
+		dominantOrientation: *reports.dominantOrientation,
+		geoTransform:        *render.geoTransform,
+		dumpThresholds:      *reports.dumpThresholds,
+		bandHeight:          *input.bandHeight,
+		normalize:           *preprocess.normalize,
+		normalizeMean:       *preprocess.normalizeMean,
+		normalizeStd:        *preprocess.normalizeStd,
+		preserveMetadata:    *input.preserveMetadata || !*input.stripMetadata,
+		density:             *render.density,
+		paletteEdges:        *render.paletteEdges,
+		subpixelFile:        *reports.subpixel,
+		force:               *input.force,
+		recursive:           *input.recursive,
+		outputPattern:       *input.outputPattern,
+		supersample:         *preprocess.supersample,
+		operatorGrid:        *render.operatorGrid,
+		minEdgeLength:       *postprocess.minEdgeLength,
+		lut:                 lut,
+		dumpRecall:          *reports.dumpRecall,
+		removeIsolated:      *postprocess.removeIsolated,
+		transparent:         transparent,
+		scaleMap:            *render.scaleMap,
+		inputData:           inputData,
+		benchmark:           *profiling.benchmark,
+		edgeColor:           *render.edgeColor,
+		edgeDarken:          *render.edgeDarken,
+		componentBounds:     *render.componentBounds,
+		fastScale:           *preprocess.fastScale,
+		ascii:               *render.ascii,
+		asciiWidth:          *render.asciiWidth,
+		memReport:           *profiling.memReport,
+		withBinary:          *preprocess.withBinary,
+		dumpGradients:       *reports.dumpGradients,
+		rotate:              *preprocess.rotate,
+		deskew:              *preprocess.deskew,
+		dumpNMS:             *reports.dumpNMS,
+		grid:                *render.grid,
+		scalebarLength:      scalebarLength,
+		scalebarLabel:       scalebarLabel,
+		rotationVote:        *preprocess.rotationVote,
+		csvMagnitude:        *render.csvMagnitude,
+		blend:               *render.blend,
+		dim:                 *render.dim,
+		dualOutput:          *render.dualOutput,
+		levels:              *render.levels,
+		confidence:          *render.confidence,
+		channelCombine:      channelCombine,
+		primitives:          *reports.primitives,
+		edgeEnergy:          *reports.edgeEnergy,
+		windowLevel:         windowLevel,
+		subtractEdges:       *postprocess.subtractEdges,
+		subtractTolerance:   *postprocess.subtractTolerance,
+	}
 
-	if *profileFlag {
+	startTime := time.Now()
+	var cpuf *os.File
+	if *profiling.profile {
+		if *profiling.profileOutput != "" {
+			if err := os.MkdirAll(*profiling.profileOutput, 0755); err != nil {
+				log.Fatal(err)
+			}
+		}
+		var err error
+		cpuf, err = os.Create(profilePath(*profiling.profileOutput, *profiling.cpuProfile, startTime))
+		if err != nil {
+			log.Fatal("could not create cpu profile: ", err)
+		}
+		if err := pprof.StartCPUProfile(cpuf); err != nil {
+			log.Fatal("could not start cpu profile: ", err)
+		}
+	}
+
+	ctx := context.Background()
+	if *input.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *input.timeout)
+		defer cancel()
+	}
+
+	var err error
+	if *input.inputNMS != "" {
+		err = resumeFromGradients(ctx, *input.inputNMS, *input.output, opts)
+	} else if inputData != nil {
+		cli.defaultOutput = !isFlagSet("output")
+		inputName := "base64 input"
+		if *input.input == "-" {
+			inputName = "stdin"
+		}
+		err = processFile(ctx, inputName, *input.output, opts, cli)
+	} else if isArchive(*input.input) && !isDirectory(*input.input) {
+		outputArchive := *input.output
+		if !isFlagSet("output") {
+			outputArchive = "out.tar"
+		}
+		processArchive(ctx, *input.input, outputArchive, opts, cli)
+	} else if isDirectory(*input.input) {
+		outputDir := *input.output
+		if !isFlagSet("output") {
+			outputDir = "out"
+		}
+		processDirectory(ctx, *input.input, outputDir, opts, cli)
+	} else if isTIFF(*input.input) {
+		err = processTIFF(ctx, *input.input, *input.output, opts, cli.bandHeight)
+	} else {
+		cli.defaultOutput = !isFlagSet("output")
+		err = processFile(ctx, *input.input, *input.output, opts, cli)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *profiling.profile {
 		pprof.StopCPUProfile()
+		if err := cpuf.Close(); err != nil {
+			log.Fatal("could not write cpu profile: ", err)
+		}
 
-		memf, err := os.Create("mem_profile")
+		memf, err := os.Create(profilePath(*profiling.profileOutput, *profiling.memProfile, startTime))
 		if err != nil {
 			log.Fatal("could not create memory profile: ", err)
 		}
 
+		// Run a collection so the heap profile reflects the final state.
+		runtime.GC()
 		if err := pprof.WriteHeapProfile(memf); err != nil {
 			log.Fatal("could not write memory profile: ", err)
 		}
-		_ = memf.Close()
+		if err := memf.Close(); err != nil {
+			log.Fatal("could not write memory profile: ", err)
+		}
+	}
+}
+
+// explain implements the explain subcommand, which prints the intermediate
+// values of the pipeline at a single pixel.
+func explain(args []string) {
+	flags := flag.NewFlagSet("explain", flag.ExitOnError)
+	blurFlagPtr := flags.Bool("blur", true, "perform gaussian blur before edge detection (optional, default: true)")
+	inputFileArgPtr := flags.String("input", "", "path to input file (required)")
+	minThresholdArgPtr := flags.Float64("min", float64(0.2), "ratio of lower threshold (optional, default: 0.2")
+	maxThresholdArgPtr := flags.Float64("max", float64(0.6), "ratio of upper threshold (optional, default: 0.6")
+	xArgPtr := flags.Int("x", 0, "x coordinate of the pixel to explain (required)")
+	yArgPtr := flags.Int("y", 0, "y coordinate of the pixel to explain (required)")
+	_ = flags.Parse(args)
+
+	if *inputFileArgPtr == "" {
+		fmt.Println("No path to input file specified, nothing to do.")
+		return
 	}
 
-	writeImage(pixels, *outputFileArgPtr)
+	image.RegisterFormat("jpeg", "jpeg", jpeg.Decode, jpeg.DecodeConfig)
+	image.RegisterFormat("png", "png", png.Decode, png.DecodeConfig)
+
+	pixels, err := openImage(*inputFileArgPtr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	opts := canny.Options{Blur: *blurFlagPtr, MinRatio: *minThresholdArgPtr, MaxRatio: *maxThresholdArgPtr}
+	report, err := canny.ExplainPixel(context.Background(), pixels, opts, *xArgPtr, *yArgPtr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("pixel:          (%d, %d)\n", *xArgPtr, *yArgPtr)
+	fmt.Printf("gray:           %d\n", report.Gray)
+	fmt.Printf("blurred:        %d\n", report.Blurred)
+	fmt.Printf("gradient x:     %.2f\n", report.GradientX)
+	fmt.Printf("gradient y:     %.2f\n", report.GradientY)
+	fmt.Printf("magnitude:      %d\n", report.Magnitude)
+	fmt.Printf("angle:          %.2f degrees\n", report.Angle)
+	fmt.Printf("direction bin:  %s\n", report.DirectionBin)
+	fmt.Printf("suppressed:     %t\n", report.Suppressed)
+	fmt.Printf("classification: %s\n", report.Classification)
+	fmt.Printf("edge:           %t\n", report.Edge)
 }
 
-func openImage(path string) [][]GrayPixel {
+// cliOptions holds the command line settings that control how a single input
+// is preprocessed and how its results are written.
+type cliOptions struct {
+	diffFile            string
+	flatten             int
+	quiverStep          int
+	quiverScale         float64
+	compare             string
+	keepLargest         bool
+	dominantOrientation bool
+	geoTransform        string
+	dumpThresholds      string
+	bandHeight          int
+	normalize           bool
+	normalizeMean       float64
+	normalizeStd        float64
+	preserveMetadata    bool
+	density             int
+	paletteEdges        bool
+	subpixelFile        string
+	force               bool
+	recursive           bool
+	outputPattern       string
+	supersample         int
+	operatorGrid        bool
+	minEdgeLength       int
+	lut                 *[256]uint8
+	dumpRecall          string
+	removeIsolated      bool
+	transparent         *color.NRGBA
+	scaleMap            bool
+	inputData           []byte
+	benchmark           int
+	edgeColor           string
+	edgeDarken          float64
+	componentBounds     bool
+	fastScale           float64
+	ascii               bool
+	asciiWidth          int
+	memReport           bool
+	withBinary          bool
+	dumpGradients       string
+	rotate              float64
+	deskew              bool
+	dumpNMS             string
+	grid                int
+	scalebarLength      int
+	scalebarLabel       string
+	rotationVote        int
+	csvMagnitude        bool
+	blend               float64
+	dim                 float64
+	dualOutput          bool
+	levels              int
+	confidence          bool
+	channelCombine      *canny.ChannelCombine
+	primitives          string
+	edgeEnergy          bool
+	windowLevel         *[2]float64
+	subtractEdges       string
+	subtractTolerance   int
+	defaultOutput       bool // the output path is the -output default
+}
+
+// processFile detects the edges of the image at inputPath, or of
+// cli.inputData if set, and writes the results to outputPath. With
+// cli.inputData, inputPath only names the input in error messages.
+func processFile(ctx context.Context, inputPath, outputPath string, opts canny.Options, cli cliOptions) error {
+	var source image.Image
+	var err error
+	if cli.inputData != nil {
+		source, err = decodeImage(bytes.NewReader(cli.inputData), inputPath)
+	} else {
+		source, err = openSourceImage(inputPath)
+	}
+	if err != nil {
+		return err
+	}
+	if cli.defaultOutput && (cli.transparent == nil) && hasTransparency(source) {
+		log.Printf("warning: input has transparent pixels, which the default output %s flattens; pass -transparent to write the edges on a transparent background or -output to silence this warning", outputPath)
+	}
+	pixels := canny.ImageToPixelArray(source)
+	// 16-bit inputs are detected on their full precision, unless they are
+	// preprocessed as 8-bit gray values.
+	samples16 := canny.Gray16Samples(source)
+	if (cli.windowLevel != nil) && canny.Is16Bit(source) {
+		samples16 = nil
+		pixels, err = canny.WindowLevelSamples16(canny.Luma16(source), cli.windowLevel[0], cli.windowLevel[1])
+		if err != nil {
+			return err
+		}
+	}
+	if cli.diffFile != "" {
+		samples16 = nil
+		reference, err := openImage(cli.diffFile)
+		if err != nil {
+			return err
+		}
+		pixels, err = canny.DifferencePixels(pixels, reference)
+		if err != nil {
+			return err
+		}
+	}
+	if cli.lut != nil {
+		samples16 = nil
+		pixels = canny.ApplyLUT(pixels, *cli.lut)
+	}
+	if cli.normalize {
+		samples16 = nil
+		pixels = canny.NormalizePixels(pixels, cli.normalizeMean, cli.normalizeStd)
+	}
+	if cli.flatten > 0 {
+		samples16 = nil
+		pixels = canny.FlattenIllumination(pixels, cli.flatten)
+	}
+	// rotation is the total rotation of pixels, which source is rotated by
+	// too so that edge colors are sampled where the edges are.
+	var rotation float64
+	if cli.rotate != 0 {
+		samples16 = nil
+		pixels = canny.RotateBilinear(pixels, cli.rotate)
+		rotation += cli.rotate
+	}
+	if cli.deskew {
+		samples16 = nil
+		var skew float64
+		pixels, skew, err = canny.Deskew(ctx, pixels, opts)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(infoOut, "Deskewed by %.1f degrees\n", skew)
+		rotation += skew
+	}
+	if rotation != 0 {
+		source = canny.RotateImage(source, rotation)
+	}
+	if cli.quiverStep > 0 {
+		quiver, _, err := canny.DrawQuiver(pixels, cli.quiverStep, cli.quiverScale)
+		if err != nil {
+			return err
+		}
+		return encodeImage(quiver, outputPath)
+	}
+	fullWidth, fullHeight := len(pixels[0]), len(pixels)
+	if cli.supersample > 1 {
+		samples16 = nil
+		pixels = canny.ResizeBilinear(pixels, len(pixels[0])*cli.supersample, len(pixels)*cli.supersample)
+		if opts.RadialCenter != nil {
+			center := opts.RadialCenter.Mul(cli.supersample)
+			opts.RadialCenter = &center
+		}
+	}
+	if cli.fastScale < 1 {
+		samples16 = nil
+		width := int(math.Max(1, math.Round(float64(fullWidth)*cli.fastScale)))
+		height := int(math.Max(1, math.Round(float64(fullHeight)*cli.fastScale)))
+		pixels = canny.ResizeBilinear(pixels, width, height)
+		if opts.RadialCenter != nil {
+			center := image.Point{opts.RadialCenter.X * width / fullWidth, opts.RadialCenter.Y * height / fullHeight}
+			opts.RadialCenter = &center
+		}
+	}
+
+	if cli.operatorGrid {
+		grid, err := canny.OperatorGrid(ctx, pixels, opts, []canny.Operator{canny.SOBEL, canny.SCHARR, canny.PREWITT})
+		if err != nil {
+			return err
+		}
+		return encodeImage(grid, outputPath)
+	}
+
+	if cli.scaleMap {
+		scales, err := canny.ScaleMap(ctx, pixels, opts, canny.SCALE_MAP_SIGMAS)
+		if err != nil {
+			return err
+		}
+		return writeImage(scales, outputPath)
+	}
+
+	if cli.levels > 0 {
+		layers, err := canny.LayeredEdges(ctx, pixels, opts, cli.levels)
+		if err != nil {
+			return err
+		}
+		return writeImage(layers, outputPath)
+	}
+
+	if cli.compare != "" {
+		sets, err := parseThresholdPairs(cli.compare, opts)
+		if err != nil {
+			return err
+		}
+		overlay, err := canny.OverlayParameterSets(ctx, pixels, sets)
+		if err != nil {
+			return err
+		}
+		return encodeImage(overlay, outputPath)
+	}
+
+	if cli.channelCombine != nil {
+		edges, err := canny.DetectChannels(ctx, canny.ChannelPixels(source), opts, *cli.channelCombine)
+		if err != nil {
+			return err
+		}
+		return writeImage(edges, outputPath)
+	}
+
+	if cli.rotationVote > 0 {
+		edges, err := canny.RotationVote(ctx, pixels, opts, cli.rotationVote)
+		if err != nil {
+			return err
+		}
+		return writeImage(edges, outputPath)
+	}
+
+	detect := func() (*canny.Stages, error) {
+		if samples16 != nil {
+			return canny.DetectStages16(ctx, samples16, opts)
+		}
+		return canny.DetectStages(ctx, pixels, opts)
+	}
+	if cli.benchmark > 0 {
+		return runBenchmark(len(pixels[0]), len(pixels), cli.benchmark, detect)
+	}
+
+	stages, err := detect()
+	if err != nil {
+		return err
+	}
+	detectionInput := pixels
+	width, height := len(pixels[0]), len(pixels)
+	pixels = stages.Edges
+	if cli.memReport {
+		total := 0
+		for _, u := range canny.StageMemory(stages, opts.Blur) {
+			fmt.Fprintf(infoOut, "%-10s %9d x %2d B = %10d B\n", u.Stage, u.Elements, u.ElementSize, u.Bytes)
+			total += u.Bytes
+		}
+		fmt.Fprintf(infoOut, "%-10s %27d B\n", "total", total)
+	}
+	if cli.dumpRecall != "" {
+		if err := writeImage(canny.PointsToPixels(stages.Strong, width, height), cli.dumpRecall+"_strong_only.png"); err != nil {
+			return err
+		}
+		if err := writeImage(stages.Edges, cli.dumpRecall+"_hysteresis.png"); err != nil {
+			return err
+		}
+		recovered := countEdgePixels(stages.Edges) - stages.Strong.Cardinality()
+		fmt.Fprintf(infoOut, "Pixels recovered by hysteresis: %d\n", recovered)
+	}
+	if cli.removeIsolated {
+		pixels = canny.RemoveIsolated(pixels)
+	}
+	if cli.keepLargest {
+		pixels = canny.KeepLargestComponent(pixels)
+	}
+	if cli.minEdgeLength > 0 {
+		pixels = canny.FilterShortContours(pixels, cli.minEdgeLength)
+	}
+	if cli.withBinary {
+		pixels = canny.CombineMasks(pixels, canny.BinarizeDark(detectionInput))
+	}
+	if cli.subtractEdges != "" {
+		reference, err := openImage(cli.subtractEdges)
+		if err != nil {
+			return err
+		}
+		pixels, err = canny.SubtractEdges(pixels, reference, cli.subtractTolerance)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cli.edgeEnergy {
+		fmt.Fprintf(infoOut, "Edge energy: %.4f\n", canny.EdgeEnergy(pixels, stages.Magnitudes))
+	}
+
+	if cli.dominantOrientation {
+		fmt.Fprintf(infoOut, "Dominant edge orientation: %.0f degrees\n", canny.DominantOrientation(pixels, stages.Directions))
+	}
+
+	if cli.dumpThresholds != "" {
+		if err := writeImage(canny.PointsToPixels(stages.Strong, width, height), cli.dumpThresholds+"_strong.png"); err != nil {
+			return err
+		}
+		if err := writeImage(canny.PointsToPixels(stages.Weak, width, height), cli.dumpThresholds+"_weak.png"); err != nil {
+			return err
+		}
+	}
+
+	if cli.dumpGradients != "" {
+		err := createAtomic(cli.dumpGradients, func(w io.Writer) error {
+			return canny.WriteRawGradients(w, stages)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if cli.dumpNMS != "" {
+		err := createAtomic(cli.dumpNMS, func(w io.Writer) error {
+			return canny.WriteRawSuppressed(w, stages)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if cli.subpixelFile != "" {
+		if err := writeSubpixelCSV(canny.SubpixelEdges(stages, opts.BinRule), cli.subpixelFile); err != nil {
+			return err
+		}
+	}
+
+	if cli.primitives != "" {
+		data, err := canny.PrimitivesJSON(pixels)
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(cli.primitives, data); err != nil {
+			return err
+		}
+	}
+
+	if cli.confidence {
+		pixels = canny.EdgeConfidence(pixels, stages.Strong)
+	}
+
+	if cli.supersample > 1 {
+		pixels = canny.DownsampleEdges(pixels, cli.supersample)
+	}
+	if cli.fastScale < 1 {
+		pixels = canny.UpsampleEdges(pixels, fullWidth, fullHeight)
+	}
+
+	if cli.ascii {
+		fmt.Print(canny.ASCIIArt(pixels, cli.asciiWidth))
+		return nil
+	}
+
+	if cli.dualOutput {
+		magnitudes := stages.Magnitudes
+		if cli.supersample > 1 {
+			magnitudes = canny.DownsampleEdges(magnitudes, cli.supersample)
+		}
+		if cli.fastScale < 1 {
+			magnitudes = canny.UpsampleEdges(magnitudes, fullWidth, fullHeight)
+		}
+		return encodeImage(canny.DualChannelEdges(magnitudes, pixels), outputPath)
+	}
+
+	if cli.paletteEdges {
+		return encodeImage(canny.PaletteComponents(pixels), outputPath)
+	}
+
+	if cli.componentBounds {
+		return encodeImage(canny.DrawComponentBounds(pixels), outputPath)
+	}
+
+	if cli.blend > 0 {
+		c := canny.OVERLAY_PALETTE[0]
+		if cli.edgeColor != "" {
+			edgeColor, _ := parseHexColor(cli.edgeColor)
+			c = color.RGBA{edgeColor.R, edgeColor.G, edgeColor.B, 255}
+		}
+		return encodeImage(canny.BlendEdges(pixels, source, c, cli.blend, cli.dim), outputPath)
+	}
+
+	if cli.edgeColor != "" {
+		var img *image.RGBA
+		if cli.edgeColor == "source" {
+			img = canny.SourceColorEdges(pixels, source, cli.edgeDarken)
+		} else {
+			c, _ := parseHexColor(cli.edgeColor)
+			img = image.NewRGBA(image.Rect(0, 0, len(pixels[0]), len(pixels)))
+			draw.Draw(img, img.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+			canny.OverlayEdges(img, pixels, color.RGBA{c.R, c.G, c.B, 255})
+		}
+		return encodeImage(img, outputPath)
+	}
+
+	if cli.transparent != nil {
+		return encodePNG(canny.TransparentEdges(pixels, *cli.transparent), outputPath)
+	}
+
+	if cli.density > 0 {
+		pixels, err = canny.EdgeDensity(pixels, cli.density)
+		if err != nil {
+			return err
+		}
+	}
+
+	if filepath.Ext(outputPath) == ".geojson" {
+		return writeGeoJSON(pixels, cli.geoTransform, outputPath)
+	}
+
+	if filepath.Ext(outputPath) == ".csv" {
+		return writeEdgeCSV(pixels, cli.csvMagnitude, outputPath)
+	}
+
+	var md *canny.Metadata
+	if cli.preserveMetadata && (cli.inputData == nil) {
+		md, err = readMetadataFile(inputPath)
+		if err != nil {
+			return err
+		}
+	}
+	if (cli.grid > 0) || (cli.scalebarLength > 0) {
+		img := canny.GrayToRGBA(pixels)
+		if cli.grid > 0 {
+			canny.DrawGrid(img, cli.grid)
+		}
+		if cli.scalebarLength > 0 {
+			canny.DrawScaleBar(img, cli.scalebarLength, cli.scalebarLabel)
+		}
+		return encodeImageMetadata(img, outputPath, md)
+	}
+	img, err := canny.PixelArrayToImage(pixels)
+	if err != nil {
+		return err
+	}
+	return encodeImageMetadata(img, outputPath, md)
+}
+
+// resumeFromGradients thresholds the gradients dumped to inputPath by
+// -dump-gradients and writes the edges to outputPath.
+func resumeFromGradients(ctx context.Context, inputPath, outputPath string, opts canny.Options) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	magnitudes, directions, err := canny.ReadRawGradients(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %v", inputPath, err)
+	}
+
+	stages, err := canny.ResumeFromGradients(ctx, magnitudes, directions, opts)
+	if err != nil {
+		return err
+	}
+	return writeImage(stages.Edges, outputPath)
+}
+
+// writeSubpixelCSV writes sub-pixel edge positions as x,y lines.
+func writeSubpixelCSV(points []canny.SubpixelPoint, path string) error {
+	var b strings.Builder
+	b.WriteString("x,y\n")
+	for _, p := range points {
+		fmt.Fprintf(&b, "%.4f,%.4f\n", p.X, p.Y)
+	}
+	return writeFileAtomic(path, []byte(b.String()))
+}
+
+// writeEdgeCSV writes the coordinates of every edge pixel as x,y lines in
+// row-major order, followed by the magnitude if withMagnitude is set. There is
+// no header, so the line count is the edge pixel count.
+func writeEdgeCSV(pixels [][]canny.GrayPixel, withMagnitude bool, path string) error {
+	var b strings.Builder
+	for y := range pixels {
+		for x := range pixels[y] {
+			if pixels[y][x].Y == 0 {
+				continue
+			}
+			if withMagnitude {
+				fmt.Fprintf(&b, "%d,%d,%d\n", x, y, pixels[y][x].Y)
+			} else {
+				fmt.Fprintf(&b, "%d,%d\n", x, y)
+			}
+		}
+	}
+	return writeFileAtomic(path, []byte(b.String()))
+}
+
+// readMetadataFile reads the EXIF and text metadata of the image at
+// inputPath.
+func readMetadataFile(inputPath string) (*canny.Metadata, error) {
+	input, err := ioutil.ReadFile(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	md, err := canny.ReadMetadata(input)
+	if err != nil {
+		return nil, err
+	}
+	return &md, nil
+}
+
+// processTIFF detects the edges of the TIFF at inputPath in bands of
+// bandHeight rows and writes them to outputPath.
+func processTIFF(ctx context.Context, inputPath, outputPath string, opts canny.Options, bandHeight int) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tiff, err := canny.OpenTIFF(f)
+	if err != nil {
+		return fmt.Errorf("%s: %v", inputPath, err)
+	}
+	if maxPixels > 0 {
+		if err := checkPixelCount(inputPath, tiff.Width, tiff.Height, maxPixels); err != nil {
+			return err
+		}
+	}
+	edges, err := canny.DetectBands(ctx, tiff.Width, tiff.Height, bandHeight, tiff.ReadRows, opts)
+	if err != nil {
+		return err
+	}
+
+	return writeImage(edges, outputPath)
+}
+
+// isTIFF reports whether the file at path starts with a TIFF header.
+func isTIFF(path string) bool {
 	file, err := os.Open(path)
 	if err != nil {
-		log.Fatal(err)
+		return false
 	}
 	defer file.Close()
 
-	pixels, err := getPixelArray(file)
-	if err != nil {
-		log.Fatal(err)
+	header := make([]byte, len(canny.TIFF_LITTLE_ENDIAN_MARK))
+	if _, err := io.ReadFull(file, header); err != nil {
+		return false
 	}
+	return (string(header) == canny.TIFF_LITTLE_ENDIAN_MARK) || (string(header) == canny.TIFF_BIG_ENDIAN_MARK)
+}
 
-	return pixels
+// runBenchmark runs detect n times on an image of the given size and prints
+// the minimum, median and maximum latency and the average throughput.
+func runBenchmark(width, height, n int, detect func() (*canny.Stages, error)) error {
+	durations := make([]time.Duration, 0, n)
+	var total time.Duration
+	for i := 0; i < n; i++ {
+		start := time.Now()
+		if _, err := detect(); err != nil {
+			return err
+		}
+		d := time.Since(start)
+		durations = append(durations, d)
+		total += d
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	megapixels := float64(width*height) / 1e6
+	average := total / time.Duration(n)
+	fmt.Fprintf(infoOut, "Runs: %d, image: %dx%d\n", n, width, height)
+	fmt.Fprintf(infoOut, "Latency: min %v, median %v, max %v, average %v\n", durations[0], durations[n/2], durations[n-1], average)
+	fmt.Fprintf(infoOut, "Throughput: %.2f megapixels/second\n", megapixels/average.Seconds())
+	return nil
 }
 
-func writeImage(pixels [][]GrayPixel, path string) {
+// countEdgePixels returns the number of edge pixels in pixels.
+func countEdgePixels(pixels [][]canny.GrayPixel) int {
+	count := 0
+	for _, row := range canny.EdgeMask(pixels) {
+		for _, edge := range row {
+			if edge {
+				count++
+			}
+		}
+	}
+	return count
+}
 
-	grayImg := getImageFromArray(pixels)
-	outFile, err := os.Create(path)
+// readLUT loads a lookup table of exactly 256 bytes from path.
+func readLUT(path string) (*[256]uint8, error) {
+	data, err := ioutil.ReadFile(path)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
+	if len(data) != 256 {
+		return nil, fmt.Errorf("lookup table %s has %d entries, expected 256", path, len(data))
+	}
+	var lut [256]uint8
+	copy(lut[:], data)
+	return &lut, nil
+}
 
-	ext := filepath.Ext(path)
-	if ext == "png" {
-		err = png.Encode(outFile, grayImg)
-	} else {
-		opts := jpeg.Options{95}
-		err = jpeg.Encode(outFile, grayImg, &opts)
+func writeGeoJSON(pixels [][]canny.GrayPixel, geoTransform string, path string) error {
+	transform := canny.IDENTITY_GEOTRANSFORM
+	if geoTransform != "" {
+		values, err := parseFloatList(geoTransform)
+		if err != nil {
+			return err
+		}
+		if len(values) != len(transform) {
+			return errors.New("geotransform must have exactly 6 coefficients")
+		}
+		copy(transform[:], values)
 	}
+
+	data, err := canny.ContoursToGeoJSON(canny.ExtractContours(pixels), transform)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	return writeFileAtomic(path, data)
 }
 
-func getPixelArray(file io.Reader) ([][]GrayPixel, error) {
-	var pixelArr [][]GrayPixel
+// infoOut receives the informational messages. It is stderr when the output
+// is written to stdout with -output -, so stdout carries only the output.
+var infoOut io.Writer = os.Stdout
+
+// maxPixels caps the declared pixel count of input images, 0 disables the cap.
+var maxPixels int64
 
-	img, _, err := image.Decode(file)
+// noProgressive rejects progressive JPEG inputs, progressiveMaxPixels caps
+// their declared pixel count, 0 disables the cap.
+var noProgressive bool
+var progressiveMaxPixels int64
+
+func openImage(path string) ([][]canny.GrayPixel, error) {
+	img, err := openSourceImage(path)
+	if err != nil {
+		return nil, err
+	}
+	return canny.ImageToPixelArray(img), nil
+}
+
+// openSourceImage decodes the image at path without converting it to gray.
+func openSourceImage(path string) (image.Image, error) {
+	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
-	height := img.Bounds().Max.Y
-	width := img.Bounds().Max.X
+	defer file.Close()
+
+	return decodeImage(file, path)
+}
+
+// decodeImage decodes the image read from r, named name in error messages.
+func decodeImage(r io.ReadSeeker, name string) (image.Image, error) {
+	if noProgressive || (progressiveMaxPixels > 0) {
+		if err := checkProgressive(r, name); err != nil {
+			return nil, err
+		}
+	}
+	if maxPixels > 0 {
+		if err := checkMaxPixels(r, name, maxPixels); err != nil {
+			return nil, err
+		}
+	}
+
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, decodeError(r, name, err)
+	}
+
+	return img, nil
+}
+
+// checkProgressive returns an error if r holds a progressive JPEG and either
+// noProgressive is set or it declares more than progressiveMaxPixels pixels.
+// r is rewound afterwards.
+func checkProgressive(r io.ReadSeeker, name string) error {
+	progressive, err := canny.IsProgressiveJPEG(r)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if !progressive {
+		return nil
+	}
+	if noProgressive {
+		return fmt.Errorf("%s: %v, rejected by -no-progressive", name, canny.ErrProgressiveJPEG)
+	}
+
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return decodeError(r, name, err)
+	}
+	if int64(config.Width)*int64(config.Height) > progressiveMaxPixels {
+		return fmt.Errorf("%s: %v declares %dx%d pixels, more than -progressive-max-pixels %d", name, canny.ErrProgressiveJPEG, config.Width, config.Height, progressiveMaxPixels)
+	}
+	_, err = r.Seek(0, io.SeekStart)
+	return err
+}
+
+// SUPPORTED_FORMATS lists the input formats with a registered decoder.
+const SUPPORTED_FORMATS = "GIF, JPEG and PNG"
+
+// decodeError replaces the generic image.ErrFormat with an error naming the
+// supported formats and, if SniffFormat recognizes the leading bytes of r,
+// the actual format of the input. Other errors are prefixed with name.
+func decodeError(r io.ReadSeeker, name string, err error) error {
+	if err != image.ErrFormat {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+
+	header := make([]byte, canny.SNIFF_LENGTH)
+	n := 0
+	if _, seekErr := r.Seek(0, io.SeekStart); seekErr == nil {
+		n, _ = io.ReadFull(r, header)
+	}
+	if format := canny.SniffFormat(header[:n]); format != "" {
+		return fmt.Errorf("%s is a %s image, which is not supported, supported formats are %s", name, format, SUPPORTED_FORMATS)
+	}
+	return fmt.Errorf("%s is not in a known image format, supported formats are %s", name, SUPPORTED_FORMATS)
+}
+
+// checkMaxPixels returns an error if the image in r declares more than
+// limit pixels, without decoding it. r is rewound afterwards.
+func checkMaxPixels(r io.ReadSeeker, name string, limit int64) error {
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return decodeError(r, name, err)
+	}
+	if err := checkPixelCount(name, config.Width, config.Height, limit); err != nil {
+		return err
+	}
+	_, err = r.Seek(0, io.SeekStart)
+	return err
+}
+
+// checkPixelCount returns an error if an image of the given size has more
+// than limit pixels.
+func checkPixelCount(name string, width, height int, limit int64) error {
+	if int64(width)*int64(height) > limit {
+		return fmt.Errorf("%s declares %dx%d pixels, more than -max-pixels %d", name, width, height, limit)
+	}
+	return nil
+}
+
+func writeImage(pixels [][]canny.GrayPixel, path string) error {
+	img, err := canny.PixelArrayToImage(pixels)
+	if err != nil {
+		return err
+	}
+	return encodeImage(img, path)
+}
+
+// encodeOptions configures how output images are encoded.
+var encodeOptions = canny.EncodeOptions{Quality: 95}
+
+// encodeImage writes img to path in the format named by the extension of
+// path, JPEG if the extension is unknown.
+func encodeImage(img image.Image, path string) error {
+	return encodeImageMetadata(img, path, nil)
+}
+
+// encodeImageMetadata is encodeImage with md, if not nil, added to the
+// encoded image before it is written, so the output never appears without
+// its metadata.
+func encodeImageMetadata(img image.Image, path string, md *canny.Metadata) error {
+	format := "jpeg"
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		format = "png"
+	case ".gif":
+		format = "gif"
+	}
+	var buf bytes.Buffer
+	err := canny.Encode(&buf, img, format, encodeOptions)
+	if err != nil {
+		return err
+	}
+	data := buf.Bytes()
+	if md != nil {
+		data, err = canny.CopyMetadata(data, *md)
+		if err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(path, data)
+}
+
+// hasTransparency reports whether img has any pixel that is not fully
+// opaque.
+func hasTransparency(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return !o.Opaque()
+	}
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a != 0xffff {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// encodePNG writes img to path as PNG regardless of the extension, for
+// outputs that need an alpha channel.
+func encodePNG(img image.Image, path string) error {
+	var buf bytes.Buffer
+	if err := canny.Encode(&buf, img, "png", encodeOptions); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+// createAtomic writes a file at path through write. The data goes to a
+// temporary file in the same directory first, which is renamed to path on
+// success and removed on failure, so readers never see a partial file. A
+// path of "-" writes to stdout instead.
+func createAtomic(path string, write func(w io.Writer) error) error {
+	if path == "-" {
+		return write(os.Stdout)
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	err = write(tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// writeFileAtomic is ioutil.WriteFile through createAtomic.
+func writeFileAtomic(path string, data []byte) error {
+	return createAtomic(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
 
-	for y := 0; y < height; y++ {
-		var row []GrayPixel
-		for x := 0; x < width; x++ {
-			pixel := img.At(x, y)
-			grayPixel := rgbaToGrayPixel(pixel)
-			row = append(row, grayPixel)
+// profilePath returns the path of a profile file. Profiles written to an
+// explicit directory get the start time appended so that runs don't
+// overwrite each other.
+func profilePath(dir, name string, start time.Time) string {
+	if dir == "" {
+		return name
+	}
+	return filepath.Join(dir, name+"_"+start.Format("20060102T150405.000000000"))
+}
+
+// parseFloatList parses a comma separated list of numbers.
+func parseFloatList(s string) ([]float64, error) {
+	var values []float64
+
+	for _, field := range strings.Split(s, ",") {
+		value, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, err
 		}
-		pixelArr = append(pixelArr, row)
+		values = append(values, value)
 	}
 
-	return pixelArr, nil
+	return values, nil
 }
 
-func getImageFromArray(pixels [][]GrayPixel) *image.Gray {
+// parsePoints parses semicolon separated x,y pairs such as "10,20;30,40".
+func parsePoints(s string) ([]image.Point, error) {
+	var points []image.Point
+	for _, pair := range strings.Split(s, ";") {
+		values, err := parseFloatList(pair)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != 2 {
+			return nil, fmt.Errorf("point %q needs two coordinates", pair)
+		}
+		points = append(points, image.Point{int(values[0]), int(values[1])})
+	}
+	return points, nil
+}
 
-	bounds := image.Rect(0, 0, len(pixels[0]), len(pixels))
-	img := image.NewGray(bounds)
+// parseThresholdPairs parses a comma separated list of min:max threshold
+// ratios into copies of base.
+func parseThresholdPairs(s string, base canny.Options) ([]canny.Options, error) {
+	var sets []canny.Options
 
-	for y := 0; y < len(pixels); y++ {
-		for x := 0; x < len(pixels[y]); x++ {
-			img.SetGray(x, y, color.Gray{pixels[y][x].y})
+	for _, pair := range strings.Split(s, ",") {
+		parts := strings.Split(pair, ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid threshold pair %q, expected min:max", pair)
+		}
+		min, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			return nil, err
 		}
+		max, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		if !isValidRatioValue(min) || !isValidRatioValue(max) {
+			return nil, fmt.Errorf("invalid threshold pair %q, ratios must be in [0, 1]", pair)
+		}
+		opts := base
+		opts.MinRatio, opts.MaxRatio = min, max
+		sets = append(sets, opts)
 	}
 
-	return img
+	return sets, nil
+}
+
+// parseHexColor parses an opaque color written as RRGGBB, optionally
+// prefixed with '#'.
+func parseHexColor(s string) (color.NRGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) != 6 {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q, expected RRGGBB", s)
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, err
+	}
+	return color.NRGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}, nil
 }
 
 func isValidRatioValue(x float64) bool {
@@ -149,10 +1457,3 @@ func isValidRatioValue(x float64) bool {
 	}
 	return false
 }
-
-func rgbaToGrayPixel(pixel color.Color) GrayPixel {
-	_, _, _, a := pixel.RGBA()
-	gray := color.GrayModel.Convert(pixel).(color.Gray).Y
-
-	return GrayPixel{gray, uint8(a >> 8)}
-}
@@ -0,0 +1,702 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chfanghr/canny-go/canny"
+)
+
+// TestMain runs main instead of the tests if CANNY_GO_RUN_MAIN is set, so
+// tests can run the command with runMain.
+func TestMain(m *testing.M) {
+	if os.Getenv("CANNY_GO_RUN_MAIN") != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runMain runs the command with args and stdin and returns its stdout.
+func runMain(t *testing.T, stdin []byte, args ...string) []byte {
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "CANNY_GO_RUN_MAIN=1")
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("%v: %v\n%s", args, err, stderr.Bytes())
+	}
+	return out
+}
+
+// runMainError runs the command with args, expecting it to fail, and returns
+// its standard error.
+func runMainError(t *testing.T, args ...string) string {
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "CANNY_GO_RUN_MAIN=1")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err == nil {
+		t.Fatalf("%v: expected the command to fail", args)
+	}
+	return stderr.String()
+}
+
+// writePNG encodes img as a PNG file at path.
+func writePNG(t *testing.T, path string, img image.Image) {
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// circleImage returns a gray image with a bright disc in its middle.
+func circleImage(width, height int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			dx, dy := x-width/2, y-height/2
+			if dx*dx+dy*dy < width*height/12 {
+				img.SetGray(x, y, color.Gray{220})
+			} else {
+				img.SetGray(x, y, color.Gray{20})
+			}
+		}
+	}
+	return img
+}
+
+func TestParseThresholdPairs(t *testing.T) {
+	base := canny.Options{Blur: true}
+	sets, err := parseThresholdPairs("0.1:0.3,0.2:0.6", base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]float64{{0.1, 0.3}, {0.2, 0.6}}
+	if len(sets) != len(want) {
+		t.Fatalf("got %d sets, want %d", len(sets), len(want))
+	}
+	for i, opts := range sets {
+		if !opts.Blur || (opts.MinRatio != want[i][0]) || (opts.MaxRatio != want[i][1]) {
+			t.Errorf("set %d: got %+v, want ratios %v of base", i, opts, want[i])
+		}
+	}
+
+	for _, s := range []string{"0.1", "0.1:0.3:0.5", "a:0.3", "0.1:b", "0.1:1.5", ""} {
+		if _, err := parseThresholdPairs(s, base); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestProfileOutputDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "in.png")
+	writePNG(t, inputPath, circleImage(32, 24))
+	profileDir := filepath.Join(dir, "profiles")
+	runMain(t, nil, "-input", inputPath, "-output", filepath.Join(dir, "out.jpg"), "-profile", "-profile-output", profileDir, "-cpu-profile", "cpu.prof")
+
+	entries, err := ioutil.ReadDir(profileDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if (len(names) != 2) || !strings.HasPrefix(names[0], "cpu.prof_") || !strings.HasPrefix(names[1], "mem_profile_") {
+		t.Errorf("got profiles %v, want cpu.prof_<time> and mem_profile_<time>", names)
+	}
+
+	// Both profiles are flushed and closed, so they are complete gzip
+	// streams.
+	for _, name := range names {
+		f, err := os.Open(filepath.Join(profileDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		zr, err := gzip.NewReader(f)
+		if err == nil {
+			_, err = ioutil.ReadAll(zr)
+		}
+		f.Close()
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestProfilePath(t *testing.T) {
+	start := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	if got := profilePath("", "cpu_profile", start); got != "cpu_profile" {
+		t.Errorf("got %q, want cpu_profile", got)
+	}
+	want := filepath.Join("out", "cpu_profile_20200102T030405.000000006")
+	if got := profilePath("out", "cpu_profile", start); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestDumpRecallFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-recall")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "in.png")
+	writePNG(t, inputPath, circleImage(32, 24))
+	prefix := filepath.Join(dir, "recall")
+	out := runMain(t, nil, "-input", inputPath, "-output", filepath.Join(dir, "out.jpg"), "-dump-recall", prefix)
+	if !strings.Contains(string(out), "Pixels recovered by hysteresis: ") {
+		t.Errorf("got output %q, want the number of recovered pixels", out)
+	}
+	for _, name := range []string{prefix + "_strong_only.png", prefix + "_hysteresis.png"} {
+		if _, err := os.Stat(name); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// pngHeader returns the signature and the IHDR chunk of a gray PNG declaring
+// the given size, without any image data.
+func pngHeader(width, height uint32) []byte {
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr, width)
+	binary.BigEndian.PutUint32(ihdr[4:], height)
+	ihdr[8] = 8
+
+	var b bytes.Buffer
+	b.WriteString("\x89PNG\r\n\x1a\n")
+	binary.Write(&b, binary.BigEndian, uint32(len(ihdr)))
+	chunk := append([]byte("IHDR"), ihdr...)
+	b.Write(chunk)
+	binary.Write(&b, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+	return b.Bytes()
+}
+
+func TestCheckMaxPixelsRejectsDeclaredSize(t *testing.T) {
+	err := checkMaxPixels(bytes.NewReader(pngHeader(100000, 100000)), "huge.png", 1000000)
+	if (err == nil) || !strings.Contains(err.Error(), "100000x100000") {
+		t.Errorf("got %v, want an error naming the declared size", err)
+	}
+}
+
+func TestCheckMaxPixelsRewinds(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 8, 8))
+	img.SetGray(3, 4, color.Gray{200})
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		t.Fatal(err)
+	}
+
+	r := bytes.NewReader(b.Bytes())
+	if err := checkMaxPixels(r, "small.png", 64); err != nil {
+		t.Fatal(err)
+	}
+	if pos, _ := r.Seek(0, io.SeekCurrent); pos != 0 {
+		t.Errorf("reader left at %d, want 0", pos)
+	}
+}
+
+func TestMaxPixelsFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-max-pixels")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	hugePath := filepath.Join(dir, "huge.png")
+	if err := ioutil.WriteFile(hugePath, pngHeader(100000, 100000), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stderr := runMainError(t, "-input", hugePath, "-output", filepath.Join(dir, "out.jpg"), "-max-pixels", "1000000")
+	if !strings.Contains(stderr, "more than -max-pixels") {
+		t.Errorf("got %q, want the -max-pixels error", stderr)
+	}
+
+	smallPath := filepath.Join(dir, "small.png")
+	writePNG(t, smallPath, circleImage(32, 24))
+	runMain(t, nil, "-input", smallPath, "-output", filepath.Join(dir, "out.jpg"), "-max-pixels", "768")
+}
+
+func TestProgressiveFlags(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-progressive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var baseline bytes.Buffer
+	if err := jpeg.Encode(&baseline, circleImage(32, 24), nil); err != nil {
+		t.Fatal(err)
+	}
+	baselinePath := filepath.Join(dir, "baseline.jpg")
+	if err := ioutil.WriteFile(baselinePath, baseline.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Relabelling the SOF0 frame header as SOF2 marks the JPEG as progressive
+	// for the header checks, which run before any image data is decoded.
+	progressivePath := filepath.Join(dir, "progressive.jpg")
+	progressive := bytes.Replace(baseline.Bytes(), []byte{0xff, 0xc0}, []byte{0xff, 0xc2}, 1)
+	if err := ioutil.WriteFile(progressivePath, progressive, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	outputPath := filepath.Join(dir, "out.png")
+	stderr := runMainError(t, "-input", progressivePath, "-output", outputPath, "-no-progressive")
+	if !strings.Contains(stderr, "progressive JPEG, rejected by -no-progressive") {
+		t.Errorf("got %q, want the -no-progressive error", stderr)
+	}
+	stderr = runMainError(t, "-input", progressivePath, "-output", outputPath, "-progressive-max-pixels", "700")
+	if !strings.Contains(stderr, "declares 32x24 pixels, more than -progressive-max-pixels 700") {
+		t.Errorf("got %q, want the -progressive-max-pixels error", stderr)
+	}
+	if _, err := os.Stat(outputPath); err == nil {
+		t.Error("the progressive JPEG was processed")
+	}
+
+	runMain(t, nil, "-input", baselinePath, "-output", outputPath, "-no-progressive", "-progressive-max-pixels", "700")
+	if _, err := os.Stat(outputPath); err != nil {
+		t.Errorf("the baseline JPEG wasn't processed: %v", err)
+	}
+	out := runMain(t, nil, "-input", baselinePath, "-output", outputPath, "-progressive-max-pixels", "-1")
+	if !strings.Contains(string(out), "Invalid value for maximum progressive JPEG pixel count") {
+		t.Errorf("got output %q, want the invalid value message", out)
+	}
+}
+
+func TestInputBase64(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var b bytes.Buffer
+	if err := png.Encode(&b, circleImage(32, 24)); err != nil {
+		t.Fatal(err)
+	}
+	inputPath := filepath.Join(dir, "in.png")
+	if err := ioutil.WriteFile(inputPath, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	filePath := filepath.Join(dir, "file.jpg")
+	inlinePath := filepath.Join(dir, "inline.jpg")
+	runMain(t, nil, "-input", inputPath, "-output", filePath)
+	runMain(t, nil, "-input-base64", base64.StdEncoding.EncodeToString(b.Bytes()), "-output", inlinePath)
+	fromFile, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inline, err := ioutil.ReadFile(inlinePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(fromFile, inline) {
+		t.Error("the base64 input gave a different output than the same file")
+	}
+
+	if out := runMain(t, nil, "-input-base64", "not base64!", "-output", inlinePath); !strings.Contains(string(out), "Invalid base64 input") {
+		t.Errorf("got %q, want the base64 input to be rejected", out)
+	}
+}
+
+func TestBenchmark(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-benchmark")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "in.png")
+	outputPath := filepath.Join(dir, "out.jpg")
+	writePNG(t, inputPath, circleImage(32, 24))
+	out := string(runMain(t, nil, "-input", inputPath, "-output", outputPath, "-benchmark", "3"))
+	for _, want := range []string{"Runs: 3, image: 32x24\n", "Latency: min ", "Throughput: "} {
+		if !strings.Contains(out, want) {
+			t.Errorf("got %q, want it to contain %q", out, want)
+		}
+	}
+	if _, err := os.Stat(outputPath); err == nil {
+		t.Error("benchmark runs shouldn't write an output")
+	}
+}
+
+func TestCreateAtomicFailureLeavesNoFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "out.png")
+	failure := errors.New("write failed")
+	err = createAtomic(path, func(w io.Writer) error {
+		w.Write([]byte("partial"))
+		return failure
+	})
+	if err != failure {
+		t.Errorf("got %v, want %v", err, failure)
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		t.Errorf("left %s behind", entry.Name())
+	}
+}
+
+func TestCreateAtomicKeepsOldFileOnFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "out.png")
+	if err := writeFileAtomic(path, []byte("old")); err != nil {
+		t.Fatal(err)
+	}
+	createAtomic(path, func(w io.Writer) error {
+		w.Write([]byte("new"))
+		return errors.New("write failed")
+	})
+	data, err := ioutil.ReadFile(path)
+	if (err != nil) || (string(data) != "old") {
+		t.Errorf("got %q, %v, want the old file", data, err)
+	}
+}
+
+func TestRoundFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-round")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "in.png")
+	writePNG(t, inputPath, circleImage(32, 32))
+	for _, mode := range []string{"nearest", "trunc", "floor"} {
+		out := runMain(t, nil, "-input", inputPath, "-output", filepath.Join(dir, "out.png"), "-round", mode)
+		if strings.Contains(string(out), "Invalid rounding mode") != (mode == "floor") {
+			t.Errorf("-round %s: got output %q", mode, out)
+		}
+	}
+}
+
+func TestWriteEdgeCSV(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-edge-csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pixels := make([][]canny.GrayPixel, 3)
+	for y := range pixels {
+		pixels[y] = make([]canny.GrayPixel, 4)
+		pixels[y][y] = canny.GrayPixel{Y: uint8(100 + y), A: 255}
+	}
+	path := filepath.Join(dir, "edges.csv")
+	if err := writeEdgeCSV(pixels, false, path); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != "0,0\n1,1\n2,2\n" {
+		t.Errorf("got %q", data)
+	}
+	if err := writeEdgeCSV(pixels, true, path); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != "0,0,100\n1,1,101\n2,2,102\n" {
+		t.Errorf("got %q with magnitudes", data)
+	}
+}
+
+func TestCSVOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "in.png")
+	writePNG(t, inputPath, circleImage(32, 24))
+	outputPath := filepath.Join(dir, "edges.csv")
+	var counts []int
+	for _, withMagnitude := range []bool{false, true} {
+		args := []string{"-input", inputPath, "-output", outputPath}
+		columns := 2
+		if withMagnitude {
+			args = append(args, "-csv-magnitude")
+			columns = 3
+		}
+		runMain(t, nil, args...)
+		data, err := ioutil.ReadFile(outputPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		seen := make(map[image.Point]bool)
+		for _, line := range lines {
+			var p image.Point
+			var m int
+			n, _ := fmt.Sscanf(line, "%d,%d,%d", &p.X, &p.Y, &m)
+			if (n != columns) || !p.In(image.Rect(0, 0, 32, 24)) || seen[p] {
+				t.Fatalf("magnitude %t: bad line %q", withMagnitude, line)
+			}
+			if withMagnitude && (m == 0) {
+				t.Errorf("line %q has a zero magnitude", line)
+			}
+			seen[p] = true
+		}
+		counts = append(counts, len(lines))
+	}
+	if (counts[0] == 0) || (counts[0] != counts[1]) {
+		t.Errorf("got %d and %d edge pixels", counts[0], counts[1])
+	}
+}
+
+func TestDefaultOutputTransparencyWarning(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-transparency")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	translucent := image.NewNRGBA(image.Rect(0, 0, 24, 24))
+	draw.Draw(translucent, translucent.Bounds(), circleImage(24, 24), image.Point{}, draw.Src)
+	translucent.SetNRGBA(0, 0, color.NRGBA{0, 0, 0, 0})
+	writePNG(t, filepath.Join(dir, "translucent.png"), translucent)
+	writePNG(t, filepath.Join(dir, "opaque.png"), circleImage(24, 24))
+
+	for _, c := range []struct {
+		args []string
+		warn bool
+	}{
+		{[]string{"-input", "translucent.png"}, true},
+		{[]string{"-input", "translucent.png", "-output", "edges.jpg"}, false},
+		{[]string{"-input", "translucent.png", "-transparent", "ff0000"}, false},
+		{[]string{"-input", "opaque.png"}, false},
+	} {
+		cmd := exec.Command(os.Args[0], c.args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "CANNY_GO_RUN_MAIN=1")
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("%v: %v\n%s", c.args, err, stderr.Bytes())
+		}
+		if strings.Contains(stderr.String(), "transparent pixels") != c.warn {
+			t.Errorf("%v: got stderr %q", c.args, stderr.String())
+		}
+	}
+}
+
+func TestUnsupportedFormatError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-heic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	heicPath := filepath.Join(dir, "photo.heic")
+	heic := append([]byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00mif1heic"), make([]byte, 64)...)
+	if err := ioutil.WriteFile(heicPath, heic, 0644); err != nil {
+		t.Fatal(err)
+	}
+	unknownPath := filepath.Join(dir, "notes.txt")
+	if err := ioutil.WriteFile(unknownPath, []byte("not an image at all"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-input", heicPath}, "photo.heic is a HEIC image, which is not supported, supported formats are GIF, JPEG and PNG"},
+		{[]string{"-input", heicPath, "-max-pixels", "1000"}, "photo.heic is a HEIC image"},
+		{[]string{"-input", unknownPath}, "notes.txt is not in a known image format, supported formats are GIF, JPEG and PNG"},
+	} {
+		args := append(c.args, "-output", filepath.Join(dir, "out.png"))
+		if stderr := runMainError(t, args...); !strings.Contains(stderr, c.want) {
+			t.Errorf("%v: got %q, want it to contain %q", c.args, stderr, c.want)
+		}
+	}
+}
+
+func TestSeedPointsFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-seed-points")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "in.png")
+	writePNG(t, inputPath, circleImage(24, 24))
+	for _, c := range []struct {
+		seeds string
+		valid bool
+	}{
+		{"10,20;30,40", true},
+		{"5,5", true},
+		{"10", false},
+		{"10,x", false},
+	} {
+		out := runMain(t, nil, "-input", inputPath, "-output", filepath.Join(dir, "out.png"), "-seed-points", c.seeds)
+		if strings.Contains(string(out), "Invalid seed points") == c.valid {
+			t.Errorf("-seed-points %s: got output %q", c.seeds, out)
+		}
+	}
+}
+
+func TestStdinAndStdoutMatchFileInput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-stdio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var input bytes.Buffer
+	if err := png.Encode(&input, circleImage(32, 24)); err != nil {
+		t.Fatal(err)
+	}
+	inputPath := filepath.Join(dir, "in.png")
+	if err := ioutil.WriteFile(inputPath, input.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outputPath := filepath.Join(dir, "out.jpg")
+	runMain(t, nil, "-input", inputPath, "-output", outputPath)
+	want, err := ioutil.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := runMain(t, input.Bytes(), "-input", "-", "-output", "-"); !bytes.Equal(got, want) {
+		t.Errorf("stdin to stdout: got %d bytes, want the %d bytes of the file output", len(got), len(want))
+	}
+	// The report goes to stderr, so stdout still carries only the image.
+	if got := runMain(t, nil, "-input", inputPath, "-output", "-", "-dominant-orientation"); !bytes.Equal(got, want) {
+		t.Errorf("file to stdout with a report: got %d bytes, want the %d bytes of the file output", len(got), len(want))
+	}
+	encoded := base64.StdEncoding.EncodeToString(input.Bytes())
+	if got := runMain(t, nil, "-input-base64", encoded, "-output", "-"); !bytes.Equal(got, want) {
+		t.Errorf("base64 to stdout: got %d bytes, want the %d bytes of the file output", len(got), len(want))
+	}
+}
+
+func TestStdoutKeepsMessagesOnStderr(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-input", "-", "-output", "-", "-min", "2")
+	cmd.Env = append(os.Environ(), "CANNY_GO_RUN_MAIN=1")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if (stdout.Len() != 0) || !strings.Contains(stderr.String(), "Invalid value for threshold ratio") {
+		t.Errorf("got stdout %q and stderr %q", stdout.String(), stderr.String())
+	}
+
+	if out := runMainError(t, "-input", "-", "-output", "-"); !strings.Contains(out, "stdin") {
+		t.Errorf("got %q, want the error to name stdin", out)
+	}
+}
+
+func TestWorkersFlag(t *testing.T) {
+	var input bytes.Buffer
+	if err := png.Encode(&input, circleImage(40, 36)); err != nil {
+		t.Fatal(err)
+	}
+	want := runMain(t, input.Bytes(), "-input", "-", "-output", "-", "-workers", "1", "-parallel-hysteresis")
+	for _, workers := range []string{"0", "3", "16"} {
+		got := runMain(t, input.Bytes(), "-input", "-", "-output", "-", "-workers", workers, "-parallel-hysteresis")
+		if !bytes.Equal(got, want) {
+			t.Errorf("-workers %s: got %d bytes, want the %d bytes of a single worker", workers, len(got), len(want))
+		}
+	}
+
+	cmd := exec.Command(os.Args[0], "-input", "-", "-output", "-", "-workers", "-1")
+	cmd.Env = append(os.Environ(), "CANNY_GO_RUN_MAIN=1")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stderr.String(), "Invalid number of workers") {
+		t.Errorf("got %q, want the negative worker count rejected", stderr.String())
+	}
+}
+
+func TestOutputFormatFromExtension(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	img := image.NewGray(image.Rect(0, 0, 4, 3))
+	for _, c := range []struct {
+		name  string
+		magic string
+	}{
+		{"edges.png", "\x89PNG\r\n\x1a\n"},
+		{"edges.PNG", "\x89PNG\r\n\x1a\n"},
+		{"edges.gif", "GIF8"},
+		{"edges.jpg", "\xff\xd8\xff"},
+		{"edges", "\xff\xd8\xff"},
+	} {
+		path := filepath.Join(dir, c.name)
+		if err := encodeImage(img, path); err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(data, []byte(c.magic)) {
+			t.Errorf("%s doesn't start with %q", c.name, c.magic)
+		}
+	}
+
+	inputPath := filepath.Join(dir, "in.png")
+	writePNG(t, inputPath, circleImage(32, 24))
+	outputPath := filepath.Join(dir, "out.png")
+	runMain(t, nil, "-input", inputPath, "-output", outputPath)
+	data, err := ioutil.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")) {
+		t.Errorf("-output %s doesn't start with the PNG signature", outputPath)
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMemReportFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-mem-report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "in.png")
+	writePNG(t, inputPath, circleImage(20, 10))
+	out := string(runMain(t, nil, "-input", inputPath, "-output", filepath.Join(dir, "out.png"), "-mem-report"))
+	for _, line := range []string{"magnitudes       200 x  2 B =        400 B", "directions       200 x  8 B =       1600 B", "total"} {
+		if !strings.Contains(out, line) {
+			t.Errorf("expected %q in the report, got %q", line, out)
+		}
+	}
+}
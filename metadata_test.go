@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chfanghr/canny-go/canny"
+)
+
+func TestPreserveMetadataFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var png bytes.Buffer
+	if err := canny.Encode(&png, circleImage(24, 24), "png", canny.EncodeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	exif := []byte("II*\x00\x08\x00\x00\x00\x00\x00")
+	data, err := canny.CopyMetadata(png.Bytes(), canny.Metadata{Exif: exif})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := filepath.Join(dir, "in.png")
+	if err := ioutil.WriteFile(input, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		args []string
+		want []byte
+	}{
+		{nil, nil},
+		{[]string{"-preserve-metadata"}, exif},
+		{[]string{"-strip-metadata=false"}, exif},
+	} {
+		output := filepath.Join(dir, "out.jpg")
+		runMain(t, nil, append([]string{"-input", input, "-output", output}, c.args...)...)
+		written, err := ioutil.ReadFile(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		md, err := canny.ReadMetadata(written)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(md.Exif, c.want) {
+			t.Errorf("%v: got exif %q, want %q", c.args, md.Exif, c.want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestThinningFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-thinning")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "in.png")
+	writePNG(t, inputPath, circleImage(24, 24))
+	for _, c := range []struct {
+		thinning string
+		valid    bool
+	}{
+		{"nms", true},
+		{"skeleton", true},
+		{"none", true},
+		{"zhang-suen", false},
+	} {
+		out := runMain(t, nil, "-input", inputPath, "-output", filepath.Join(dir, "out.png"), "-thinning", c.thinning)
+		if strings.Contains(string(out), "Unknown thinning") == c.valid {
+			t.Errorf("-thinning %s: got output %q", c.thinning, out)
+		}
+	}
+}
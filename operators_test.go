@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRadialFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-radial")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "in.png")
+	writePNG(t, inputPath, circleImage(32, 32))
+	for _, c := range []struct {
+		radial string
+		valid  bool
+	}{
+		{"16,16", true},
+		{"16", false},
+		{"16,x", false},
+	} {
+		out := runMain(t, nil, "-input", inputPath, "-output", filepath.Join(dir, "out.png"), "-radial", c.radial)
+		if strings.Contains(string(out), "Invalid radial center") == c.valid {
+			t.Errorf("-radial %s: got output %q", c.radial, out)
+		}
+	}
+}
+
+func TestAngleRangeFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-angle-range")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "in.png")
+	writePNG(t, inputPath, circleImage(32, 32))
+	for _, c := range []struct {
+		r     string
+		valid bool
+	}{
+		{"80,100", true},
+		{"170,10", true},
+		{"80", false},
+		{"80,180", false},
+	} {
+		out := runMain(t, nil, "-input", inputPath, "-output", filepath.Join(dir, "out.png"), "-angle-range", c.r)
+		if strings.Contains(string(out), "Invalid angle range") == c.valid {
+			t.Errorf("-angle-range %s: got output %q", c.r, out)
+		}
+	}
+}
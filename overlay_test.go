@@ -0,0 +1,63 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBlendSamplesRotatedSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-blend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A red square in the top left corner of a gray image, which rotating by
+	// 180 degrees moves to the bottom right.
+	src := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	draw.Draw(src, src.Bounds(), image.NewUniform(color.RGBA{128, 128, 128, 255}), image.Point{}, draw.Src)
+	draw.Draw(src, image.Rect(4, 4, 16, 16), image.NewUniform(color.RGBA{255, 0, 0, 255}), image.Point{}, draw.Src)
+	inputPath := filepath.Join(dir, "in.png")
+	outputPath := filepath.Join(dir, "out.jpg")
+	writePNG(t, inputPath, src)
+	runMain(t, nil, "-input", inputPath, "-output", outputPath, "-rotate", "180", "-blend", "0.5")
+
+	f, err := os.Open(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	isRed := func(x, y int) bool {
+		r, g, _, _ := img.At(x, y).RGBA()
+		return (r > 0xc000) && (g < 0x4000)
+	}
+	if !isRed(30, 30) || isRed(10, 10) {
+		t.Errorf("got red %t at the bottom right and %t at the top left, want the rotated square in the background", isRed(30, 30), isRed(10, 10))
+	}
+}
+
+func TestParseHexColor(t *testing.T) {
+	for s, want := range map[string]color.NRGBA{
+		"ff8000":  {255, 128, 0, 255},
+		"#00A0ff": {0, 160, 255, 255},
+	} {
+		if got, err := parseHexColor(s); (err != nil) || (got != want) {
+			t.Errorf("%q: got %v, %v, want %v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "fff", "#12345", "12345g", "1234567"} {
+		if _, err := parseHexColor(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLUTFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-lut")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "in.png")
+	writePNG(t, inputPath, circleImage(32, 24))
+	identity := make([]byte, 256)
+	for i := range identity {
+		identity[i] = byte(i)
+	}
+	lutPath := filepath.Join(dir, "identity.lut")
+	if err := ioutil.WriteFile(lutPath, identity, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	plainPath := filepath.Join(dir, "plain.png")
+	lutOutputPath := filepath.Join(dir, "lut.png")
+	runMain(t, nil, "-input", inputPath, "-output", plainPath)
+	runMain(t, nil, "-input", inputPath, "-output", lutOutputPath, "-lut", lutPath)
+	plain, err := ioutil.ReadFile(plainPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withLUT, err := ioutil.ReadFile(lutOutputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plain, withLUT) {
+		t.Error("an identity lookup table changed the output")
+	}
+
+	if err := ioutil.WriteFile(lutPath, identity[:255], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readLUT(lutPath); err == nil {
+		t.Error("expected an error for a lookup table of 255 entries")
+	}
+}
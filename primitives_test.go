@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chfanghr/canny-go/canny"
+)
+
+func TestPrimitivesFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-primitives")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "in.png")
+	primitivesPath := filepath.Join(dir, "primitives.json")
+	writePNG(t, inputPath, circleImage(32, 24))
+	runMain(t, nil, "-input", inputPath, "-output", filepath.Join(dir, "out.png"), "-primitives", primitivesPath)
+
+	data, err := ioutil.ReadFile(primitivesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var primitives canny.Primitives
+	if err := json.Unmarshal(data, &primitives); err != nil {
+		t.Fatal(err)
+	}
+	if (primitives.Width != 32) || (primitives.Height != 24) {
+		t.Errorf("got %dx%d, want 32x24", primitives.Width, primitives.Height)
+	}
+	if (len(primitives.Contours) == 0) || (len(primitives.Components) == 0) {
+		t.Errorf("expected the circle in %s", data)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWindowLevelFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-window-level")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "in.png")
+	// A 16-bit square whose contrast is lost when rounded to 8 bits.
+	img := image.NewNRGBA64(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			v := uint16(1000)
+			if (x >= 8) && (x < 24) && (y >= 8) && (y < 24) {
+				v = 1400
+			}
+			img.SetNRGBA64(x, y, color.NRGBA64{v, v, v, 0xffff})
+		}
+	}
+	writePNG(t, inputPath, img)
+	energy := func(args ...string) float64 {
+		args = append([]string{"-input", inputPath, "-output", filepath.Join(dir, "out.png"), "-edge-energy"}, args...)
+		out := string(runMain(t, nil, args...))
+		i := strings.Index(out, "Edge energy: ")
+		if i < 0 {
+			t.Fatalf("got output %q", out)
+		}
+		value, err := strconv.ParseFloat(strings.SplitN(out[i+len("Edge energy: "):], "\n", 2)[0], 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return value
+	}
+	if plain, windowed := energy(), energy("-window-level", "1024,1024"); windowed <= 10*plain {
+		t.Errorf("got edge energy %v with a window, want well above the %v without", windowed, plain)
+	}
+
+	for _, windowLevel := range []string{"1024", "0,1024", "x,1"} {
+		out := runMain(t, nil, "-input", inputPath, "-output", filepath.Join(dir, "out.png"), "-window-level", windowLevel)
+		if !strings.Contains(string(out), "Invalid window and level") {
+			t.Errorf("-window-level %s: got output %q", windowLevel, out)
+		}
+	}
+}
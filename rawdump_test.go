@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chfanghr/canny-go/canny"
+)
+
+func TestDumpGradientsFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-dump-gradients")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "in.png")
+	dumpPath := filepath.Join(dir, "gradients.bin")
+	writePNG(t, inputPath, circleImage(24, 16))
+	runMain(t, nil, "-input", inputPath, "-output", filepath.Join(dir, "out.png"), "-dump-gradients", dumpPath)
+
+	f, err := os.Open(dumpPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	magnitudes, _, err := canny.ReadRawGradients(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (len(magnitudes) != 16) || (len(magnitudes[0]) != 24) {
+		t.Errorf("got %dx%d, want 24x16", len(magnitudes[0]), len(magnitudes))
+	}
+	var max uint8
+	for _, row := range magnitudes {
+		for _, m := range row {
+			if m.Y > max {
+				max = m.Y
+			}
+		}
+	}
+	if max == 0 {
+		t.Error("expected non-zero magnitudes around the circle")
+	}
+}
+
+func TestInputNMSFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-input-nms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "in.png")
+	dumpPath := filepath.Join(dir, "gradients.bin")
+	fullPath := filepath.Join(dir, "full.png")
+	resumedPath := filepath.Join(dir, "resumed.png")
+	writePNG(t, inputPath, circleImage(24, 16))
+	runMain(t, nil, "-input", inputPath, "-output", fullPath, "-dump-gradients", dumpPath)
+	runMain(t, nil, "-input-nms", dumpPath, "-output", resumedPath)
+
+	full, err := ioutil.ReadFile(fullPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumed, err := ioutil.ReadFile(resumedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(full, resumed) {
+		t.Error("resumed output differs from the full run")
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSupersampleKeepsInputSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-supersample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "in.png")
+	outputPath := filepath.Join(dir, "out.png")
+	writePNG(t, inputPath, circleImage(30, 20))
+	runMain(t, nil, "-input", inputPath, "-output", outputPath, "-supersample", "3")
+
+	f, err := os.Open(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	config, _, err := image.DecodeConfig(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image.Pt(config.Width, config.Height) != image.Pt(30, 20) {
+		t.Errorf("got %dx%d output, want 30x20", config.Width, config.Height)
+	}
+
+	if out := runMain(t, nil, "-input", inputPath, "-output", outputPath, "-supersample", "0"); len(out) == 0 {
+		t.Error("expected -supersample 0 to be rejected")
+	}
+}
+
+func TestFastScaleGivesCoarseFullResolutionEdges(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-fast-scale")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "in.png")
+	writePNG(t, inputPath, circleImage(64, 64))
+	decode := func(path string) image.Image {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		img, _, err := image.Decode(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return img
+	}
+	// blocky reports whether every 4x4 block of img is uniformly edge or
+	// background.
+	blocky := func(img image.Image) bool {
+		for y := 0; y < 64; y++ {
+			for x := 0; x < 64; x++ {
+				edge := func(x, y int) bool { r, _, _, _ := img.At(x, y).RGBA(); return r > 0x8000 }
+				if edge(x, y) != edge(x-x%4, y-y%4) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	fastPath := filepath.Join(dir, "fast.png")
+	fullPath := filepath.Join(dir, "full.png")
+	runMain(t, nil, "-input", inputPath, "-output", fastPath, "-fast-scale", "0.25")
+	runMain(t, nil, "-input", inputPath, "-output", fullPath)
+	fast, full := decode(fastPath), decode(fullPath)
+	if fast.Bounds() != image.Rect(0, 0, 64, 64) {
+		t.Errorf("got %v output, want the input size", fast.Bounds())
+	}
+	if !blocky(fast) || blocky(full) {
+		t.Error("expected coarse 4x4 edge blocks only with -fast-scale 0.25")
+	}
+
+	out := runMain(t, nil, "-input", inputPath, "-output", fastPath, "-fast-scale", "0.5", "-supersample", "2")
+	if !strings.Contains(string(out), "can't be combined") {
+		t.Error("expected -fast-scale and -supersample to be rejected together")
+	}
+}
+
+func TestRotateSamplesSourceColors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A red square in the top left corner of a gray image, which rotating by
+	// 180 degrees moves to the bottom right.
+	src := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	draw.Draw(src, src.Bounds(), image.NewUniform(color.RGBA{128, 128, 128, 255}), image.Point{}, draw.Src)
+	draw.Draw(src, image.Rect(4, 4, 16, 16), image.NewUniform(color.RGBA{255, 0, 0, 255}), image.Point{}, draw.Src)
+	inputPath := filepath.Join(dir, "in.png")
+	outputPath := filepath.Join(dir, "out.jpg")
+	writePNG(t, inputPath, src)
+	runMain(t, nil, "-input", inputPath, "-output", outputPath, "-rotate", "180", "-edge-color", "source")
+
+	f, err := os.Open(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var red, gray int
+	for y := 20; y < 40; y++ {
+		for x := 20; x < 40; x++ {
+			r, g, _, _ := img.At(x, y).RGBA()
+			switch {
+			case (r > 0x6000) && (r > 2*g):
+				red++
+			case (g > 0x6000) && (r < g+0x2000):
+				gray++
+			}
+		}
+	}
+	if (red == 0) || (gray > red) {
+		t.Errorf("got %d red and %d gray edge pixels around the rotated square, want red ones", red, gray)
+	}
+}
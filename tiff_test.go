@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chfanghr/canny-go/canny"
+)
+
+// grayTIFF returns an uncompressed little-endian TIFF holding a single strip
+// of width x height 8-bit gray values with a horizontal gradient.
+func grayTIFF(width, height int) []byte {
+	var out bytes.Buffer
+	out.WriteString(canny.TIFF_LITTLE_ENDIAN_MARK)
+	binary.Write(&out, binary.LittleEndian, uint32(8+width*height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			out.WriteByte(uint8(255 * x / width))
+		}
+	}
+
+	entries := [][3]uint32{
+		{canny.TIFF_IMAGE_WIDTH, 4, uint32(width)},
+		{canny.TIFF_IMAGE_LENGTH, 4, uint32(height)},
+		{canny.TIFF_BITS_PER_SAMPLE, 3, 8},
+		{canny.TIFF_COMPRESSION, 3, canny.TIFF_UNCOMPRESSED},
+		{canny.TIFF_PHOTOMETRIC, 3, canny.TIFF_BLACK_IS_ZERO},
+		{canny.TIFF_STRIP_OFFSETS, 4, 8},
+		{canny.TIFF_SAMPLES_PER_PIXEL, 3, 1},
+		{canny.TIFF_ROWS_PER_STRIP, 4, uint32(height)},
+		{canny.TIFF_STRIP_BYTE_COUNTS, 4, uint32(width * height)},
+	}
+	binary.Write(&out, binary.LittleEndian, uint16(len(entries)))
+	for _, e := range entries {
+		binary.Write(&out, binary.LittleEndian, uint16(e[0]))
+		binary.Write(&out, binary.LittleEndian, uint16(e[1]))
+		binary.Write(&out, binary.LittleEndian, uint32(1))
+		if e[1] == 3 {
+			binary.Write(&out, binary.LittleEndian, [2]uint16{uint16(e[2])})
+		} else {
+			binary.Write(&out, binary.LittleEndian, e[2])
+		}
+	}
+	binary.Write(&out, binary.LittleEndian, uint32(0))
+	return out.Bytes()
+}
+
+func TestDirectoryModeReadsTIFF(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-tiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputDir := filepath.Join(dir, "in")
+	outputDir := filepath.Join(dir, "out")
+	if err := os.Mkdir(inputDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := grayTIFF(37, 29)
+	if err := ioutil.WriteFile(filepath.Join(inputDir, "scan.tif"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	runMain(t, nil, "-input", inputDir, "-output", outputDir, "-band-height", "5")
+	if _, err := os.Stat(filepath.Join(outputDir, "scan.tif")); err != nil {
+		t.Errorf("missing output for the TIFF input: %v", err)
+	}
+}
+
+func TestMaxPixelsRejectsTIFF(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canny-tiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "scan.tif")
+	outputPath := filepath.Join(dir, "out.png")
+	data := grayTIFF(37, 29)
+	if err := ioutil.WriteFile(inputPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	stderr := runMainError(t, "-input", inputPath, "-output", outputPath, "-max-pixels", "1000")
+	if !strings.Contains(stderr, "37x29 pixels, more than -max-pixels 1000") {
+		t.Errorf("got %q, want the -max-pixels error", stderr)
+	}
+	if _, err := os.Stat(outputPath); err == nil {
+		t.Error("the oversized TIFF was processed")
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVersionFlagDefaults(t *testing.T) {
+	out := runMain(t, nil, "-version")
+	if want := "canny-go dev (commit unknown, built unknown)\n"; string(out) != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestVersionInjectedAtLinkTime(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the command")
+	}
+	dir, err := ioutil.TempDir("", "canny-version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	binary := filepath.Join(dir, "canny-go")
+	ldflags := "-X github.com/chfanghr/canny-go/canny.version=1.2.3 -X github.com/chfanghr/canny-go/canny.commit=abc123 -X github.com/chfanghr/canny-go/canny.date=2020-01-02"
+	build := exec.Command("go", "build", "-ldflags", ldflags, "-o", binary, ".")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("go build: %v\n%s", err, out)
+	}
+	out, err := exec.Command(binary, "-version").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "canny-go 1.2.3 (commit abc123, built 2020-01-02)"; strings.TrimSpace(string(out)) != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}